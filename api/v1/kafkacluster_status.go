@@ -8,13 +8,35 @@ import (
 type ClusterConditionType string
 
 const (
-	ClusterConditionPodsReady ClusterConditionType = "PodsReady"
-	ClusterConditionUpgrading                      = "Upgrading"
-	ClusterConditionError                          = "Error"
+	ClusterConditionPodsReady              ClusterConditionType = "PodsReady"
+	ClusterConditionUpgrading                                   = "Upgrading"
+	ClusterConditionError                                       = "Error"
+	ClusterConditionZookeeperUnreachable                        = "ZookeeperUnreachable"
+	ClusterConditionExternalAddressPending                      = "ExternalAddressPending"
+	ClusterConditionCertificatesPending                         = "CertificatesPending"
 
 	// UpdatingClusterReason Reasons for cluster upgrading condition
 	UpdatingClusterReason = "Updating Cluster"
 	UpgradeErrorReason    = "Upgrade Error"
+	UpgradeFailedReason   = "UpgradeFailed"
+
+	// ImmutableFieldChangedReason Reasons for cluster error condition
+	ImmutableFieldChangedReason = "Immutable Field Changed"
+
+	// ZookeeperNotFoundReason Reasons for zookeeper unreachable condition
+	ZookeeperNotFoundReason      = "Zookeeper Not Found"
+	ZookeeperConnectFailedReason = "Zookeeper Connect Failed"
+
+	// LoadBalancerPendingReason Reasons for external address pending condition
+	LoadBalancerPendingReason = "Load Balancer Pending"
+
+	// CertificateNotIssuedReason Reasons for certificates pending condition
+	CertificateNotIssuedReason = "Certificate Not Issued"
+
+	// CARotationPhaseTrustNew the brokers trust both the old and the new CA while their certificates are still signed by the old one
+	CARotationPhaseTrustNew = "TrustNew"
+	// CARotationPhaseSignNew the certificates of the brokers are signed by the new CA while the old one is still trusted
+	CARotationPhaseSignNew = "SignNew"
 )
 
 // MembersStatus is the status of the members of the cluster with both
@@ -48,6 +70,142 @@ type ClusterCondition struct {
 	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
 }
 
+// DynamicConfigStatus shows the result of applying a dynamic config to the running brokers
+type DynamicConfigStatus struct {
+	// Key of the config
+	Key string `json:"key"`
+
+	// Value of the config, empty if the config is being removed or holds a secret such as a password
+	Value string `json:"value,omitempty"`
+
+	// Hash is the sha256 of the value the changes are detected by
+	Hash string `json:"hash,omitempty"`
+
+	// UpdateMode of the config, one of per-broker, cluster-wide
+	UpdateMode string `json:"updateMode,omitempty"`
+
+	// Applied is true if the value has been applied to all the brokers
+	Applied bool `json:"applied"`
+
+	// A human-readable message indicating the error of the last apply.
+	Message string `json:"message,omitempty"`
+
+	// The last time the config was applied.
+	LastAppliedTime string `json:"lastAppliedTime,omitempty"`
+}
+
+// ExternalAddressStatus is the address advertised by a broker on the external listener
+type ExternalAddressStatus struct {
+	// Ordinal of the pod of the broker
+	Ordinal int32 `json:"ordinal"`
+
+	// Host advertised by the broker, empty if the broker advertises the ip of its node
+	Host string `json:"host,omitempty"`
+
+	// Port advertised by the broker
+	Port int32 `json:"port,omitempty"`
+}
+
+// CertificateStatus shows the expiry of a certificate
+type CertificateStatus struct {
+	// Name of the certificate, the name of the pod for the certificates of the brokers
+	Name string `json:"name"`
+
+	// NotAfter is the time the certificate expires
+	NotAfter string `json:"notAfter,omitempty"`
+}
+
+// ReassignmentStatus shows the progress of the partition movements of an operation. the movements are submitted
+// in batches, and the ones not submitted yet are kept so the operation resumes after a restart of the operator
+type ReassignmentStatus struct {
+	// Pending are the partition movements waiting for a batch
+	Pending []PartitionMovement `json:"pending,omitempty"`
+
+	// Topics are the topics whose replicas are being moved by the current batch
+	Topics []string `json:"topics,omitempty"`
+
+	// LogDirs are the movements between the disks of the brokers of the current batch
+	LogDirs []PartitionMovement `json:"logDirs,omitempty"`
+
+	// Partitions is the num of the partitions whose replicas are moved by the operation
+	Partitions int32 `json:"partitions,omitempty"`
+
+	// CompletedPartitions is the num of the partitions whose movements are done
+	CompletedPartitions int32 `json:"completedPartitions,omitempty"`
+
+	// ThrottledBrokers are the ids of the brokers throttled for the operation
+	ThrottledBrokers []int32 `json:"throttledBrokers,omitempty"`
+
+	// ThrottleRate is the replication throttle of the brokers moving the replicas in bytes per second
+	ThrottleRate int64 `json:"throttleRate,omitempty"`
+}
+
+// IsReassigning returns true if some of the movements are in progress or waiting for a batch
+func (rs *ReassignmentStatus) IsReassigning() bool {
+	return len(rs.Topics) > 0 || len(rs.LogDirs) > 0 || len(rs.Pending) > 0
+}
+
+// BrokerDrainStatus shows the progress of moving the replicas off the brokers being removed
+type BrokerDrainStatus struct {
+	// Replicas is the num of the brokers kept running until the drain completes
+	Replicas int32 `json:"replicas"`
+
+	// Brokers are the ids of the brokers being drained
+	Brokers []int32 `json:"brokers,omitempty"`
+
+	ReassignmentStatus `json:",inline"`
+
+	// StartTime is the time the drain started
+	StartTime string `json:"startTime,omitempty"`
+}
+
+// BrokerLoadStatus shows the load of a broker before and after a rebalance
+type BrokerLoadStatus struct {
+	// Broker is the id of the broker
+	Broker int32 `json:"broker"`
+
+	// LogDir is the log dir of the broker whose load is shown, only set by the rebalances between the disks
+	LogDir string `json:"logDir,omitempty"`
+
+	// Before is the num of the replicas, or the bytes of them, hosted by the broker before the rebalance
+	Before int64 `json:"before"`
+
+	// After is the num of the replicas, or the bytes of them, hosted by the broker after the rebalance
+	After int64 `json:"after"`
+}
+
+// ClusterRebalanceStatus shows the plan and the progress of balancing the replicas across the brokers
+type ClusterRebalanceStatus struct {
+	// Replicas is the num of the brokers the replicas are balanced across
+	Replicas int32 `json:"replicas"`
+
+	// BalanceBy is the load balanced across the brokers, one of partitions, size
+	BalanceBy string `json:"balanceBy,omitempty"`
+
+	// Plan is the load of each broker before and after the rebalance
+	Plan []BrokerLoadStatus `json:"plan,omitempty"`
+
+	ReassignmentStatus `json:",inline"`
+
+	// StartTime is the time the replicas started to be moved, empty while waiting for the brokers added
+	StartTime string `json:"startTime,omitempty"`
+
+	// CompletionTime is the time the rebalance completed
+	CompletionTime string `json:"completionTime,omitempty"`
+}
+
+// CruiseControlStatus shows the state of the cruise control deployed next to the cluster
+type CruiseControlStatus struct {
+	// Endpoint is the url of the rest api of the cruise control, authenticated by the credentials in the secret <cluster>-cruise-control-credentials
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// MetricsTopicReady is true once the topic of the metrics reported by the brokers has been created
+	MetricsTopicReady bool `json:"metricsTopicReady,omitempty"`
+
+	// Ready is true once the cruise control is available
+	Ready bool `json:"ready,omitempty"`
+}
+
 // KafkaClusterStatus defines the observed state of KafkaCluster
 type KafkaClusterStatus struct {
 	// Members is the members in the cluster
@@ -62,9 +220,49 @@ type KafkaClusterStatus struct {
 	// InternalClientEndpoint is the internal client IP and port
 	InternalClientEndpoint string `json:"internalClientEndpoint,omitempty"`
 
-	// ExternalClientEndpoint is the internal client IP and port
+	// ExternalClientEndpoint is the external client IP and port
 	ExternalClientEndpoint string `json:"externalClientEndpoint,omitempty"`
 
+	// ExternalAddresses are the addresses advertised by the brokers on the external listener
+	ExternalAddresses []ExternalAddressStatus `json:"externalAddresses,omitempty"`
+
+	// CertificatesHash is the hash of the certificates mounted by the brokers, a change of it rolls the brokers
+	CertificatesHash string `json:"certificatesHash,omitempty"`
+
+	// ClusterCACertificates are the CA certificates trusted by the brokers, both the old and the new one during a CA rotation
+	ClusterCACertificates []CertificateStatus `json:"clusterCACertificates,omitempty"`
+
+	// BrokerCertificates are the certificates of the brokers
+	BrokerCertificates []CertificateStatus `json:"brokerCertificates,omitempty"`
+
+	// CARotationPhase is the phase of the CA rotation in progress, one of TrustNew, SignNew
+	CARotationPhase string `json:"caRotationPhase,omitempty"`
+
+	// ClientsCAHash is the hash of the clients CA certificates trusted by the brokers, a change of it rolls the brokers
+	ClientsCAHash string `json:"clientsCAHash,omitempty"`
+
+	// ClientsCACertificates are the clients CA certificates trusted by the brokers
+	ClientsCACertificates []CertificateStatus `json:"clientsCACertificates,omitempty"`
+
+	// OperatorCertificate is the client certificate of the operator issued by the operator
+	OperatorCertificate *CertificateStatus `json:"operatorCertificate,omitempty"`
+
+	// LastTopicSyncTime is the last time the topics of the cluster were adopted
+	LastTopicSyncTime string `json:"lastTopicSyncTime,omitempty"`
+
+	// MaxReplicationFactor is the highest replication factor of the topics observed in the cluster,
+	// the brokers can not be scaled down below it
+	MaxReplicationFactor int32 `json:"maxReplicationFactor,omitempty"`
+
+	// Drain is the progress of the scale down in progress
+	Drain *BrokerDrainStatus `json:"drain,omitempty"`
+
+	// Rebalance is the last rebalance after a scale up
+	Rebalance *ClusterRebalanceStatus `json:"rebalance,omitempty"`
+
+	// CruiseControl is the state of the cruise control, nil if it is not deployed
+	CruiseControl *CruiseControlStatus `json:"cruiseControl,omitempty"`
+
 	//MetaRootCreated bool `json:"metaRootCreated,omitempty"`
 
 	// CurrentVersion is the current cluster version
@@ -72,6 +270,23 @@ type KafkaClusterStatus struct {
 
 	TargetVersion string `json:"targetVersion,omitempty"`
 
+	// ClusterID is the id of the cluster in kraft mode
+	ClusterID string `json:"clusterId,omitempty"`
+
+	// KraftControllers is the num of the controllers of the quorum pinned when the cluster is bootstrapped,
+	// the voters are the brokers of the first ordinals
+	KraftControllers int32 `json:"kraftControllers,omitempty"`
+
+	// ControllerSecurityProtocol is the protocol of the controller listener of the kraft mode pinned when the cluster
+	// is bootstrapped, since the quorum can not move to another protocol by a rolling restart
+	ControllerSecurityProtocol string `json:"controllerSecurityProtocol,omitempty"`
+
+	// ZookeeperConnect is the resolved zookeeper connect string including the chroot
+	ZookeeperConnect string `json:"zookeeperConnect,omitempty"`
+
+	// DynamicConfigs list the configs applied to the running brokers without a restart
+	DynamicConfigs []DynamicConfigStatus `json:"dynamicConfigs,omitempty"`
+
 	// Conditions list all the applied conditions
 	Conditions []ClusterCondition `json:"conditions,omitempty"`
 }
@@ -132,6 +347,36 @@ func (zs *KafkaClusterStatus) SetErrorConditionFalse() {
 	zs.setClusterCondition(*c)
 }
 
+func (zs *KafkaClusterStatus) SetZookeeperUnreachableConditionTrue(reason, message string) {
+	c := newClusterCondition(ClusterConditionZookeeperUnreachable, corev1.ConditionTrue, reason, message)
+	zs.setClusterCondition(*c)
+}
+
+func (zs *KafkaClusterStatus) SetZookeeperUnreachableConditionFalse() {
+	c := newClusterCondition(ClusterConditionZookeeperUnreachable, corev1.ConditionFalse, "", "")
+	zs.setClusterCondition(*c)
+}
+
+func (zs *KafkaClusterStatus) SetExternalAddressPendingConditionTrue(reason, message string) {
+	c := newClusterCondition(ClusterConditionExternalAddressPending, corev1.ConditionTrue, reason, message)
+	zs.setClusterCondition(*c)
+}
+
+func (zs *KafkaClusterStatus) SetExternalAddressPendingConditionFalse() {
+	c := newClusterCondition(ClusterConditionExternalAddressPending, corev1.ConditionFalse, "", "")
+	zs.setClusterCondition(*c)
+}
+
+func (zs *KafkaClusterStatus) SetCertificatesPendingConditionTrue(reason, message string) {
+	c := newClusterCondition(ClusterConditionCertificatesPending, corev1.ConditionTrue, reason, message)
+	zs.setClusterCondition(*c)
+}
+
+func (zs *KafkaClusterStatus) SetCertificatesPendingConditionFalse() {
+	c := newClusterCondition(ClusterConditionCertificatesPending, corev1.ConditionFalse, "", "")
+	zs.setClusterCondition(*c)
+}
+
 func (zs *KafkaClusterStatus) GetClusterCondition(t ClusterConditionType) (int, *ClusterCondition) {
 	for i, c := range zs.Conditions {
 		if t == c.Type {
@@ -170,7 +415,7 @@ func (zs *KafkaClusterStatus) IsClusterInUpgradeFailedState() bool {
 	if errorCondition == nil {
 		return false
 	}
-	if errorCondition.Status == corev1.ConditionTrue && errorCondition.Reason == "UpgradeFailed" {
+	if errorCondition.Status == corev1.ConditionTrue && errorCondition.Reason == UpgradeFailedReason {
 		return true
 	}
 	return false
@@ -195,6 +440,11 @@ func (zs *KafkaClusterStatus) IsClusterInReadyState() bool {
 	return false
 }
 
+func (zs *KafkaClusterStatus) IsClusterInErrorState(reason string) bool {
+	_, errorCondition := zs.GetClusterCondition(ClusterConditionError)
+	return errorCondition != nil && errorCondition.Status == corev1.ConditionTrue && errorCondition.Reason == reason
+}
+
 func (zs *KafkaClusterStatus) UpdateProgress(reason, updatedReplicas string) {
 	if zs.IsClusterInUpgradingState() {
 		// Set the upgrade condition reason to be UpgradingClusterReason, message to be the upgradedReplicas
@@ -210,3 +460,38 @@ func (zs *KafkaClusterStatus) GetLastCondition() (lastCondition *ClusterConditio
 	// nothing to do if we are not upgrading
 	return nil
 }
+
+func (zs *KafkaClusterStatus) IsExternalAddressPending() bool {
+	_, pendingCondition := zs.GetClusterCondition(ClusterConditionExternalAddressPending)
+	return pendingCondition != nil && pendingCondition.Status == corev1.ConditionTrue
+}
+
+func (zs *KafkaClusterStatus) IsCertificatesPending() bool {
+	_, pendingCondition := zs.GetClusterCondition(ClusterConditionCertificatesPending)
+	return pendingCondition != nil && pendingCondition.Status == corev1.ConditionTrue
+}
+
+func (zs *KafkaClusterStatus) IsCARotationInProgress() bool {
+	return zs.CARotationPhase != ""
+}
+
+func (zs *KafkaClusterStatus) HasPendingDynamicConfigs() bool {
+	for _, c := range zs.DynamicConfigs {
+		if !c.Applied {
+			return true
+		}
+	}
+	return false
+}
+
+func (zs *KafkaClusterStatus) IsDrainInProgress() bool {
+	return zs.Drain != nil
+}
+
+func (zs *KafkaClusterStatus) IsRebalanceInProgress() bool {
+	return zs.Rebalance != nil && zs.Rebalance.CompletionTime == ""
+}
+
+func (zs *KafkaClusterStatus) IsCruiseControlMetricsTopicPending() bool {
+	return zs.CruiseControl != nil && !zs.CruiseControl.MetricsTopicReady
+}
@@ -18,6 +18,7 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -51,6 +52,241 @@ type ImageConfig struct {
 	PullSecrets string `json:"pullSecret,omitempty"`
 }
 
+// DefaultKraftControllers is the default num of the controllers in kraft mode
+const DefaultKraftControllers = 3
+
+type KraftConfig struct {
+	// ClusterID. the id of the kraft cluster. generated by the operator if not specified
+	// +optional
+	ClusterID string `json:"clusterId,omitempty"`
+	// Controllers. num of the brokers which also act as the controllers of the quorum. default value is 3
+	// +optional
+	Controllers int32 `json:"controllers,omitempty"`
+}
+
+type ZookeeperReference struct {
+	// Kind. kind of the referenced object. ZookeeperCluster of the nineinfra zookeeper-operator or Service. default value is ZookeeperCluster
+	// +kubebuilder:validation:Enum=ZookeeperCluster;Service
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Name. name of the referenced object
+	Name string `json:"name"`
+	// Namespace. namespace of the referenced object. default value is the namespace of the cluster
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Port. client port of the zookeeper. default value is 2181
+	// +optional
+	Port int32 `json:"port,omitempty"`
+}
+
+type ZookeeperConfig struct {
+	// ConnectString. raw connect string of an external zookeeper, such as zk-0:2181,zk-1:2181/kafka
+	// +optional
+	ConnectString string `json:"connectString,omitempty"`
+	// Reference. reference to a zookeeper managed by nineinfra or a service in any namespace
+	// +optional
+	Reference *ZookeeperReference `json:"reference,omitempty"`
+	// Chroot. the chroot of the cluster in the zookeeper. default value is /kafka/<namespace>/<name>
+	// +optional
+	Chroot string `json:"chroot,omitempty"`
+}
+
+type ExternalListenerType string
+
+const (
+	ExternalListenerTypeNodePort     ExternalListenerType = "NodePort"
+	ExternalListenerTypeLoadBalancer ExternalListenerType = "LoadBalancer"
+	ExternalListenerTypeIngress      ExternalListenerType = "Ingress"
+	ExternalListenerTypeGateway      ExternalListenerType = "Gateway"
+
+	// HostTemplateOrdinalPlaceholder is replaced by the ordinal of the pod in the host template
+	HostTemplateOrdinalPlaceholder = "{ordinal}"
+)
+
+type ListenerAuthenticationType string
+
+const (
+	ListenerAuthenticationScramSHA512 ListenerAuthenticationType = "scram-sha-512"
+	// ListenerAuthenticationTLS the clients authenticate by the certificates signed by the clients CA
+	ListenerAuthenticationTLS ListenerAuthenticationType = "tls"
+)
+
+type GatewayReference struct {
+	// Name. name of the gateway
+	Name string `json:"name"`
+	// Namespace. namespace of the gateway. default value is the namespace of the cluster
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// SectionName. name of the tls passthrough listener of the gateway
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+type ExternalListenerConfig struct {
+	// Type. how the brokers are exposed to the clients outside the k8s. one of NodePort,LoadBalancer,Ingress,Gateway.
+	// Ingress and Gateway route the tls connections by SNI, so the external listener uses the SSL protocol
+	// +kubebuilder:validation:Enum=NodePort;LoadBalancer;Ingress;Gateway
+	Type ExternalListenerType `json:"type"`
+	// Annotations. annotations of the external services, or of the ingresses and the routes for Ingress and Gateway
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// LoadBalancerSourceRanges. the client ip ranges allowed to access the load balancers. only for LoadBalancer
+	// +optional
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+	// HostTemplate. hostname of the brokers for Ingress and Gateway, {ordinal} is replaced by the ordinal of the pod.
+	// e.g. broker-{ordinal}.kafka.example.com
+	// +optional
+	HostTemplate string `json:"hostTemplate,omitempty"`
+	// BootstrapHost. hostname of the bootstrap for Ingress and Gateway. default value is the host template with {ordinal} replaced by bootstrap
+	// +optional
+	BootstrapHost string `json:"bootstrapHost,omitempty"`
+	// Port. port the clients connect to at the edge for Ingress and Gateway. default value is 443
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// IngressClassName. class of the ingresses. only for Ingress
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+	// GatewayRef. the gateway the tls routes attach to. required for Gateway
+	// +optional
+	GatewayRef *GatewayReference `json:"gatewayRef,omitempty"`
+	// TLS. encrypt the external connections with the certificates issued by the cluster CA, always true for Ingress and Gateway
+	// +optional
+	TLS bool `json:"tls,omitempty"`
+	// Authentication. how the external clients authenticate. one of scram-sha-512,tls. tls requires the tls of the listener,
+	// and any authentication requires the tls of the internal listener
+	// +kubebuilder:validation:Enum=scram-sha-512;tls
+	// +optional
+	Authentication ListenerAuthenticationType `json:"authentication,omitempty"`
+}
+
+type InternalListenerConfig struct {
+	// TLS. encrypt the connections inside the k8s, including the ones between the brokers, with the certificates issued by the cluster CA.
+	// the controller quorum of a kraft cluster bootstrapped with it is secured by it too, so it can not be disabled later
+	// +optional
+	TLS bool `json:"tls,omitempty"`
+	// Authentication. how the internal clients authenticate. one of scram-sha-512,tls. tls requires the tls of the listener.
+	// the brokers and the operator move to a dedicated replication listener once any listener is authenticated, which
+	// requires the tls of the internal listener and is only published by the headless service
+	// +kubebuilder:validation:Enum=scram-sha-512;tls
+	// +optional
+	Authentication ListenerAuthenticationType `json:"authentication,omitempty"`
+}
+
+type AuthorizationConfig struct {
+	// SuperUsers. extra principals allowed to do anything, e.g. User:admin. the brokers and the operator are always super users.
+	// the authorization of a kraft cluster requires the controller listener secured by the tls since the bootstrap
+	// +optional
+	SuperUsers []string `json:"superUsers,omitempty"`
+}
+
+type TopicSyncConfig struct {
+	// Interval. interval of adopting the topics created outside the operator. default value is 5m
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// ExcludePatterns. regular expressions of the names of the topics not to adopt. the internal topics prefixed with __ are never adopted
+	// +optional
+	ExcludePatterns []string `json:"excludePatterns,omitempty"`
+}
+
+type ReassignmentConfig struct {
+	// ThrottleRate. replication throttle of the brokers moving the partitions in bytes per second. default value is 52428800
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ThrottleRate int64 `json:"throttleRate,omitempty"`
+	// BatchSize. max num of the partitions moved at a time by an operation. default value is 50
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BatchSize int32 `json:"batchSize,omitempty"`
+}
+
+type RebalanceLoadType string
+
+const (
+	// RebalanceLoadPartitions the brokers host about the same num of replicas
+	RebalanceLoadPartitions RebalanceLoadType = "partitions"
+	// RebalanceLoadSize the brokers host about the same bytes of replicas on the disks
+	RebalanceLoadSize RebalanceLoadType = "size"
+)
+
+type RebalanceConfig struct {
+	// OnScaleUp. move the replicas onto the brokers added once they are ready, throttled by the reassignment throttle rate
+	// +optional
+	OnScaleUp bool `json:"onScaleUp,omitempty"`
+	// BalanceBy. the load balanced across the brokers, one of partitions,size. default value is partitions
+	// +kubebuilder:validation:Enum=partitions;size
+	// +optional
+	BalanceBy RebalanceLoadType `json:"balanceBy,omitempty"`
+}
+
+type CruiseControlCapacityConfig struct {
+	// CPU. num of the cpu cores of each broker. default value is the cpu limits, or the cpu requests, of the brokers rounded up
+	// +optional
+	CPU *resource.Quantity `json:"cpu,omitempty"`
+	// InboundNetwork. inbound network throughput of each broker in bytes per second. default value is 10485760
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	InboundNetwork int64 `json:"inboundNetwork,omitempty"`
+	// OutboundNetwork. outbound network throughput of each broker in bytes per second. default value is 10485760
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	OutboundNetwork int64 `json:"outboundNetwork,omitempty"`
+}
+
+type CruiseControlConfig struct {
+	// Image. image of the cruise control, which also provides the metrics reporter copied to the brokers. the tag is
+	// required and can not be latest
+	Image ImageConfig `json:"image"`
+	// MetricsReporterJar. path of the jar of the metrics reporter in the image. default value is /opt/cruise-control/cruise-control-metrics-reporter.jar
+	// +optional
+	MetricsReporterJar string `json:"metricsReporterJar,omitempty"`
+	// Conf. k/v configs for the cruisecontrol.properties, such as the goals. the security of the rest api is always
+	// enabled by the operator, which is its only user
+	// +optional
+	Conf map[string]string `json:"conf,omitempty"`
+	// Capacity. capacity of the brokers, the disk capacity is always derived from the disks of the brokers
+	// +optional
+	Capacity *CruiseControlCapacityConfig `json:"capacity,omitempty"`
+	// ResourceRequirements. the resource requirements of the cruise control
+	// +optional
+	ResourceRequirements corev1.ResourceRequirements `json:"resourceRequirements,omitempty"`
+}
+
+type IssuerReference struct {
+	// Name. name of the issuer
+	Name string `json:"name"`
+	// Kind. kind of the issuer, one of Issuer,ClusterIssuer. default value is Issuer
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Group. group of the issuer. default value is cert-manager.io
+	// +optional
+	Group string `json:"group,omitempty"`
+}
+
+type TLSConfig struct {
+	// IssuerRef. the cert-manager issuer of the certificates of the brokers.
+	// the certificates are issued by the operator-managed cluster CA if not specified
+	// +optional
+	IssuerRef *IssuerReference `json:"issuerRef,omitempty"`
+	// RenewBefore. how long before the expiry the certificates of the brokers are renewed. default value is 720h
+	// +optional
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+	// CARenewBefore. how long before the expiry the operator-managed cluster CA is rotated. default value is 2160h
+	// +optional
+	CARenewBefore *metav1.Duration `json:"caRenewBefore,omitempty"`
+}
+
+type ListenersConfig struct {
+	// Internal. the internal listener for the clients inside the k8s and the replication between the brokers
+	// +optional
+	Internal *InternalListenerConfig `json:"internal,omitempty"`
+	// External. the external listener for the clients outside the k8s
+	// +optional
+	External *ExternalListenerConfig `json:"external,omitempty"`
+}
+
 // KafkaClusterSpec defines the desired state of KafkaCluster
 type KafkaClusterSpec struct {
 	// Version. version of the cluster.
@@ -66,6 +302,37 @@ type KafkaClusterSpec struct {
 	// K8sConf. k/v configs for the cluster in k8s.such as the cluster domain
 	// +optional
 	K8sConf map[string]string `json:"k8sConf,omitempty"`
+	// Kraft. run the cluster in kraft mode with an operator managed controller quorum instead of zookeeper
+	// +optional
+	Kraft *KraftConfig `json:"kraft,omitempty"`
+	// Zookeeper. the zookeeper used by the cluster in zookeeper mode
+	// +optional
+	Zookeeper *ZookeeperConfig `json:"zookeeper,omitempty"`
+	// BrokerIDBase. id of the broker with ordinal 0, the ids of the others are offset by their ordinals. default value is 0
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BrokerIDBase int32 `json:"brokerIdBase,omitempty"`
+	// Listeners. configs of the listeners of the brokers
+	// +optional
+	Listeners *ListenersConfig `json:"listeners,omitempty"`
+	// TLS. source of the certificates of the listeners with the tls enabled
+	// +optional
+	TLS *TLSConfig `json:"tls,omitempty"`
+	// Authorization. enforce the acls of the users. requires the internal tls and all the listeners authenticated
+	// +optional
+	Authorization *AuthorizationConfig `json:"authorization,omitempty"`
+	// TopicSync. adopt the topics without a KafkaTopic into KafkaTopics retaining the topics on deletion
+	// +optional
+	TopicSync *TopicSyncConfig `json:"topicSync,omitempty"`
+	// Reassignment. how the partitions are moved between the brokers
+	// +optional
+	Reassignment *ReassignmentConfig `json:"reassignment,omitempty"`
+	// Rebalance. how the replicas are balanced across the brokers
+	// +optional
+	Rebalance *RebalanceConfig `json:"rebalance,omitempty"`
+	// CruiseControl. deploy a cruise control next to the cluster, which computes and executes the KafkaRebalances
+	// +optional
+	CruiseControl *CruiseControlConfig `json:"cruiseControl,omitempty"`
 }
 
 // +genclient
@@ -17,6 +17,10 @@ limitations under the License.
 package v1
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -51,20 +55,203 @@ func (r *KafkaCluster) Default() {
 
 var _ webhook.Validator = &KafkaCluster{}
 
+func (r *KafkaCluster) validateKraft() error {
+	if r.Spec.Kraft == nil {
+		return nil
+	}
+	if _, ok := r.Spec.Conf["zookeeper.connect"]; ok {
+		return fmt.Errorf("zookeeper.connect must not be set in kraft mode")
+	}
+	if r.Spec.Kraft.Controllers < 0 || (r.Spec.Kraft.Controllers != 0 && r.Spec.Kraft.Controllers%2 == 0) {
+		return fmt.Errorf("the num of the kraft controllers must be odd, got %d", r.Spec.Kraft.Controllers)
+	}
+	controllers := r.Spec.Kraft.Controllers
+	if controllers == 0 {
+		controllers = DefaultKraftControllers
+	}
+	if replicas := r.Spec.Resource.Replicas; replicas != 0 && replicas < controllers {
+		return fmt.Errorf("the brokers can not be fewer than the %d kraft controllers, got %d", controllers, replicas)
+	}
+	return nil
+}
+
+func (r *KafkaCluster) validateZookeeper() error {
+	if r.Spec.Zookeeper == nil {
+		return nil
+	}
+	if r.Spec.Kraft != nil {
+		return fmt.Errorf("zookeeper must not be specified in kraft mode")
+	}
+	if _, ok := r.Spec.Conf["zookeeper.connect"]; ok {
+		return fmt.Errorf("zookeeper.connect in conf conflicts with the zookeeper config")
+	}
+	zkConf := r.Spec.Zookeeper
+	if (zkConf.ConnectString == "") == (zkConf.Reference == nil) {
+		return fmt.Errorf("exactly one of the connect string and the reference of the zookeeper must be specified")
+	}
+	if zkConf.Chroot != "" && strings.Contains(zkConf.ConnectString, "/") {
+		return fmt.Errorf("the chroot is specified both in the connect string and the chroot")
+	}
+	return nil
+}
+
+func (r *KafkaCluster) validateListeners() error {
+	if r.Spec.Listeners == nil {
+		return nil
+	}
+	internal := r.Spec.Listeners.Internal
+	if internal != nil && internal.Authentication == ListenerAuthenticationTLS && !internal.TLS {
+		return fmt.Errorf("the tls authentication of the internal listener requires the tls of the listener")
+	}
+	// the replication listener opened by the authentication is only authenticated by the certificates of the internal tls
+	authenticated := (internal != nil && internal.Authentication != "") ||
+		(r.Spec.Listeners.External != nil && r.Spec.Listeners.External.Authentication != "")
+	if authenticated && (internal == nil || !internal.TLS) {
+		return fmt.Errorf("the authentication of the listeners requires the tls of the internal listener")
+	}
+	if r.Spec.Listeners.External == nil {
+		return nil
+	}
+	external := r.Spec.Listeners.External
+	routed := external.Type == ExternalListenerTypeIngress || external.Type == ExternalListenerTypeGateway
+	if external.Authentication == ListenerAuthenticationTLS && !external.TLS && !routed {
+		return fmt.Errorf("the tls authentication of the external listener requires the tls of the listener")
+	}
+	if len(external.LoadBalancerSourceRanges) > 0 && external.Type != ExternalListenerTypeLoadBalancer {
+		return fmt.Errorf("loadBalancerSourceRanges is only supported by the LoadBalancer external listener")
+	}
+	switch external.Type {
+	case ExternalListenerTypeIngress, ExternalListenerTypeGateway:
+		if !strings.Contains(external.HostTemplate, HostTemplateOrdinalPlaceholder) {
+			return fmt.Errorf("hostTemplate must contain %s for the %s external listener", HostTemplateOrdinalPlaceholder, external.Type)
+		}
+		if external.Type == ExternalListenerTypeGateway && (external.GatewayRef == nil || external.GatewayRef.Name == "") {
+			return fmt.Errorf("gatewayRef must be specified for the Gateway external listener")
+		}
+	}
+	return nil
+}
+
+// validateAuthorization requires the brokers and the operator to be identified by their certificates
+// and every client to be authenticated, or the anonymous connections would bypass the acls
+func (r *KafkaCluster) validateAuthorization() error {
+	if r.Spec.Authorization == nil {
+		return nil
+	}
+	listeners := r.Spec.Listeners
+	if listeners == nil || listeners.Internal == nil || !listeners.Internal.TLS || listeners.Internal.Authentication == "" {
+		return fmt.Errorf("authorization requires the tls and the authentication of the internal listener")
+	}
+	if listeners.External != nil && listeners.External.Authentication == "" {
+		return fmt.Errorf("authorization requires the authentication of the external listener")
+	}
+	return nil
+}
+
+func (r *KafkaCluster) validateTopicSync() error {
+	if r.Spec.TopicSync == nil {
+		return nil
+	}
+	for _, pattern := range r.Spec.TopicSync.ExcludePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid topic sync exclude pattern %s: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// validateCruiseControl requires a pinned image of the cruise control, since the brokers load the metrics reporter
+// copied from it and a moving tag would change the reporter on any restart of a broker
+func (r *KafkaCluster) validateCruiseControl() error {
+	if r.Spec.CruiseControl == nil {
+		return nil
+	}
+	if tag := r.Spec.CruiseControl.Image.Tag; tag == "" || tag == "latest" {
+		return fmt.Errorf("the image of the cruise control requires a pinned tag")
+	}
+	return nil
+}
+
+func (r *KafkaCluster) validateTLS() error {
+	if r.Spec.TLS == nil || r.Spec.TLS.RenewBefore == nil || r.Spec.TLS.CARenewBefore == nil {
+		return nil
+	}
+	if r.Spec.TLS.RenewBefore.Duration >= r.Spec.TLS.CARenewBefore.Duration {
+		return fmt.Errorf("renewBefore must be shorter than caRenewBefore, or the certificates expire along with the CA")
+	}
+	return nil
+}
+
+func (r *KafkaCluster) validate() error {
+	if err := r.validateKraft(); err != nil {
+		return err
+	}
+	if err := r.validateZookeeper(); err != nil {
+		return err
+	}
+	if err := r.validateListeners(); err != nil {
+		return err
+	}
+	if err := r.validateAuthorization(); err != nil {
+		return err
+	}
+	if err := r.validateTopicSync(); err != nil {
+		return err
+	}
+	if err := r.validateCruiseControl(); err != nil {
+		return err
+	}
+	return r.validateTLS()
+}
+
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (r *KafkaCluster) ValidateCreate() (admission.Warnings, error) {
 	kafkaclusterlog.Info("validate create", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object creation.
-	return nil, nil
+	return nil, r.validate()
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *KafkaCluster) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
 	kafkaclusterlog.Info("validate update", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object update.
-	return nil, nil
+	oldCluster, ok := old.(*KafkaCluster)
+	if !ok {
+		return nil, fmt.Errorf("expected a KafkaCluster but got a %T", old)
+	}
+	if r.Spec.BrokerIDBase != oldCluster.Spec.BrokerIDBase {
+		return nil, fmt.Errorf("brokerIdBase can not be changed since the brokers are identified by their ids")
+	}
+	if (oldCluster.Spec.Kraft == nil) != (r.Spec.Kraft == nil) {
+		return nil, fmt.Errorf("switching between kraft and zookeeper mode is not supported")
+	}
+	if r.Spec.Kraft != nil {
+		if oldCluster.Status.ClusterID != "" && r.Spec.Kraft.ClusterID != "" && r.Spec.Kraft.ClusterID != oldCluster.Status.ClusterID {
+			return nil, fmt.Errorf("the kraft cluster id %s can not be changed", oldCluster.Status.ClusterID)
+		}
+		if r.Spec.Kraft.Controllers != oldCluster.Spec.Kraft.Controllers {
+			return nil, fmt.Errorf("the num of the kraft controllers can not be changed")
+		}
+		internalTLS := r.Spec.Listeners != nil && r.Spec.Listeners.Internal != nil && r.Spec.Listeners.Internal.TLS
+		if oldCluster.Status.ControllerSecurityProtocol == "SSL" && !internalTLS {
+			return nil, fmt.Errorf("the tls of the internal listener securing the kraft controllers can not be disabled")
+		}
+		// the controllers would authorize the brokers connecting to a plaintext controller listener as anonymous
+		if r.Spec.Authorization != nil && oldCluster.Status.ClusterID != "" && oldCluster.Status.ControllerSecurityProtocol != "SSL" {
+			return nil, fmt.Errorf("authorization requires the kraft controller listener secured by the tls, " +
+				"which can not be enabled on a cluster bootstrapped with a plaintext one")
+		}
+		// the voters of the quorum are pinned, removing any of them loses the quorum
+		if controllers := oldCluster.Status.KraftControllers; r.Spec.Resource.Replicas != 0 && r.Spec.Resource.Replicas < controllers {
+			return nil, fmt.Errorf("the brokers can not be scaled down to %d below the %d kraft controllers", r.Spec.Resource.Replicas, controllers)
+		}
+	}
+	if replicas := r.Spec.Resource.Replicas; replicas != 0 && replicas < oldCluster.Status.Replicas &&
+		replicas < oldCluster.Status.MaxReplicationFactor {
+		return nil, fmt.Errorf("the brokers can not be scaled down to %d below the highest replication factor %d of the topics",
+			replicas, oldCluster.Status.MaxReplicationFactor)
+	}
+	return nil, r.validate()
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -0,0 +1,229 @@
+package v1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestValidateKraftControllers(t *testing.T) {
+	g := NewWithT(t)
+	for _, tc := range []struct {
+		name        string
+		replicas    int32
+		controllers int32
+		valid       bool
+	}{
+		{name: "default replicas and controllers", valid: true},
+		{name: "single broker with the default controllers", replicas: 1},
+		{name: "single broker with a single controller", replicas: 1, controllers: 1, valid: true},
+		{name: "fewer brokers than the controllers", replicas: 3, controllers: 5},
+		{name: "even controllers", replicas: 5, controllers: 2},
+	} {
+		cluster := &KafkaCluster{Spec: KafkaClusterSpec{
+			Resource: ResourceConfig{Replicas: tc.replicas},
+			Kraft:    &KraftConfig{Controllers: tc.controllers},
+		}}
+		_, err := cluster.ValidateCreate()
+		g.Expect(err == nil).To(Equal(tc.valid), tc.name)
+	}
+}
+
+func TestValidateUpdatePinnedKraftControllers(t *testing.T) {
+	g := NewWithT(t)
+	old := &KafkaCluster{
+		Spec:   KafkaClusterSpec{Resource: ResourceConfig{Replicas: 5}, Kraft: &KraftConfig{}},
+		Status: KafkaClusterStatus{ClusterID: "id", KraftControllers: 5},
+	}
+	cluster := old.DeepCopy()
+	cluster.Spec.Resource.Replicas = 7
+	_, err := cluster.ValidateUpdate(old)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// the voters pinned at the bootstrap can not be removed by a scale down
+	cluster.Spec.Resource.Replicas = 3
+	_, err = cluster.ValidateUpdate(old)
+	g.Expect(err).To(MatchError(ContainSubstring("below the 5 kraft controllers")))
+
+	cluster = old.DeepCopy()
+	cluster.Spec.Kraft.Controllers = 3
+	_, err = cluster.ValidateUpdate(old)
+	g.Expect(err).To(MatchError(ContainSubstring("can not be changed")))
+}
+
+func TestValidateUpdateKraftControllerTLS(t *testing.T) {
+	g := NewWithT(t)
+	old := &KafkaCluster{
+		Spec: KafkaClusterSpec{
+			Resource:  ResourceConfig{Replicas: 3},
+			Kraft:     &KraftConfig{},
+			Listeners: &ListenersConfig{Internal: &InternalListenerConfig{TLS: true}},
+		},
+		Status: KafkaClusterStatus{ClusterID: "id", KraftControllers: 3, ControllerSecurityProtocol: "SSL"},
+	}
+	cluster := old.DeepCopy()
+	cluster.Spec.Listeners.Internal.TLS = false
+	_, err := cluster.ValidateUpdate(old)
+	g.Expect(err).To(MatchError(ContainSubstring("securing the kraft controllers")))
+
+	// the plaintext controller listener does not depend on the internal tls
+	old.Status.ControllerSecurityProtocol = "PLAINTEXT"
+	_, err = cluster.ValidateUpdate(old)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// the authorization requires the controller listener secured by the tls
+	cluster = old.DeepCopy()
+	cluster.Spec.Listeners.Internal.Authentication = ListenerAuthenticationTLS
+	cluster.Spec.Authorization = &AuthorizationConfig{}
+	_, err = cluster.ValidateUpdate(old)
+	g.Expect(err).To(MatchError(ContainSubstring("authorization requires the kraft controller listener")))
+	old.Status.ControllerSecurityProtocol = "SSL"
+	_, err = cluster.ValidateUpdate(old)
+	g.Expect(err).NotTo(HaveOccurred())
+}
+
+func TestValidateListeners(t *testing.T) {
+	g := NewWithT(t)
+	for _, tc := range []struct {
+		name      string
+		listeners *ListenersConfig
+		valid     bool
+	}{
+		{name: "no listeners", valid: true},
+		{name: "internal tls without authentication", listeners: &ListenersConfig{Internal: &InternalListenerConfig{TLS: true}}, valid: true},
+		{
+			name:      "internal scram with tls",
+			listeners: &ListenersConfig{Internal: &InternalListenerConfig{TLS: true, Authentication: ListenerAuthenticationScramSHA512}},
+			valid:     true,
+		},
+		{
+			name:      "internal scram without tls",
+			listeners: &ListenersConfig{Internal: &InternalListenerConfig{Authentication: ListenerAuthenticationScramSHA512}},
+		},
+		{
+			name:      "internal tls authentication without tls",
+			listeners: &ListenersConfig{Internal: &InternalListenerConfig{Authentication: ListenerAuthenticationTLS}},
+		},
+		{
+			name: "external authentication without internal tls",
+			listeners: &ListenersConfig{External: &ExternalListenerConfig{
+				Type: ExternalListenerTypeLoadBalancer, TLS: true, Authentication: ListenerAuthenticationTLS,
+			}},
+		},
+	} {
+		cluster := &KafkaCluster{Spec: KafkaClusterSpec{Listeners: tc.listeners}}
+		g.Expect(cluster.validateListeners() == nil).To(Equal(tc.valid), tc.name)
+	}
+}
+
+func TestValidateAuthorization(t *testing.T) {
+	g := NewWithT(t)
+	authenticatedInternal := &InternalListenerConfig{TLS: true, Authentication: ListenerAuthenticationTLS}
+	for _, tc := range []struct {
+		name      string
+		listeners *ListenersConfig
+		valid     bool
+	}{
+		{name: "no listeners"},
+		{name: "internal listener without tls", listeners: &ListenersConfig{Internal: &InternalListenerConfig{Authentication: ListenerAuthenticationScramSHA512}}},
+		{name: "internal listener without authentication", listeners: &ListenersConfig{Internal: &InternalListenerConfig{TLS: true}}},
+		{name: "authenticated internal listener", listeners: &ListenersConfig{Internal: authenticatedInternal}, valid: true},
+		{
+			name:      "unauthenticated external listener",
+			listeners: &ListenersConfig{Internal: authenticatedInternal, External: &ExternalListenerConfig{Type: ExternalListenerTypeNodePort}},
+		},
+		{
+			name: "authenticated external listener",
+			listeners: &ListenersConfig{Internal: authenticatedInternal, External: &ExternalListenerConfig{
+				Type: ExternalListenerTypeNodePort, Authentication: ListenerAuthenticationScramSHA512,
+			}},
+			valid: true,
+		},
+	} {
+		cluster := &KafkaCluster{Spec: KafkaClusterSpec{Listeners: tc.listeners, Authorization: &AuthorizationConfig{}}}
+		g.Expect(cluster.validateAuthorization() == nil).To(Equal(tc.valid), tc.name)
+	}
+}
+
+func TestValidateCruiseControl(t *testing.T) {
+	g := NewWithT(t)
+	for _, tc := range []struct {
+		tag   string
+		valid bool
+	}{
+		{tag: ""},
+		{tag: "latest"},
+		{tag: "2.5.138", valid: true},
+	} {
+		cluster := &KafkaCluster{Spec: KafkaClusterSpec{CruiseControl: &CruiseControlConfig{
+			Image: ImageConfig{Repository: "cruise-control", Tag: tc.tag},
+		}}}
+		g.Expect(cluster.validateCruiseControl() == nil).To(Equal(tc.valid), tc.tag)
+	}
+	g.Expect((&KafkaCluster{}).validateCruiseControl()).To(Succeed())
+}
+
+func TestValidateUpdateScaleDownReplicationFactor(t *testing.T) {
+	g := NewWithT(t)
+	old := &KafkaCluster{
+		Spec:   KafkaClusterSpec{Resource: ResourceConfig{Replicas: 5}},
+		Status: KafkaClusterStatus{Replicas: 5, MaxReplicationFactor: 3},
+	}
+	for _, tc := range []struct {
+		name     string
+		replicas int32
+		valid    bool
+	}{
+		{name: "scale up", replicas: 7, valid: true},
+		{name: "scale down to the highest replication factor", replicas: 3, valid: true},
+		{name: "scale down below the highest replication factor", replicas: 2},
+	} {
+		cluster := old.DeepCopy()
+		cluster.Spec.Resource.Replicas = tc.replicas
+		_, err := cluster.ValidateUpdate(old)
+		g.Expect(err == nil).To(Equal(tc.valid), tc.name)
+	}
+
+	// the brokers below the replication factor are only kept from being removed, not from being added
+	old.Status.Replicas = 1
+	cluster := old.DeepCopy()
+	cluster.Spec.Resource.Replicas = 2
+	_, err := cluster.ValidateUpdate(old)
+	g.Expect(err).NotTo(HaveOccurred())
+}
+
+func TestValidateUpdateKraftImmutability(t *testing.T) {
+	g := NewWithT(t)
+	old := &KafkaCluster{
+		Spec:   KafkaClusterSpec{Resource: ResourceConfig{Replicas: 3}, Kraft: &KraftConfig{ClusterID: "id"}},
+		Status: KafkaClusterStatus{ClusterID: "id", KraftControllers: 3},
+	}
+	for _, tc := range []struct {
+		name   string
+		update func(cluster *KafkaCluster)
+		err    string
+	}{
+		{name: "unchanged", update: func(cluster *KafkaCluster) {}},
+		{name: "cluster id cleared", update: func(cluster *KafkaCluster) { cluster.Spec.Kraft.ClusterID = "" }},
+		{name: "cluster id changed", update: func(cluster *KafkaCluster) { cluster.Spec.Kraft.ClusterID = "other" }, err: "can not be changed"},
+		{name: "controllers changed", update: func(cluster *KafkaCluster) { cluster.Spec.Kraft.Controllers = 1 }, err: "can not be changed"},
+		{
+			name: "switched to zookeeper",
+			update: func(cluster *KafkaCluster) {
+				cluster.Spec.Kraft = nil
+				cluster.Spec.Zookeeper = &ZookeeperConfig{ConnectString: "zk:2181"}
+			},
+			err: "switching between kraft and zookeeper",
+		},
+		{name: "broker id base changed", update: func(cluster *KafkaCluster) { cluster.Spec.BrokerIDBase = 100 }, err: "brokerIdBase can not be changed"},
+	} {
+		cluster := old.DeepCopy()
+		tc.update(cluster)
+		_, err := cluster.ValidateUpdate(old)
+		if tc.err == "" {
+			g.Expect(err).NotTo(HaveOccurred(), tc.name)
+		} else {
+			g.Expect(err).To(MatchError(ContainSubstring(tc.err)), tc.name)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 nineinfra.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// QuotaEntityDefault matches the users or the client ids without a quota of their own
+	QuotaEntityDefault = "<default>"
+)
+
+// QuotaEntity is the clients the quotas apply to, at least one of user and clientId is required
+type QuotaEntity struct {
+	// User. principal name of the user, <default> for the default user
+	// +optional
+	User string `json:"user,omitempty"`
+	// ClientID. id of the client, <default> for the default client id
+	// +optional
+	ClientID string `json:"clientId,omitempty"`
+}
+
+// Quotas is the limits of the clients
+type Quotas struct {
+	// ProducerByteRate. upper bound of the bytes produced per second per broker
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ProducerByteRate *int64 `json:"producerByteRate,omitempty"`
+	// ConsumerByteRate. upper bound of the bytes fetched per second per broker
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ConsumerByteRate *int64 `json:"consumerByteRate,omitempty"`
+	// RequestPercentage. upper bound of the percentage of the time of a network and io thread per broker,
+	// a decimal like 12.5 or an integer
+	// +kubebuilder:validation:XIntOrString
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	RequestPercentage *intstr.IntOrString `json:"requestPercentage,omitempty"`
+	// ControllerMutationRate. upper bound of the partitions created or deleted per second, a decimal like 0.5 or an integer
+	// +kubebuilder:validation:XIntOrString
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	ControllerMutationRate *intstr.IntOrString `json:"controllerMutationRate,omitempty"`
+}
+
+// KafkaQuotaSpec defines the desired state of KafkaQuota
+type KafkaQuotaSpec struct {
+	// ClusterRef. name of the KafkaCluster in the same namespace the quota belongs to
+	ClusterRef string `json:"clusterRef"`
+	// Entity. the clients the quotas apply to
+	Entity QuotaEntity `json:"entity"`
+	// Quotas. the quotas of the entity, the ones left out are not managed
+	Quotas Quotas `json:"quotas"`
+}
+
+// KafkaQuotaStatus defines the observed state of KafkaQuota
+type KafkaQuotaStatus struct {
+	// Entity. the entity the owned quotas are applied to
+	// +optional
+	Entity *QuotaEntity `json:"entity,omitempty"`
+	// OwnedQuotas. keys of the quotas applied by the operator
+	// +optional
+	OwnedQuotas []string `json:"ownedQuotas,omitempty"`
+	// EffectiveQuotas. the quotas applying to the clients of the entity observed in the cluster, the ones not set
+	// for the entity fall back to the ones of the <default> entities in the order the brokers look them up
+	// +optional
+	EffectiveQuotas map[string]string `json:"effectiveQuotas,omitempty"`
+	// Ready. true if the quotas match the spec
+	Ready bool `json:"ready,omitempty"`
+	// Message. reason of the quota not being ready
+	Message string `json:"message,omitempty"`
+	// ObservedGeneration. generation of the spec the status is based on
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterRef`
+//+kubebuilder:printcolumn:name="User",type=string,JSONPath=`.spec.entity.user`
+//+kubebuilder:printcolumn:name="Client ID",type=string,JSONPath=`.spec.entity.clientId`
+//+kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
+
+// KafkaQuota is the Schema for the kafkaquotas API
+type KafkaQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KafkaQuotaSpec   `json:"spec,omitempty"`
+	Status KafkaQuotaStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// KafkaQuotaList contains a list of KafkaQuota
+type KafkaQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KafkaQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KafkaQuota{}, &KafkaQuotaList{})
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024 nineinfra.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type RebalanceState string
+
+const (
+	// RebalanceStatePendingProposal the cruise control is computing the proposal
+	RebalanceStatePendingProposal RebalanceState = "PendingProposal"
+	// RebalanceStateProposalReady the proposal is waiting for the approval
+	RebalanceStateProposalReady RebalanceState = "ProposalReady"
+	// RebalanceStateRebalancing the replicas of the proposal are being moved
+	RebalanceStateRebalancing RebalanceState = "Rebalancing"
+	// RebalanceStateReady the replicas of the proposal have been moved, or there is nothing to move
+	RebalanceStateReady RebalanceState = "Ready"
+	// RebalanceStateStopped the rebalance has been stopped before completing
+	RebalanceStateStopped RebalanceState = "Stopped"
+	// RebalanceStateNotReady the proposal can not be computed or executed
+	RebalanceStateNotReady RebalanceState = "NotReady"
+)
+
+// KafkaRebalanceSpec defines the desired state of KafkaRebalance
+type KafkaRebalanceSpec struct {
+	// ClusterRef. name of the KafkaCluster in the same namespace to rebalance
+	ClusterRef string `json:"clusterRef"`
+	// Brokers. ids of the brokers the replicas are balanced across, the replicas only move between them.
+	// if the cluster runs a cruise control they are its destination brokers instead: the replicas only move
+	// onto them, while the replicas of the other brokers may move onto them too. default value is all the brokers
+	// +optional
+	Brokers []int32 `json:"brokers,omitempty"`
+	// BalanceBy. the load balanced across the brokers, or across the disks of each broker, one of partitions,size.
+	// default value is partitions. ignored by the rebalances across the brokers of a cluster running a cruise control,
+	// which balances the brokers by its goals
+	// +kubebuilder:validation:Enum=partitions;size
+	// +optional
+	BalanceBy RebalanceLoadType `json:"balanceBy,omitempty"`
+	// ThrottleRate. replication throttle of the brokers moving the replicas, or the throttle of the copying between their disks, in bytes per second. default value is the reassignment throttle rate of the cluster
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ThrottleRate int64 `json:"throttleRate,omitempty"`
+	// IntraBroker. balances the load across the disks of each broker instead of across the brokers, the replicas
+	// are moved between the disks of the brokers in Brokers by the operator even if the cluster runs a cruise control
+	// +optional
+	IntraBroker bool `json:"intraBroker,omitempty"`
+	// Goals. the goals of the cruise control the proposal is optimized for, in the order of their priorities.
+	// default value is the default goals of the cruise control. ignored if the cluster does not run a cruise control
+	// +optional
+	Goals []string `json:"goals,omitempty"`
+}
+
+// PartitionMovement is the replicas of a partition before and after the rebalance
+type PartitionMovement struct {
+	// Topic. name of the topic
+	Topic string `json:"topic"`
+	// Partition. id of the partition
+	Partition int32 `json:"partition"`
+	// From. the replicas before the rebalance
+	From []int32 `json:"from"`
+	// To. the replicas after the rebalance
+	To []int32 `json:"to"`
+	// LogDirs. the log dirs of the replicas of To, any keeps the replica on its disk. only set by the rebalances
+	// between the disks of the brokers, whose replicas stay on their brokers
+	// +optional
+	LogDirs []string `json:"logDirs,omitempty"`
+}
+
+// RebalanceProposal is the partition movements proposed to balance the brokers
+type RebalanceProposal struct {
+	// PartitionMovements. num of the partitions whose replicas move
+	PartitionMovements int32 `json:"partitionMovements"`
+	// ReplicaMovements. num of the replicas copied to another broker
+	ReplicaMovements int32 `json:"replicaMovements"`
+	// DataToMoveBytes. estimated bytes copied to the brokers
+	DataToMoveBytes int64 `json:"dataToMoveBytes"`
+	// Plan. the load of each broker before and after the rebalance
+	// +optional
+	Plan []BrokerLoadStatus `json:"plan,omitempty"`
+	// Movements. the partition movements executed once approved
+	// +optional
+	Movements []PartitionMovement `json:"movements,omitempty"`
+	// CreationTime. the time the proposal was computed
+	CreationTime string `json:"creationTime,omitempty"`
+}
+
+// KafkaRebalanceStatus defines the observed state of KafkaRebalance
+type KafkaRebalanceStatus struct {
+	// State. one of PendingProposal, ProposalReady, Rebalancing, Ready, Stopped, NotReady
+	State RebalanceState `json:"state,omitempty"`
+	// Message. details of the state
+	Message string `json:"message,omitempty"`
+	// Proposal. the partition movements proposed
+	// +optional
+	Proposal *RebalanceProposal `json:"proposal,omitempty"`
+	// ReassignmentStatus. the movements of the proposal moved by the operator, the completed partitions are the
+	// ones whose replicas match the proposal
+	ReassignmentStatus `json:",inline"`
+	// StartTime. the time the replicas started to be moved
+	StartTime string `json:"startTime,omitempty"`
+	// CompletionTime. the time the rebalance completed or stopped
+	CompletionTime string `json:"completionTime,omitempty"`
+	// UserTaskID. id of the task of the cruise control computing the proposal or moving the replicas
+	// +optional
+	UserTaskID string `json:"userTaskId,omitempty"`
+	// ObservedGeneration. generation of the spec the proposal is based on
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterRef`
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+//+kubebuilder:printcolumn:name="Movements",type=integer,JSONPath=`.status.proposal.partitionMovements`
+//+kubebuilder:printcolumn:name="Completed",type=integer,JSONPath=`.status.completedPartitions`
+
+// KafkaRebalance is the Schema for the kafkarebalances API
+type KafkaRebalance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KafkaRebalanceSpec   `json:"spec,omitempty"`
+	Status KafkaRebalanceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// KafkaRebalanceList contains a list of KafkaRebalance
+type KafkaRebalanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KafkaRebalance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KafkaRebalance{}, &KafkaRebalanceList{})
+}
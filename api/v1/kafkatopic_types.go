@@ -0,0 +1,116 @@
+/*
+Copyright 2024 nineinfra.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type TopicDeletionPolicy string
+
+const (
+	// TopicDeletionPolicyDelete the topic is deleted from the cluster along with the KafkaTopic
+	TopicDeletionPolicyDelete TopicDeletionPolicy = "Delete"
+	// TopicDeletionPolicyRetain the topic is kept in the cluster after the KafkaTopic is deleted
+	TopicDeletionPolicyRetain TopicDeletionPolicy = "Retain"
+)
+
+// KafkaTopicSpec defines the desired state of KafkaTopic
+type KafkaTopicSpec struct {
+	// ClusterRef. name of the KafkaCluster in the same namespace the topic belongs to
+	ClusterRef string `json:"clusterRef"`
+	// TopicName. name of the topic in the cluster. default value is the name of the KafkaTopic
+	// +optional
+	TopicName string `json:"topicName,omitempty"`
+	// Partitions. num of the partitions, can be increased but not decreased. default value is num.partitions of the cluster
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Partitions int32 `json:"partitions,omitempty"`
+	// ReplicationFactor. num of the replicas of each partition. default value is default.replication.factor of the cluster.
+	// a change of it moves the replicas with the replication throttled
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ReplicationFactor int32 `json:"replicationFactor,omitempty"`
+	// Config. k/v configs of the topic overriding the ones of the cluster
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+	// DeletionPolicy. whether the topic is deleted along with the KafkaTopic, one of Delete,Retain. default value is Delete
+	// +kubebuilder:validation:Enum=Delete;Retain
+	// +optional
+	DeletionPolicy TopicDeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// TopicReassignmentStatus is the progress of moving the replicas of the topic to a new replication factor
+type TopicReassignmentStatus struct {
+	// ReplicationFactor. the target replication factor
+	ReplicationFactor int32 `json:"replicationFactor"`
+	// ReassignmentStatus. the movements of the replicas of the partitions
+	ReassignmentStatus `json:",inline"`
+	// StartTime. the time the reassignment started
+	StartTime string `json:"startTime,omitempty"`
+}
+
+// KafkaTopicStatus defines the observed state of KafkaTopic
+type KafkaTopicStatus struct {
+	// TopicName. name of the topic in the cluster
+	TopicName string `json:"topicName,omitempty"`
+	// Partitions. num of the partitions observed in the cluster
+	Partitions int32 `json:"partitions,omitempty"`
+	// ReplicationFactor. num of the replicas of the partitions observed in the cluster
+	ReplicationFactor int32 `json:"replicationFactor,omitempty"`
+	// Config. the configs of the topic overriding the ones of the cluster observed in the cluster
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+	// Reassignment. progress of the replication factor change in progress
+	// +optional
+	Reassignment *TopicReassignmentStatus `json:"reassignment,omitempty"`
+	// Ready. true if the topic matches the spec
+	Ready bool `json:"ready,omitempty"`
+	// Message. reason of the topic not being ready
+	Message string `json:"message,omitempty"`
+	// ObservedGeneration. generation of the spec the status is based on
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterRef`
+//+kubebuilder:printcolumn:name="Partitions",type=integer,JSONPath=`.status.partitions`
+//+kubebuilder:printcolumn:name="Replication Factor",type=integer,JSONPath=`.status.replicationFactor`
+//+kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
+
+// KafkaTopic is the Schema for the kafkatopics API
+type KafkaTopic struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KafkaTopicSpec   `json:"spec,omitempty"`
+	Status KafkaTopicStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// KafkaTopicList contains a list of KafkaTopic
+type KafkaTopicList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KafkaTopic `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KafkaTopic{}, &KafkaTopicList{})
+}
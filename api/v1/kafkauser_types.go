@@ -0,0 +1,149 @@
+/*
+Copyright 2024 nineinfra.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type UserAuthenticationType string
+
+const (
+	UserAuthenticationScramSHA512 UserAuthenticationType = "scram-sha-512"
+	// UserAuthenticationTLS the user authenticates by a client certificate signed by the clients CA of the cluster
+	UserAuthenticationTLS UserAuthenticationType = "tls"
+)
+
+type AclResourceType string
+
+const (
+	AclResourceTopic           AclResourceType = "topic"
+	AclResourceGroup           AclResourceType = "group"
+	AclResourceCluster         AclResourceType = "cluster"
+	AclResourceTransactionalID AclResourceType = "transactionalId"
+)
+
+type AclPatternType string
+
+const (
+	AclPatternLiteral  AclPatternType = "literal"
+	AclPatternPrefixed AclPatternType = "prefixed"
+)
+
+// +kubebuilder:validation:Enum=All;Read;Write;Create;Delete;Alter;Describe;ClusterAction;DescribeConfigs;AlterConfigs;IdempotentWrite
+type AclOperation string
+
+type AclRule struct {
+	// ResourceType. type of the resource, one of topic,group,cluster,transactionalId
+	// +kubebuilder:validation:Enum=topic;group;cluster;transactionalId
+	ResourceType AclResourceType `json:"resourceType"`
+	// ResourceName. name of the resource, or its prefix for the prefixed pattern. * matches all the resources.
+	// not required for cluster
+	// +optional
+	ResourceName string `json:"resourceName,omitempty"`
+	// PatternType. how the resource name matches the resources, one of literal,prefixed. default value is literal
+	// +kubebuilder:validation:Enum=literal;prefixed
+	// +optional
+	PatternType AclPatternType `json:"patternType,omitempty"`
+	// Operations. operations allowed on the resource
+	// +kubebuilder:validation:MinItems=1
+	Operations []AclOperation `json:"operations"`
+	// Host. host the user is allowed from. default value is *
+	// +optional
+	Host string `json:"host,omitempty"`
+}
+
+type UserAuthorization struct {
+	// Acls. the operations the user is allowed to do. the acls of the user not listed here are deleted
+	// +optional
+	Acls []AclRule `json:"acls,omitempty"`
+}
+
+type PasswordSource struct {
+	// SecretKeyRef. the key of the secret holding the password, other than the connection secret named after the user
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+type UserAuthentication struct {
+	// Type. type of the credential of the user, one of scram-sha-512,tls
+	// +kubebuilder:validation:Enum=scram-sha-512;tls
+	Type UserAuthenticationType `json:"type"`
+	// Password. source of the password of the user, a random password is generated if not set. only for scram-sha-512
+	// +optional
+	Password *PasswordSource `json:"password,omitempty"`
+}
+
+// KafkaUserSpec defines the desired state of KafkaUser
+type KafkaUserSpec struct {
+	// ClusterRef. name of the KafkaCluster in the same namespace the user belongs to
+	ClusterRef string `json:"clusterRef"`
+	// Authentication. credential of the user
+	Authentication UserAuthentication `json:"authentication"`
+	// Authorization. acls of the user, enforced if the authorization of the cluster is enabled
+	// +optional
+	Authorization *UserAuthorization `json:"authorization,omitempty"`
+}
+
+// KafkaUserStatus defines the observed state of KafkaUser
+type KafkaUserStatus struct {
+	// Username. name of the principal in the cluster
+	Username string `json:"username,omitempty"`
+	// SecretName. name of the secret holding the connection info of the user
+	SecretName string `json:"secretName,omitempty"`
+	// Ready. true if the credential has been created in the cluster
+	Ready bool `json:"ready,omitempty"`
+	// Message. reason of the user not being ready
+	Message string `json:"message,omitempty"`
+	// ObservedGeneration. generation of the spec the status is based on
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// CertificateNotAfter. expiry of the client certificate of the user. only for tls
+	CertificateNotAfter string `json:"certificateNotAfter,omitempty"`
+	// AclDrift. the acls of the user found missing or unexpected in the cluster by the last reconciliation, and corrected since
+	// +optional
+	AclDrift []string `json:"aclDrift,omitempty"`
+	// LastAclDriftTime. the last time the acls of the user drifted
+	// +optional
+	LastAclDriftTime string `json:"lastAclDriftTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterRef`
+//+kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
+
+// KafkaUser is the Schema for the kafkausers API
+type KafkaUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KafkaUserSpec   `json:"spec,omitempty"`
+	Status KafkaUserStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// KafkaUserList contains a list of KafkaUser
+type KafkaUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KafkaUser `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KafkaUser{}, &KafkaUserList{})
+}
@@ -21,9 +21,103 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AclRule) DeepCopyInto(out *AclRule) {
+	*out = *in
+	if in.Operations != nil {
+		in, out := &in.Operations, &out.Operations
+		*out = make([]AclOperation, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AclRule.
+func (in *AclRule) DeepCopy() *AclRule {
+	if in == nil {
+		return nil
+	}
+	out := new(AclRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuthorizationConfig) DeepCopyInto(out *AuthorizationConfig) {
+	*out = *in
+	if in.SuperUsers != nil {
+		in, out := &in.SuperUsers, &out.SuperUsers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthorizationConfig.
+func (in *AuthorizationConfig) DeepCopy() *AuthorizationConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthorizationConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BrokerDrainStatus) DeepCopyInto(out *BrokerDrainStatus) {
+	*out = *in
+	if in.Brokers != nil {
+		in, out := &in.Brokers, &out.Brokers
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	in.ReassignmentStatus.DeepCopyInto(&out.ReassignmentStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BrokerDrainStatus.
+func (in *BrokerDrainStatus) DeepCopy() *BrokerDrainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BrokerDrainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BrokerLoadStatus) DeepCopyInto(out *BrokerLoadStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BrokerLoadStatus.
+func (in *BrokerLoadStatus) DeepCopy() *BrokerLoadStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BrokerLoadStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateStatus) DeepCopyInto(out *CertificateStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateStatus.
+func (in *CertificateStatus) DeepCopy() *CertificateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterCondition) DeepCopyInto(out *ClusterCondition) {
 	*out = *in
@@ -39,6 +133,173 @@ func (in *ClusterCondition) DeepCopy() *ClusterCondition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterRebalanceStatus) DeepCopyInto(out *ClusterRebalanceStatus) {
+	*out = *in
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = make([]BrokerLoadStatus, len(*in))
+		copy(*out, *in)
+	}
+	in.ReassignmentStatus.DeepCopyInto(&out.ReassignmentStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterRebalanceStatus.
+func (in *ClusterRebalanceStatus) DeepCopy() *ClusterRebalanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterRebalanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CruiseControlCapacityConfig) DeepCopyInto(out *CruiseControlCapacityConfig) {
+	*out = *in
+	if in.CPU != nil {
+		in, out := &in.CPU, &out.CPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CruiseControlCapacityConfig.
+func (in *CruiseControlCapacityConfig) DeepCopy() *CruiseControlCapacityConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CruiseControlCapacityConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CruiseControlConfig) DeepCopyInto(out *CruiseControlConfig) {
+	*out = *in
+	out.Image = in.Image
+	if in.Conf != nil {
+		in, out := &in.Conf, &out.Conf
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = new(CruiseControlCapacityConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	in.ResourceRequirements.DeepCopyInto(&out.ResourceRequirements)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CruiseControlConfig.
+func (in *CruiseControlConfig) DeepCopy() *CruiseControlConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CruiseControlConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CruiseControlStatus) DeepCopyInto(out *CruiseControlStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CruiseControlStatus.
+func (in *CruiseControlStatus) DeepCopy() *CruiseControlStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CruiseControlStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DynamicConfigStatus) DeepCopyInto(out *DynamicConfigStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DynamicConfigStatus.
+func (in *DynamicConfigStatus) DeepCopy() *DynamicConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DynamicConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalAddressStatus) DeepCopyInto(out *ExternalAddressStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalAddressStatus.
+func (in *ExternalAddressStatus) DeepCopy() *ExternalAddressStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalAddressStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalListenerConfig) DeepCopyInto(out *ExternalListenerConfig) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LoadBalancerSourceRanges != nil {
+		in, out := &in.LoadBalancerSourceRanges, &out.LoadBalancerSourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IngressClassName != nil {
+		in, out := &in.IngressClassName, &out.IngressClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.GatewayRef != nil {
+		in, out := &in.GatewayRef, &out.GatewayRef
+		*out = new(GatewayReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalListenerConfig.
+func (in *ExternalListenerConfig) DeepCopy() *ExternalListenerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalListenerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GatewayReference) DeepCopyInto(out *GatewayReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayReference.
+func (in *GatewayReference) DeepCopy() *GatewayReference {
+	if in == nil {
+		return nil
+	}
+	out := new(GatewayReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageConfig) DeepCopyInto(out *ImageConfig) {
 	*out = *in
@@ -54,6 +315,36 @@ func (in *ImageConfig) DeepCopy() *ImageConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InternalListenerConfig) DeepCopyInto(out *InternalListenerConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InternalListenerConfig.
+func (in *InternalListenerConfig) DeepCopy() *InternalListenerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(InternalListenerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IssuerReference) DeepCopyInto(out *IssuerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IssuerReference.
+func (in *IssuerReference) DeepCopy() *IssuerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(IssuerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KafkaCluster) DeepCopyInto(out *KafkaCluster) {
 	*out = *in
@@ -132,6 +423,51 @@ func (in *KafkaClusterSpec) DeepCopyInto(out *KafkaClusterSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Kraft != nil {
+		in, out := &in.Kraft, &out.Kraft
+		*out = new(KraftConfig)
+		**out = **in
+	}
+	if in.Zookeeper != nil {
+		in, out := &in.Zookeeper, &out.Zookeeper
+		*out = new(ZookeeperConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Listeners != nil {
+		in, out := &in.Listeners, &out.Listeners
+		*out = new(ListenersConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Authorization != nil {
+		in, out := &in.Authorization, &out.Authorization
+		*out = new(AuthorizationConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TopicSync != nil {
+		in, out := &in.TopicSync, &out.TopicSync
+		*out = new(TopicSyncConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Reassignment != nil {
+		in, out := &in.Reassignment, &out.Reassignment
+		*out = new(ReassignmentConfig)
+		**out = **in
+	}
+	if in.Rebalance != nil {
+		in, out := &in.Rebalance, &out.Rebalance
+		*out = new(RebalanceConfig)
+		**out = **in
+	}
+	if in.CruiseControl != nil {
+		in, out := &in.CruiseControl, &out.CruiseControl
+		*out = new(CruiseControlConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaClusterSpec.
@@ -148,6 +484,51 @@ func (in *KafkaClusterSpec) DeepCopy() *KafkaClusterSpec {
 func (in *KafkaClusterStatus) DeepCopyInto(out *KafkaClusterStatus) {
 	*out = *in
 	in.Members.DeepCopyInto(&out.Members)
+	if in.ExternalAddresses != nil {
+		in, out := &in.ExternalAddresses, &out.ExternalAddresses
+		*out = make([]ExternalAddressStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterCACertificates != nil {
+		in, out := &in.ClusterCACertificates, &out.ClusterCACertificates
+		*out = make([]CertificateStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.BrokerCertificates != nil {
+		in, out := &in.BrokerCertificates, &out.BrokerCertificates
+		*out = make([]CertificateStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClientsCACertificates != nil {
+		in, out := &in.ClientsCACertificates, &out.ClientsCACertificates
+		*out = make([]CertificateStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.OperatorCertificate != nil {
+		in, out := &in.OperatorCertificate, &out.OperatorCertificate
+		*out = new(CertificateStatus)
+		**out = **in
+	}
+	if in.Drain != nil {
+		in, out := &in.Drain, &out.Drain
+		*out = new(BrokerDrainStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rebalance != nil {
+		in, out := &in.Rebalance, &out.Rebalance
+		*out = new(ClusterRebalanceStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CruiseControl != nil {
+		in, out := &in.CruiseControl, &out.CruiseControl
+		*out = new(CruiseControlStatus)
+		**out = **in
+	}
+	if in.DynamicConfigs != nil {
+		in, out := &in.DynamicConfigs, &out.DynamicConfigs
+		*out = make([]DynamicConfigStatus, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]ClusterCondition, len(*in))
@@ -166,42 +547,847 @@ func (in *KafkaClusterStatus) DeepCopy() *KafkaClusterStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MembersStatus) DeepCopyInto(out *MembersStatus) {
+func (in *KafkaQuota) DeepCopyInto(out *KafkaQuota) {
 	*out = *in
-	if in.Ready != nil {
-		in, out := &in.Ready, &out.Ready
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.Unready != nil {
-		in, out := &in.Unready, &out.Unready
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MembersStatus.
-func (in *MembersStatus) DeepCopy() *MembersStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaQuota.
+func (in *KafkaQuota) DeepCopy() *KafkaQuota {
 	if in == nil {
 		return nil
 	}
-	out := new(MembersStatus)
+	out := new(KafkaQuota)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KafkaQuota) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ResourceConfig) DeepCopyInto(out *ResourceConfig) {
+func (in *KafkaQuotaList) DeepCopyInto(out *KafkaQuotaList) {
 	*out = *in
-	in.ResourceRequirements.DeepCopyInto(&out.ResourceRequirements)
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KafkaQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceConfig.
-func (in *ResourceConfig) DeepCopy() *ResourceConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaQuotaList.
+func (in *KafkaQuotaList) DeepCopy() *KafkaQuotaList {
 	if in == nil {
 		return nil
 	}
-	out := new(ResourceConfig)
+	out := new(KafkaQuotaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KafkaQuotaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaQuotaSpec) DeepCopyInto(out *KafkaQuotaSpec) {
+	*out = *in
+	out.Entity = in.Entity
+	in.Quotas.DeepCopyInto(&out.Quotas)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaQuotaSpec.
+func (in *KafkaQuotaSpec) DeepCopy() *KafkaQuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaQuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaQuotaStatus) DeepCopyInto(out *KafkaQuotaStatus) {
+	*out = *in
+	if in.Entity != nil {
+		in, out := &in.Entity, &out.Entity
+		*out = new(QuotaEntity)
+		**out = **in
+	}
+	if in.OwnedQuotas != nil {
+		in, out := &in.OwnedQuotas, &out.OwnedQuotas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EffectiveQuotas != nil {
+		in, out := &in.EffectiveQuotas, &out.EffectiveQuotas
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaQuotaStatus.
+func (in *KafkaQuotaStatus) DeepCopy() *KafkaQuotaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaQuotaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaRebalance) DeepCopyInto(out *KafkaRebalance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaRebalance.
+func (in *KafkaRebalance) DeepCopy() *KafkaRebalance {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaRebalance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KafkaRebalance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaRebalanceList) DeepCopyInto(out *KafkaRebalanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KafkaRebalance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaRebalanceList.
+func (in *KafkaRebalanceList) DeepCopy() *KafkaRebalanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaRebalanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KafkaRebalanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaRebalanceSpec) DeepCopyInto(out *KafkaRebalanceSpec) {
+	*out = *in
+	if in.Brokers != nil {
+		in, out := &in.Brokers, &out.Brokers
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.Goals != nil {
+		in, out := &in.Goals, &out.Goals
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaRebalanceSpec.
+func (in *KafkaRebalanceSpec) DeepCopy() *KafkaRebalanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaRebalanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaRebalanceStatus) DeepCopyInto(out *KafkaRebalanceStatus) {
+	*out = *in
+	if in.Proposal != nil {
+		in, out := &in.Proposal, &out.Proposal
+		*out = new(RebalanceProposal)
+		(*in).DeepCopyInto(*out)
+	}
+	in.ReassignmentStatus.DeepCopyInto(&out.ReassignmentStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaRebalanceStatus.
+func (in *KafkaRebalanceStatus) DeepCopy() *KafkaRebalanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaRebalanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaTopic) DeepCopyInto(out *KafkaTopic) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaTopic.
+func (in *KafkaTopic) DeepCopy() *KafkaTopic {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaTopic)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KafkaTopic) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaTopicList) DeepCopyInto(out *KafkaTopicList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KafkaTopic, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaTopicList.
+func (in *KafkaTopicList) DeepCopy() *KafkaTopicList {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaTopicList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KafkaTopicList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaTopicSpec) DeepCopyInto(out *KafkaTopicSpec) {
+	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaTopicSpec.
+func (in *KafkaTopicSpec) DeepCopy() *KafkaTopicSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaTopicSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaTopicStatus) DeepCopyInto(out *KafkaTopicStatus) {
+	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Reassignment != nil {
+		in, out := &in.Reassignment, &out.Reassignment
+		*out = new(TopicReassignmentStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaTopicStatus.
+func (in *KafkaTopicStatus) DeepCopy() *KafkaTopicStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaTopicStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaUser) DeepCopyInto(out *KafkaUser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaUser.
+func (in *KafkaUser) DeepCopy() *KafkaUser {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KafkaUser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaUserList) DeepCopyInto(out *KafkaUserList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KafkaUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaUserList.
+func (in *KafkaUserList) DeepCopy() *KafkaUserList {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaUserList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KafkaUserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaUserSpec) DeepCopyInto(out *KafkaUserSpec) {
+	*out = *in
+	in.Authentication.DeepCopyInto(&out.Authentication)
+	if in.Authorization != nil {
+		in, out := &in.Authorization, &out.Authorization
+		*out = new(UserAuthorization)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaUserSpec.
+func (in *KafkaUserSpec) DeepCopy() *KafkaUserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaUserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaUserStatus) DeepCopyInto(out *KafkaUserStatus) {
+	*out = *in
+	if in.AclDrift != nil {
+		in, out := &in.AclDrift, &out.AclDrift
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaUserStatus.
+func (in *KafkaUserStatus) DeepCopy() *KafkaUserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaUserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KraftConfig) DeepCopyInto(out *KraftConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KraftConfig.
+func (in *KraftConfig) DeepCopy() *KraftConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KraftConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ListenersConfig) DeepCopyInto(out *ListenersConfig) {
+	*out = *in
+	if in.Internal != nil {
+		in, out := &in.Internal, &out.Internal
+		*out = new(InternalListenerConfig)
+		**out = **in
+	}
+	if in.External != nil {
+		in, out := &in.External, &out.External
+		*out = new(ExternalListenerConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ListenersConfig.
+func (in *ListenersConfig) DeepCopy() *ListenersConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ListenersConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MembersStatus) DeepCopyInto(out *MembersStatus) {
+	*out = *in
+	if in.Ready != nil {
+		in, out := &in.Ready, &out.Ready
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Unready != nil {
+		in, out := &in.Unready, &out.Unready
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MembersStatus.
+func (in *MembersStatus) DeepCopy() *MembersStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MembersStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartitionMovement) DeepCopyInto(out *PartitionMovement) {
+	*out = *in
+	if in.From != nil {
+		in, out := &in.From, &out.From
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.To != nil {
+		in, out := &in.To, &out.To
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.LogDirs != nil {
+		in, out := &in.LogDirs, &out.LogDirs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartitionMovement.
+func (in *PartitionMovement) DeepCopy() *PartitionMovement {
+	if in == nil {
+		return nil
+	}
+	out := new(PartitionMovement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PasswordSource) DeepCopyInto(out *PasswordSource) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PasswordSource.
+func (in *PasswordSource) DeepCopy() *PasswordSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PasswordSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuotaEntity) DeepCopyInto(out *QuotaEntity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuotaEntity.
+func (in *QuotaEntity) DeepCopy() *QuotaEntity {
+	if in == nil {
+		return nil
+	}
+	out := new(QuotaEntity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Quotas) DeepCopyInto(out *Quotas) {
+	*out = *in
+	if in.ProducerByteRate != nil {
+		in, out := &in.ProducerByteRate, &out.ProducerByteRate
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ConsumerByteRate != nil {
+		in, out := &in.ConsumerByteRate, &out.ConsumerByteRate
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RequestPercentage != nil {
+		in, out := &in.RequestPercentage, &out.RequestPercentage
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.ControllerMutationRate != nil {
+		in, out := &in.ControllerMutationRate, &out.ControllerMutationRate
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Quotas.
+func (in *Quotas) DeepCopy() *Quotas {
+	if in == nil {
+		return nil
+	}
+	out := new(Quotas)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReassignmentConfig) DeepCopyInto(out *ReassignmentConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReassignmentConfig.
+func (in *ReassignmentConfig) DeepCopy() *ReassignmentConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ReassignmentConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReassignmentStatus) DeepCopyInto(out *ReassignmentStatus) {
+	*out = *in
+	if in.Pending != nil {
+		in, out := &in.Pending, &out.Pending
+		*out = make([]PartitionMovement, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Topics != nil {
+		in, out := &in.Topics, &out.Topics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LogDirs != nil {
+		in, out := &in.LogDirs, &out.LogDirs
+		*out = make([]PartitionMovement, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ThrottledBrokers != nil {
+		in, out := &in.ThrottledBrokers, &out.ThrottledBrokers
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReassignmentStatus.
+func (in *ReassignmentStatus) DeepCopy() *ReassignmentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReassignmentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebalanceConfig) DeepCopyInto(out *RebalanceConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebalanceConfig.
+func (in *RebalanceConfig) DeepCopy() *RebalanceConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RebalanceConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebalanceProposal) DeepCopyInto(out *RebalanceProposal) {
+	*out = *in
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = make([]BrokerLoadStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Movements != nil {
+		in, out := &in.Movements, &out.Movements
+		*out = make([]PartitionMovement, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebalanceProposal.
+func (in *RebalanceProposal) DeepCopy() *RebalanceProposal {
+	if in == nil {
+		return nil
+	}
+	out := new(RebalanceProposal)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceConfig) DeepCopyInto(out *ResourceConfig) {
+	*out = *in
+	in.ResourceRequirements.DeepCopyInto(&out.ResourceRequirements)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceConfig.
+func (in *ResourceConfig) DeepCopy() *ResourceConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSConfig) DeepCopyInto(out *TLSConfig) {
+	*out = *in
+	if in.IssuerRef != nil {
+		in, out := &in.IssuerRef, &out.IssuerRef
+		*out = new(IssuerReference)
+		**out = **in
+	}
+	if in.RenewBefore != nil {
+		in, out := &in.RenewBefore, &out.RenewBefore
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.CARenewBefore != nil {
+		in, out := &in.CARenewBefore, &out.CARenewBefore
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSConfig.
+func (in *TLSConfig) DeepCopy() *TLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopicReassignmentStatus) DeepCopyInto(out *TopicReassignmentStatus) {
+	*out = *in
+	in.ReassignmentStatus.DeepCopyInto(&out.ReassignmentStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopicReassignmentStatus.
+func (in *TopicReassignmentStatus) DeepCopy() *TopicReassignmentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TopicReassignmentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopicSyncConfig) DeepCopyInto(out *TopicSyncConfig) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ExcludePatterns != nil {
+		in, out := &in.ExcludePatterns, &out.ExcludePatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopicSyncConfig.
+func (in *TopicSyncConfig) DeepCopy() *TopicSyncConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TopicSyncConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserAuthentication) DeepCopyInto(out *UserAuthentication) {
+	*out = *in
+	if in.Password != nil {
+		in, out := &in.Password, &out.Password
+		*out = new(PasswordSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserAuthentication.
+func (in *UserAuthentication) DeepCopy() *UserAuthentication {
+	if in == nil {
+		return nil
+	}
+	out := new(UserAuthentication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserAuthorization) DeepCopyInto(out *UserAuthorization) {
+	*out = *in
+	if in.Acls != nil {
+		in, out := &in.Acls, &out.Acls
+		*out = make([]AclRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserAuthorization.
+func (in *UserAuthorization) DeepCopy() *UserAuthorization {
+	if in == nil {
+		return nil
+	}
+	out := new(UserAuthorization)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZookeeperConfig) DeepCopyInto(out *ZookeeperConfig) {
+	*out = *in
+	if in.Reference != nil {
+		in, out := &in.Reference, &out.Reference
+		*out = new(ZookeeperReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZookeeperConfig.
+func (in *ZookeeperConfig) DeepCopy() *ZookeeperConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ZookeeperConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZookeeperReference) DeepCopyInto(out *ZookeeperReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZookeeperReference.
+func (in *ZookeeperReference) DeepCopy() *ZookeeperReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ZookeeperReference)
 	in.DeepCopyInto(out)
 	return out
 }
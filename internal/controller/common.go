@@ -1,9 +1,14 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"strings"
 )
 
@@ -29,6 +34,10 @@ func GetFullSvcName(cluster *kafkav1.KafkaCluster) string {
 	return fmt.Sprintf("%s.%s.svc.%s", ClusterResourceName(cluster), cluster.Namespace, GetClusterDomain(cluster))
 }
 
+func GetFullHeadlessSvcName(cluster *kafkav1.KafkaCluster) string {
+	return fmt.Sprintf("%s.%s.svc.%s", ClusterResourceName(cluster, DefaultHeadlessSvcNameSuffix), cluster.Namespace, GetClusterDomain(cluster))
+}
+
 func GetClusterDomain(cluster *kafkav1.KafkaCluster) string {
 	if cluster.Spec.K8sConf != nil {
 		if value, ok := cluster.Spec.K8sConf[DefaultClusterDomainName]; ok {
@@ -109,3 +118,87 @@ func DefaultEnvs() []corev1.EnvVar {
 	)
 	return envs
 }
+
+func IsKraftMode(cluster *kafkav1.KafkaCluster) bool {
+	return cluster.Spec.Kraft != nil
+}
+
+// GetKraftControllers returns the num of the controllers of the quorum, which is pinned in the status once the cluster
+// is bootstrapped since the voters of a static quorum can not change
+func GetKraftControllers(cluster *kafkav1.KafkaCluster) int32 {
+	if cluster.Status.KraftControllers != 0 {
+		return cluster.Status.KraftControllers
+	}
+	controllers := int32(kafkav1.DefaultKraftControllers)
+	if cluster.Spec.Kraft != nil && cluster.Spec.Kraft.Controllers != 0 {
+		controllers = cluster.Spec.Kraft.Controllers
+	}
+	if replicas := getReplicas(cluster); controllers > replicas {
+		return replicas
+	}
+	return controllers
+}
+
+func GetPodFullName(cluster *kafkav1.KafkaCluster, ordinal int) string {
+	return fmt.Sprintf("%s-%d.%s.%s.svc.%s",
+		ClusterResourceName(cluster),
+		ordinal,
+		ClusterResourceName(cluster, DefaultHeadlessSvcNameSuffix),
+		cluster.Namespace,
+		GetClusterDomain(cluster))
+}
+
+func getBrokerIDBase(cluster *kafkav1.KafkaCluster) int32 {
+	return cluster.Spec.BrokerIDBase
+}
+
+// GetBrokerID returns the broker.id or node.id of the broker derived from the ordinal of its pod
+func GetBrokerID(cluster *kafkav1.KafkaCluster, ordinal int) int32 {
+	return getBrokerIDBase(cluster) + int32(ordinal)
+}
+
+func GetControllerQuorumVoters(cluster *kafkav1.KafkaCluster) string {
+	voters := make([]string, 0)
+	for i := 0; i < int(GetKraftControllers(cluster)); i++ {
+		voters = append(voters, fmt.Sprintf("%d@%s:%d", GetBrokerID(cluster, i), GetPodFullName(cluster, i), DefaultControllerPort))
+	}
+	return strings.Join(voters, ",")
+}
+
+// getClusterRef returns the cluster referred to by a resource in its namespace
+func getClusterRef(c client.Client, namespace string, name string) (*kafkav1.KafkaCluster, error) {
+	cluster := &kafkav1.KafkaCluster{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// addFinalizer adds the finalizer to a resource managing the things in a cluster
+func addFinalizer(c client.Client, obj client.Object) error {
+	if controllerutil.ContainsFinalizer(obj, DefaultFinalizer) {
+		return nil
+	}
+	controllerutil.AddFinalizer(obj, DefaultFinalizer)
+	return c.Update(context.TODO(), obj)
+}
+
+// finalizeClusterResource cleans up the things of a resource in the cluster it refers to before releasing the
+// resource. the cleanup is skipped if the cluster does not exist or is being deleted, they are gone with the cluster
+func finalizeClusterResource(c client.Client, obj client.Object, clusterRef string, cleanup func(cluster *kafkav1.KafkaCluster) error) error {
+	if !controllerutil.ContainsFinalizer(obj, DefaultFinalizer) {
+		return nil
+	}
+	cluster, err := getClusterRef(c, obj.GetNamespace(), clusterRef)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if cluster != nil && cluster.DeletionTimestamp.IsZero() {
+		if err = cleanup(cluster); err != nil {
+			return err
+		}
+	}
+	controllerutil.RemoveFinalizer(obj, DefaultFinalizer)
+	return c.Update(context.TODO(), obj)
+}
@@ -2,7 +2,9 @@ package controller
 
 import (
 	"fmt"
+	"github.com/IBM/sarama"
 	"strconv"
+	"time"
 )
 
 const (
@@ -28,21 +30,103 @@ const (
 
 	DefaultLogVolumeName = "log"
 
+	DefaultRuntimeVolumeName = "runtime"
+
 	DefaultConfigNameSuffix      = "-config"
 	DefaultHeadlessSvcNameSuffix = "-headless"
+	DefaultExternalSvcNameSuffix = "-external"
+	DefaultBootstrapNameSuffix   = "-bootstrap"
+
+	// DefaultClusterCASecretSuffix is the name suffix of the secret holding the cluster CA and its key
+	DefaultClusterCASecretSuffix = "-cluster-ca"
+	// DefaultClusterCACertSecretSuffix is the name suffix of the secret publishing the cluster CA certificate to the clients
+	DefaultClusterCACertSecretSuffix = "-cluster-ca-cert"
+	// DefaultBrokersTLSSecretSuffix is the name suffix of the secret holding the keystores of the brokers
+	DefaultBrokersTLSSecretSuffix = "-brokers-tls"
+	// DefaultOperatorTLSSecretSuffix is the name suffix of the secret holding the client certificate of the operator
+	DefaultOperatorTLSSecretSuffix = "-operator-tls"
+	// DefaultClientsCASecretSuffix is the name suffix of the secret holding the clients CA signing the certificates of the users
+	DefaultClientsCASecretSuffix = "-clients-ca"
+	// DefaultClientsCACertSecretSuffix is the name suffix of the secret holding the clients CA certificates trusted by the brokers
+	DefaultClientsCACertSecretSuffix = "-clients-ca-cert"
+
+	// DefaultBrokerCertificateSuffix is the name suffix of the cert-manager certificates of the brokers and their secrets
+	DefaultBrokerCertificateSuffix = "-tls"
+
+	CertManagerGroup         = "cert-manager.io"
+	CertManagerVersion       = "v1"
+	CertificateKind          = "Certificate"
+	CertificateListKind      = "CertificateList"
+	DefaultCertManagerIssuer = "Issuer"
+
+	DefaultTLSVolumeName = "tls"
+	DefaultCACertKey     = "ca.crt"
+	DefaultCAKeyKey      = "ca.key"
+	// DefaultClientsCAVolumeName is the volume of the clients CA certificates mounted by the brokers
+	DefaultClientsCAVolumeName = "clients-ca"
+	// DefaultUserCertKey and DefaultUserKeyKey are the keys of the client certificate in the secrets of the tls users
+	DefaultUserCertKey = "user.crt"
+	DefaultUserKeyKey  = "user.key"
+	// DefaultKeystoreSuffix is the suffix of the keys of the PEM keystores in the brokers secret, named after the pods
+	DefaultKeystoreSuffix = ".pem"
+
+	// DefaultCAValidity is the validity of the cluster CA
+	DefaultCAValidity = 5 * 365 * 24 * time.Hour
+	// DefaultCertValidity is the validity of the certificates of the brokers
+	DefaultCertValidity = 365 * 24 * time.Hour
+	// DefaultCertRenewBefore is how long before the expiry the certificates of the brokers are renewed
+	DefaultCertRenewBefore = 30 * 24 * time.Hour
+	// DefaultCARenewBefore is how long before the expiry the cluster CA is rotated
+	DefaultCARenewBefore = 90 * 24 * time.Hour
+	// DefaultKeySize is the size of the rsa keys of the CA and the certificates
+	DefaultKeySize = 2048
+
+	// DefaultListenerLabel is the label of the services of a listener
+	DefaultListenerLabel = "listener"
+
+	// DefaultAdoptedLabel is the label of the KafkaTopics adopted from the cluster by the topic sync
+	DefaultAdoptedLabel = "kafka.nineinfra.tech/adopted"
+	// DefaultInternalTopicPrefix is the name prefix of the internal topics, which are never adopted
+	DefaultInternalTopicPrefix = "__"
+	// DefaultResourceNameMaxLength is the max length of the names of the k8s resources
+	DefaultResourceNameMaxLength = 253
+
+	// DefaultRebalanceAnnotation is the annotation of the KafkaRebalances asking to approve, stop or refresh the rebalance
+	DefaultRebalanceAnnotation = "kafka.nineinfra.tech/rebalance"
+	RebalanceActionApprove     = "approve"
+	RebalanceActionStop        = "stop"
+	RebalanceActionRefresh     = "refresh"
+
+	// DefaultPodNameLabel is the label of the pod name set by the StatefulSet controller
+	DefaultPodNameLabel = "statefulset.kubernetes.io/pod-name"
+
+	// DefaultExternalRoutePort is the default port the clients connect to at the edge for Ingress and Gateway
+	DefaultExternalRoutePort = 443
+
+	// DefaultBootstrapHostOrdinal replaces the ordinal in the host template to render the default bootstrap host
+	DefaultBootstrapHostOrdinal = "bootstrap"
+
+	// DefaultSSLPassthroughAnnotation enables the tls passthrough of the ingress-nginx
+	DefaultSSLPassthroughAnnotation = "nginx.ingress.kubernetes.io/ssl-passthrough"
+
+	GatewayAPIGroup  = "gateway.networking.k8s.io"
+	TLSRouteVersion  = "v1alpha2"
+	TLSRouteKind     = "TLSRoute"
+	TLSRouteListKind = "TLSRouteList"
 
 	DefaultKafkaHome           = "/opt/kafka"
 	DefaultKafkaConfigFileName = "server.properties"
 	DefaultLogConfigFileName   = "log4j.properties"
+	DefaultStartScriptFileName = "start-kafka.sh"
 	DefaultDiskPathPrefix      = "disk"
 
-	DefaultMaxBrokerID                = -1
 	DefaultNetworkThreads             = 3
 	DefaultIOThreads                  = 8
 	DefaultSocketSendBufferSize       = 102400
 	DefaultSocketReceiveBufferSize    = 102400
 	DefaultSocketRequestsMaxSize      = 104857600
 	DefaultPartitionsPerTopic         = 1
+	DefaultReplicationFactorPerTopic  = 1
 	DefaultRecoveryThreadsPerDir      = 1
 	DefaultOffsetsFactor              = 1
 	DefaultTransactionFactor          = 1
@@ -61,6 +145,65 @@ const (
 	DefaultExternalPortName = "external"
 	DefaultExternalPort     = 9093
 
+	DefaultControllerPortName = "controller"
+	DefaultControllerPort     = 9090
+
+	// DefaultReplicationPortName is the listener used by the brokers and the operator once any listener is authenticated
+	DefaultReplicationPortName = "replication"
+	DefaultReplicationPort     = 9091
+
+	// DefaultScramMechanism is the sasl mechanism of the scram-sha-512 authentication
+	DefaultScramMechanism = "SCRAM-SHA-512"
+	// DefaultScramIterations is the num of the iterations of the scram credentials
+	DefaultScramIterations = 4096
+	// DefaultCommonNameMappingRule maps the certificate subjects to the common names, so the principals of the tls users are their names
+	DefaultCommonNameMappingRule = "RULE:^CN=([^,]+)(,.*)?$/$1/"
+
+	// DefaultZookeeperAuthorizer and DefaultKraftAuthorizer are the authorizers of the acls in zookeeper and kraft mode
+	DefaultZookeeperAuthorizer = "kafka.security.authorizer.AclAuthorizer"
+	DefaultKraftAuthorizer     = "org.apache.kafka.metadata.authorizer.StandardAuthorizer"
+	// DefaultAnonymousPrincipal is the principal of the unauthenticated connections
+	DefaultAnonymousPrincipal = "User:ANONYMOUS"
+	// DefaultAclClusterResourceName is the name of the cluster resource of the acls
+	DefaultAclClusterResourceName = "kafka-cluster"
+	// DefaultScramSaltSize is the size of the salt of the scram credentials
+	DefaultScramSaltSize = 32
+	// DefaultPasswordSize is the num of the random bytes of the generated passwords
+	DefaultPasswordSize = 24
+
+	// DefaultUserSign is the app label of the connection secrets of the users
+	DefaultUserSign = "kafka-user"
+	// DefaultFinalizer is the finalizer of the resources whose deletion needs to be applied to the cluster
+	DefaultFinalizer = "kafka.nineinfra.tech/finalizer"
+
+	// keys of the connection secrets of the users
+	DefaultUsernameKey         = "username"
+	DefaultPasswordKey         = "password"
+	DefaultSaslMechanismKey    = "sasl.mechanism"
+	DefaultSaslJaasConfigKey   = "sasl.jaas.config"
+	DefaultSecurityProtocolKey = "security.protocol"
+	DefaultBootstrapServersKey = "bootstrap.servers"
+
+	// DefaultKraftClusterIDEnvName is the env name of the cluster id in kraft mode
+	DefaultKraftClusterIDEnvName = "KAFKA_CLUSTER_ID"
+
+	// DefaultZookeeperPort is the default client port of the zookeeper
+	DefaultZookeeperPort = 2181
+
+	// DefaultZookeeperSvcNameSuffix is the name suffix of the services created by the nineinfra zookeeper-operator
+	DefaultZookeeperSvcNameSuffix = "-zookeeper"
+
+	// DefaultZookeeperChrootPrefix is the prefix of the default chroot of the cluster
+	DefaultZookeeperChrootPrefix = "/kafka"
+
+	// DefaultZookeeperSessionTimeout is the session timeout of the connection used to create the chroot
+	DefaultZookeeperSessionTimeout = 10 * time.Second
+
+	ZookeeperReferenceKindCluster = "ZookeeperCluster"
+	ZookeeperReferenceKindService = "Service"
+	ZookeeperClusterGroup         = "zookeeper.nineinfra.tech"
+	ZookeeperClusterVersion       = "v1"
+
 	// DefaultTerminationGracePeriod is the default time given before the
 	// container is stopped. This gives clients time to disconnect from a
 	// specific node gracefully.
@@ -116,15 +259,143 @@ const (
 
 	//DefaultProbeTypeReadiness readiness type probe
 	DefaultProbeTypeReadiness = "readiness"
+
+	// DefaultSpecHashAnnotation is the annotation of the hash of the desired spec of the workload
+	DefaultSpecHashAnnotation = "kafka.nineinfra.tech/spec-hash"
+
+	// DefaultConfigHashAnnotation is the annotation of the hash of the configs rendered for the brokers
+	DefaultConfigHashAnnotation = "kafka.nineinfra.tech/config-hash"
+
+	// DefaultCertificatesHashAnnotation is the annotation of the hash of the certificates on the pod template to roll the pods once they are reissued
+	DefaultCertificatesHashAnnotation = "kafka.nineinfra.tech/certificates-hash"
+	// DefaultClientsCAHashAnnotation is the annotation of the hash of the clients CA certificates on the pod template
+	DefaultClientsCAHashAnnotation = "kafka.nineinfra.tech/clients-ca-hash"
+
+	// DefaultAdminClientID is the client id of the admin client of the operator
+	DefaultAdminClientID = "kafka-operator"
+
+	// DefaultAdminTimeout is the timeout of the requests of the admin client
+	DefaultAdminTimeout = 10 * time.Second
+
+	// DefaultRollingRestartTimeout is the time a restarted broker has to become ready
+	// and catch up with all the partitions it hosts before the rolling restart is paused
+	DefaultRollingRestartTimeout = 10 * time.Minute
+
+	// DefaultRequeueInterval is the interval of the reconciliation while the cluster is being updated
+	DefaultRequeueInterval = 15 * time.Second
+	// DefaultAclResyncInterval is the interval of checking the acls of the users for drift
+	DefaultAclResyncInterval = 5 * time.Minute
+	// DefaultTopicSyncInterval is the default interval of adopting the topics created outside the operator
+	DefaultTopicSyncInterval = 5 * time.Minute
+
+	// DefaultReassignmentThrottleRate is the default replication throttle of the partition reassignments in bytes per second
+	DefaultReassignmentThrottleRate = 50 * 1024 * 1024
+	// DefaultReassignmentBatchSize is the default max num of the partitions moved at a time by an operation
+	DefaultReassignmentBatchSize = 50
+
+	// the configs throttling the replication of the reassigned replicas
+	LeaderThrottledRateConfig       = "leader.replication.throttled.rate"
+	FollowerThrottledRateConfig     = "follower.replication.throttled.rate"
+	LeaderThrottledReplicasConfig   = "leader.replication.throttled.replicas"
+	FollowerThrottledReplicasConfig = "follower.replication.throttled.replicas"
+	// LogDirThrottledRateConfig throttles the copying of the replicas moved between the disks of a broker
+	LogDirThrottledRateConfig = "replica.alter.log.dirs.io.max.bytes.per.second"
+	// DefaultAnyLogDir is the log dir of the replicas kept on their disks by a movement between the disks
+	DefaultAnyLogDir = "any"
+
+	// the keys of the client quotas
+	ProducerByteRateQuota       = "producer_byte_rate"
+	ConsumerByteRateQuota       = "consumer_byte_rate"
+	RequestPercentageQuota      = "request_percentage"
+	ControllerMutationRateQuota = "controller_mutation_rate"
+
+	// DefaultRevisionLabel is the label of the revision of the pods of a StatefulSet
+	DefaultRevisionLabel = "controller-revision-hash"
+
+	// DefaultCruiseControlSign is the app label of the cruise control, which must not match the pods of the brokers
+	DefaultCruiseControlSign = "kafka-cruise-control"
+	// DefaultCruiseControlNameSuffix is the name suffix of the resources of the cruise control
+	DefaultCruiseControlNameSuffix = "-cruise-control"
+
+	DefaultCruiseControlHome                = "/opt/cruise-control"
+	DefaultCruiseControlConfigFileName      = "cruisecontrol.properties"
+	DefaultCruiseControlCapacityFileName    = "capacity.json"
+	DefaultCruiseControlStartScriptFileName = "start-cruise-control.sh"
+	// DefaultCruiseControlKeystoreFileName is the PEM keystore rendered from the client certificate of the operator
+	DefaultCruiseControlKeystoreFileName = "keystore.pem"
+
+	DefaultCruiseControlPortName = "rest"
+	DefaultCruiseControlPort     = 9090
+	// DefaultCruiseControlAPIPrefix is the path prefix of the rest api of the cruise control
+	DefaultCruiseControlAPIPrefix = "/kafkacruisecontrol"
+	// DefaultUserTaskIDHeader is the header of the rest api of the cruise control identifying the task of a request
+	DefaultUserTaskIDHeader = "User-Task-ID"
+	// DefaultCruiseControlTimeout is the timeout of the requests to the rest api of the cruise control
+	DefaultCruiseControlTimeout = 30 * time.Second
+	// DefaultCruiseControlCredentialsSecretSuffix is the name suffix of the secret holding the credentials of the rest api
+	DefaultCruiseControlCredentialsSecretSuffix = "-cruise-control-credentials"
+	// DefaultCruiseControlCredentialsFileName is the credentials file of the basic authentication of the rest api
+	DefaultCruiseControlCredentialsFileName = "credentials.properties"
+	// DefaultCruiseControlUsername is the user of the operator on the rest api, which is the only user
+	DefaultCruiseControlUsername    = "operator"
+	DefaultCruiseControlPasswordKey = "password"
+	// DefaultCruiseControlSecurityProvider authenticates the requests to the rest api by the credentials file
+	DefaultCruiseControlSecurityProvider = "com.linkedin.kafka.cruisecontrol.servlet.security.BasicSecurityProvider"
+
+	// DefaultCruiseControlMetricsTopic is the topic the metrics reporter of the brokers produces to
+	DefaultCruiseControlMetricsTopic = "__CruiseControlMetrics"
+	// DefaultCruiseControlMetricsRetention is the retention of the metrics topic in milliseconds
+	DefaultCruiseControlMetricsRetention = 5 * 60 * 60 * 1000
+	// DefaultCruiseControlMaxReplicationFactor is the highest replication factor of the topics of the cruise control
+	DefaultCruiseControlMaxReplicationFactor = 3
+	// DefaultCruiseControlMetricsReporter is the class of the metrics reporter of the cruise control
+	DefaultCruiseControlMetricsReporter = "com.linkedin.kafka.cruisecontrol.metricsreporter.CruiseControlMetricsReporter"
+	// DefaultMetricsReporterJar is the default path of the jar of the metrics reporter in the image of the cruise control
+	DefaultMetricsReporterJar = DefaultCruiseControlHome + "/cruise-control-metrics-reporter.jar"
+	// DefaultMetricsReporterVolumeName is the volume the jar of the metrics reporter is copied to for the brokers
+	DefaultMetricsReporterVolumeName = "metrics-reporter"
+	// DefaultCruiseControlConfigVolumeName is the volume of the configs of the cruise control
+	DefaultCruiseControlConfigVolumeName = "config"
+	// DefaultOperatorTLSVolumeName is the volume of the client certificate of the operator mounted by the cruise control
+	DefaultOperatorTLSVolumeName = "operator-tls"
+	// DefaultCruiseControlCredentialsVolumeName is the volume of the credentials of the rest api of the cruise control
+	DefaultCruiseControlCredentialsVolumeName = "credentials"
+
+	// DefaultCruiseControlCPUCapacity is the num of the cpu cores of a broker if the brokers have no cpu resources
+	DefaultCruiseControlCPUCapacity = 1
+	// DefaultCruiseControlNetworkCapacity is the network throughput of a broker in bytes per second
+	DefaultCruiseControlNetworkCapacity = 10 * 1024 * 1024
+
+	// DefaultCruiseControlConfigHashAnnotation is the annotation of the hash of the configs of the cruise control
+	DefaultCruiseControlConfigHashAnnotation = "kafka.nineinfra.tech/cruise-control-config-hash"
 )
 
 var (
+	// DefaultKafkaVersion is the protocol version of the admin client if the version of the cluster is unknown
+	DefaultKafkaVersion = sarama.V3_0_0_0
+
+	// KafkaErrResourceNotFound is the RESOURCE_NOT_FOUND error which sarama does not define
+	KafkaErrResourceNotFound sarama.KError = 91
+
 	DefaultConfPath = fmt.Sprintf("%s/%s", DefaultKafkaHome, "conf")
 	DefaultDataPath = fmt.Sprintf("%s/%s", DefaultKafkaHome, "data")
 	DefaultLogPath  = fmt.Sprintf("%s/%s", DefaultKafkaHome, "logs")
+	// DefaultRuntimePath is where the start script renders the config of the pod
+	DefaultRuntimePath = fmt.Sprintf("%s/%s", DefaultKafkaHome, "runtime")
+	// DefaultTLSPath is where the keystores and the truststore of the brokers are mounted
+	DefaultTLSPath = fmt.Sprintf("%s/%s", DefaultKafkaHome, "tls")
+	// DefaultClientsCAPath is where the clients CA certificates are mounted
+	DefaultClientsCAPath = fmt.Sprintf("%s/%s", DefaultKafkaHome, "clients-ca")
+	// DefaultMetricsReporterPath is where the jar of the metrics reporter of the cruise control is copied for the brokers
+	DefaultMetricsReporterPath = fmt.Sprintf("%s/%s", DefaultKafkaHome, "metrics-reporter")
+
+	DefaultCruiseControlConfPath    = fmt.Sprintf("%s/%s", DefaultCruiseControlHome, "conf")
+	DefaultCruiseControlTLSPath     = fmt.Sprintf("%s/%s", DefaultCruiseControlHome, "tls")
+	DefaultCruiseControlRuntimePath = fmt.Sprintf("%s/%s", DefaultCruiseControlHome, "runtime")
+	// DefaultCruiseControlCredentialsPath is where the credentials of the rest api are mounted
+	DefaultCruiseControlCredentialsPath = fmt.Sprintf("%s/%s", DefaultCruiseControlHome, "credentials")
 )
 var DefaultClusterConfKeyValue = map[string]string{
-	"broker.id": strconv.Itoa(DefaultMaxBrokerID),
 	//"log.dirs":                                 DefaultDataPath,
 	//"listeners":                                "",
 	//"advertised.listeners":                     "",
@@ -144,9 +415,33 @@ var DefaultClusterConfKeyValue = map[string]string{
 	"log.retention.hours":                      strconv.Itoa(DefaultLogRetentionHours),
 	"log.segment.bytes":                        strconv.Itoa(DefaultLogSegmentSize),
 	"log.retention.check.interval.ms":          strconv.Itoa(DefaultLogRetentionCheckInterval),
+	"group.initial.rebalance.delay.ms":         strconv.Itoa(DefaultGroupInitialRebalanceDelay),
+}
+
+var DefaultZookeeperConfKeyValue = map[string]string{
 	//"zookeeper.connect":                        "",
-	"zookeeper.connection.timeout.ms":  strconv.Itoa(DefaultZKConnectionTimeout),
-	"group.initial.rebalance.delay.ms": strconv.Itoa(DefaultGroupInitialRebalanceDelay),
+	"zookeeper.connection.timeout.ms": strconv.Itoa(DefaultZKConnectionTimeout),
+}
+
+var DefaultKraftConfKeyValue = map[string]string{
+	"controller.listener.names": DefaultControllerPortName,
+}
+
+// DefaultCruiseControlConfKeyValue are the default configs of the cruise control, the connection to the cluster is set by the operator
+var DefaultCruiseControlConfKeyValue = map[string]string{
+	"webserver.http.address":                "0.0.0.0",
+	"webserver.api.urlprefix":               DefaultCruiseControlAPIPrefix + "/*",
+	"broker.capacity.config.resolver.class": "com.linkedin.kafka.cruisecontrol.config.BrokerCapacityConfigFileResolver",
+	"sample.store.class":                    "com.linkedin.kafka.cruisecontrol.monitor.sampling.KafkaSampleStore",
+	"partition.metric.sample.store.topic":   "__KafkaCruiseControlPartitionMetricSamples",
+	"broker.metric.sample.store.topic":      "__KafkaCruiseControlModelTrainingSamples",
+	"kafka.broker.failure.detection.enable": "true",
+	"partition.metrics.window.ms":           "300000",
+	"num.partition.metrics.windows":         "5",
+	"broker.metrics.window.ms":              "300000",
+	"num.broker.metrics.windows":            "20",
+	"metric.sampling.interval.ms":           "120000",
+	"min.valid.partition.ratio":             "0.95",
 }
 
 var DefaultLogConfKeyValue = map[string]string{
@@ -161,3 +456,87 @@ var DefaultLogConfKeyValue = map[string]string{
 	"log4j.appender.kafkaAppender.layout":                   "org.apache.log4j.PatternLayout",
 	"log4j.appender.kafkaAppender.layout.ConversionPattern": "[%d] %p %m (%c)%n",
 }
+
+const (
+	ConfigUpdateModeReadOnly    = "read-only"
+	ConfigUpdateModePerBroker   = "per-broker"
+	ConfigUpdateModeClusterWide = "cluster-wide"
+)
+
+// DynamicConfigUpdateModes are the update modes of the broker configs which can be updated without a restart.
+// configs managed by the operator such as the listeners are left out on purpose
+var DynamicConfigUpdateModes = map[string]string{
+	"background.threads":                       ConfigUpdateModeClusterWide,
+	"compression.type":                         ConfigUpdateModeClusterWide,
+	"log.cleaner.backoff.ms":                   ConfigUpdateModeClusterWide,
+	"log.cleaner.dedupe.buffer.size":           ConfigUpdateModeClusterWide,
+	"log.cleaner.delete.retention.ms":          ConfigUpdateModeClusterWide,
+	"log.cleaner.io.buffer.load.factor":        ConfigUpdateModeClusterWide,
+	"log.cleaner.io.buffer.size":               ConfigUpdateModeClusterWide,
+	"log.cleaner.io.max.bytes.per.second":      ConfigUpdateModeClusterWide,
+	"log.cleaner.max.compaction.lag.ms":        ConfigUpdateModeClusterWide,
+	"log.cleaner.min.cleanable.ratio":          ConfigUpdateModeClusterWide,
+	"log.cleaner.min.compaction.lag.ms":        ConfigUpdateModeClusterWide,
+	"log.cleaner.threads":                      ConfigUpdateModeClusterWide,
+	"log.cleanup.policy":                       ConfigUpdateModeClusterWide,
+	"log.flush.interval.messages":              ConfigUpdateModeClusterWide,
+	"log.flush.interval.ms":                    ConfigUpdateModeClusterWide,
+	"log.index.interval.bytes":                 ConfigUpdateModeClusterWide,
+	"log.index.size.max.bytes":                 ConfigUpdateModeClusterWide,
+	"log.message.downconversion.enable":        ConfigUpdateModeClusterWide,
+	"log.message.timestamp.difference.max.ms":  ConfigUpdateModeClusterWide,
+	"log.message.timestamp.type":               ConfigUpdateModeClusterWide,
+	"log.preallocate":                          ConfigUpdateModeClusterWide,
+	"log.retention.bytes":                      ConfigUpdateModeClusterWide,
+	"log.retention.ms":                         ConfigUpdateModeClusterWide,
+	"log.roll.jitter.ms":                       ConfigUpdateModeClusterWide,
+	"log.roll.ms":                              ConfigUpdateModeClusterWide,
+	"log.segment.bytes":                        ConfigUpdateModeClusterWide,
+	"log.segment.delete.delay.ms":              ConfigUpdateModeClusterWide,
+	"max.connection.creation.rate":             ConfigUpdateModeClusterWide,
+	"max.connections":                          ConfigUpdateModeClusterWide,
+	"max.connections.per.ip":                   ConfigUpdateModeClusterWide,
+	"max.connections.per.ip.overrides":         ConfigUpdateModeClusterWide,
+	"message.max.bytes":                        ConfigUpdateModeClusterWide,
+	"metric.reporters":                         ConfigUpdateModeClusterWide,
+	"min.insync.replicas":                      ConfigUpdateModeClusterWide,
+	"num.io.threads":                           ConfigUpdateModeClusterWide,
+	"num.network.threads":                      ConfigUpdateModeClusterWide,
+	"num.recovery.threads.per.data.dir":        ConfigUpdateModeClusterWide,
+	"num.replica.fetchers":                     ConfigUpdateModeClusterWide,
+	"unclean.leader.election.enable":           ConfigUpdateModeClusterWide,
+	"principal.builder.class":                  ConfigUpdateModePerBroker,
+	"sasl.enabled.mechanisms":                  ConfigUpdateModePerBroker,
+	"sasl.jaas.config":                         ConfigUpdateModePerBroker,
+	"sasl.kerberos.kinit.cmd":                  ConfigUpdateModePerBroker,
+	"sasl.kerberos.min.time.before.relogin":    ConfigUpdateModePerBroker,
+	"sasl.kerberos.principal.to.local.rules":   ConfigUpdateModePerBroker,
+	"sasl.kerberos.service.name":               ConfigUpdateModePerBroker,
+	"sasl.kerberos.ticket.renew.jitter":        ConfigUpdateModePerBroker,
+	"sasl.kerberos.ticket.renew.window.factor": ConfigUpdateModePerBroker,
+	"sasl.login.refresh.buffer.seconds":        ConfigUpdateModePerBroker,
+	"sasl.login.refresh.min.period.seconds":    ConfigUpdateModePerBroker,
+	"sasl.login.refresh.window.factor":         ConfigUpdateModePerBroker,
+	"sasl.login.refresh.window.jitter":         ConfigUpdateModePerBroker,
+	"sasl.mechanism.inter.broker.protocol":     ConfigUpdateModePerBroker,
+	"ssl.cipher.suites":                        ConfigUpdateModePerBroker,
+	"ssl.client.auth":                          ConfigUpdateModePerBroker,
+	"ssl.enabled.protocols":                    ConfigUpdateModePerBroker,
+	"ssl.endpoint.identification.algorithm":    ConfigUpdateModePerBroker,
+	"ssl.engine.factory.class":                 ConfigUpdateModePerBroker,
+	"ssl.key.password":                         ConfigUpdateModePerBroker,
+	"ssl.keymanager.algorithm":                 ConfigUpdateModePerBroker,
+	"ssl.keystore.certificate.chain":           ConfigUpdateModePerBroker,
+	"ssl.keystore.key":                         ConfigUpdateModePerBroker,
+	"ssl.keystore.location":                    ConfigUpdateModePerBroker,
+	"ssl.keystore.password":                    ConfigUpdateModePerBroker,
+	"ssl.keystore.type":                        ConfigUpdateModePerBroker,
+	"ssl.protocol":                             ConfigUpdateModePerBroker,
+	"ssl.provider":                             ConfigUpdateModePerBroker,
+	"ssl.secure.random.implementation":         ConfigUpdateModePerBroker,
+	"ssl.trustmanager.algorithm":               ConfigUpdateModePerBroker,
+	"ssl.truststore.certificates":              ConfigUpdateModePerBroker,
+	"ssl.truststore.location":                  ConfigUpdateModePerBroker,
+	"ssl.truststore.password":                  ConfigUpdateModePerBroker,
+	"ssl.truststore.type":                      ConfigUpdateModePerBroker,
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// the states of the user tasks of the cruise control
+const (
+	UserTaskActive             = "Active"
+	UserTaskInExecution        = "InExecution"
+	UserTaskCompleted          = "Completed"
+	UserTaskCompletedWithError = "CompletedWithError"
+
+	// ExecutorNoTaskInProgress is the state of the executor of the cruise control once it is not moving any replica
+	ExecutorNoTaskInProgress = "NO_TASK_IN_PROGRESS"
+)
+
+// cruiseControlClient calls the rest api of the cruise control as the operator user. the requests taking long are
+// answered with 202 Accepted and the id of their user task, which is sent again to get the result once it is done
+type cruiseControlClient struct {
+	url      string
+	password string
+	client   *http.Client
+}
+
+func newCruiseControlClient(url string, password string) *cruiseControlClient {
+	return &cruiseControlClient{
+		url:      strings.TrimSuffix(url, "/"),
+		password: password,
+		client:   &http.Client{Timeout: DefaultCruiseControlTimeout},
+	}
+}
+
+type cruiseControlState struct {
+	MonitorState struct {
+		State                 string  `json:"state"`
+		NumMonitoredWindows   int32   `json:"numMonitoredWindows"`
+		MonitoringCoveragePct float64 `json:"monitoringCoveragePct"`
+	} `json:"MonitorState"`
+	ExecutorState struct {
+		State string `json:"state"`
+	} `json:"ExecutorState"`
+	AnalyzerState struct {
+		IsProposalReady bool `json:"isProposalReady"`
+	} `json:"AnalyzerState"`
+}
+
+type cruiseControlProposal struct {
+	TopicPartition struct {
+		Topic     string `json:"topic"`
+		Partition int32  `json:"partition"`
+	} `json:"topicPartition"`
+	OldReplicas []int32 `json:"oldReplicas"`
+	NewReplicas []int32 `json:"newReplicas"`
+}
+
+type cruiseControlBrokerLoad struct {
+	Broker   int32   `json:"Broker"`
+	Replicas int64   `json:"Replicas"`
+	DiskMB   float64 `json:"DiskMB"`
+}
+
+type cruiseControlClusterLoad struct {
+	Brokers []cruiseControlBrokerLoad `json:"brokers"`
+}
+
+// cruiseControlOptimizationResult is the result of a rebalance, the proposals and the loads are only returned by the verbose requests
+type cruiseControlOptimizationResult struct {
+	Summary struct {
+		NumReplicaMovements int32   `json:"numReplicaMovements"`
+		NumLeaderMovements  int32   `json:"numLeaderMovements"`
+		DataToMoveMB        float64 `json:"dataToMoveMB"`
+	} `json:"summary"`
+	Proposals              []cruiseControlProposal   `json:"proposals"`
+	LoadBeforeOptimization *cruiseControlClusterLoad `json:"loadBeforeOptimization"`
+	LoadAfterOptimization  *cruiseControlClusterLoad `json:"loadAfterOptimization"`
+}
+
+type cruiseControlUserTask struct {
+	UserTaskID string `json:"UserTaskId"`
+	Status     string `json:"Status"`
+	RequestURL string `json:"RequestURL"`
+}
+
+// do sends the request, it returns the body and the id of the user task, or a nil body if the task is still in progress
+func (c *cruiseControlClient) do(method string, endpoint string, params url.Values, userTaskID string) ([]byte, string, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("json", "true")
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/%s?%s", c.url, endpoint, params.Encode()), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.SetBasicAuth(DefaultCruiseControlUsername, c.password)
+	if userTaskID != "" {
+		req.Header.Set(DefaultUserTaskIDHeader, userTaskID)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	taskID := resp.Header.Get(DefaultUserTaskIDHeader)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, taskID, nil
+	case http.StatusAccepted:
+		return nil, taskID, nil
+	}
+	var failure struct {
+		ErrorMessage string `json:"errorMessage"`
+	}
+	if json.Unmarshal(body, &failure) == nil && failure.ErrorMessage != "" {
+		return nil, taskID, fmt.Errorf("cruise control %s failed with %d: %s", endpoint, resp.StatusCode, failure.ErrorMessage)
+	}
+	return nil, taskID, fmt.Errorf("cruise control %s failed with %d", endpoint, resp.StatusCode)
+}
+
+func (c *cruiseControlClient) state() (*cruiseControlState, error) {
+	params := url.Values{}
+	params.Set("substates", "monitor,executor,analyzer")
+	body, _, err := c.do(http.MethodGet, "state", params, "")
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, fmt.Errorf("cruise control state is not available yet")
+	}
+	state := &cruiseControlState{}
+	if err = json.Unmarshal(body, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// rebalance starts a rebalance, or gets the result of the user task of a rebalance started before.
+// the result is nil while the task is in progress
+func (c *cruiseControlClient) rebalance(params url.Values, userTaskID string) (*cruiseControlOptimizationResult, string, error) {
+	body, taskID, err := c.do(http.MethodPost, "rebalance", params, userTaskID)
+	if err != nil || body == nil {
+		return nil, taskID, err
+	}
+	result := &cruiseControlOptimizationResult{}
+	if err = json.Unmarshal(body, result); err != nil {
+		return nil, taskID, err
+	}
+	return result, taskID, nil
+}
+
+// userTask returns the user task, nil if the cruise control does not know it, for example after a restart
+func (c *cruiseControlClient) userTask(userTaskID string) (*cruiseControlUserTask, error) {
+	params := url.Values{}
+	params.Set("user_task_ids", userTaskID)
+	body, _, err := c.do(http.MethodGet, "user_tasks", params, "")
+	if err != nil || body == nil {
+		return nil, err
+	}
+	var tasks struct {
+		UserTasks []cruiseControlUserTask `json:"userTasks"`
+	}
+	if err = json.Unmarshal(body, &tasks); err != nil {
+		return nil, err
+	}
+	for i := range tasks.UserTasks {
+		if tasks.UserTasks[i].UserTaskID == userTaskID {
+			return &tasks.UserTasks[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// stopProposalExecution stops moving the replicas, the partitions already moved are kept
+func (c *cruiseControlClient) stopProposalExecution() error {
+	_, _, err := c.do(http.MethodPost, "stop_proposal_execution", nil, "")
+	return err
+}
@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+// newCruiseControlStub serves the rest api of the cruise control used by the operator. the rebalance is computed in
+// the task stub-task, which is in progress until it is asked for with its id
+func newCruiseControlStub(t *testing.T) (*httptest.Server, *[]*http.Request) {
+	requests := make([]*http.Request, 0)
+	mux := http.NewServeMux()
+	mux.HandleFunc(DefaultCruiseControlAPIPrefix+"/rebalance", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set(DefaultUserTaskIDHeader, "stub-task")
+		if req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if req.Header.Get(DefaultUserTaskIDHeader) == "" {
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"progress":[{"operation":"Rebalance"}],"version":1}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"summary": {"numReplicaMovements": 2, "numLeaderMovements": 1, "dataToMoveMB": 3},
+			"proposals": [
+				{"topicPartition": {"topic": "orders", "partition": 1}, "oldLeader": 0, "oldReplicas": [0, 1], "newReplicas": [2, 1]},
+				{"topicPartition": {"topic": "events", "partition": 0}, "oldLeader": 1, "oldReplicas": [1, 0], "newReplicas": [2, 0]}
+			],
+			"loadBeforeOptimization": {"brokers": [{"Broker": 0, "Replicas": 4}, {"Broker": 1, "Replicas": 4}, {"Broker": 2, "Replicas": 0}]},
+			"loadAfterOptimization": {"brokers": [{"Broker": 0, "Replicas": 3}, {"Broker": 1, "Replicas": 3}, {"Broker": 2, "Replicas": 2}]},
+			"version": 1
+		}`))
+	})
+	mux.HandleFunc(DefaultCruiseControlAPIPrefix+"/user_tasks", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"userTasks":[{"UserTaskId":"stub-task","Status":"InExecution","RequestURL":"POST /rebalance"}],"version":1}`))
+	})
+	mux.HandleFunc(DefaultCruiseControlAPIPrefix+"/state", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"MonitorState": {"state": "RUNNING", "numMonitoredWindows": 5, "monitoringCoveragePct": 100.0},
+			"ExecutorState": {"state": "NO_TASK_IN_PROGRESS"},
+			"AnalyzerState": {"isProposalReady": true},
+			"version": 1
+		}`))
+	})
+	mux.HandleFunc(DefaultCruiseControlAPIPrefix+"/stop_proposal_execution", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"errorMessage":"stub failure","version":1}`))
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req)
+		if username, password, ok := req.BasicAuth(); !ok || username != DefaultCruiseControlUsername || password != "stub-password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, req)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestCruiseControlClientRebalance(t *testing.T) {
+	g := NewWithT(t)
+	server, requests := newCruiseControlStub(t)
+	cc := newCruiseControlClient(server.URL+DefaultCruiseControlAPIPrefix+"/", "stub-password")
+
+	rebalance := &kafkav1.KafkaRebalance{
+		Spec: kafkav1.KafkaRebalanceSpec{Goals: []string{"RackAwareGoal", "DiskUsageDistributionGoal"}},
+	}
+	params := getCruiseControlRebalanceParams(rebalance, &kafkav1.KafkaCluster{}, true)
+	result, taskID, err := cc.rebalance(params, "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(BeNil())
+	g.Expect(taskID).To(Equal("stub-task"))
+	query := (*requests)[0].URL.Query()
+	g.Expect(query.Get("json")).To(Equal("true"))
+	g.Expect(query.Get("dryrun")).To(Equal("true"))
+	g.Expect(query.Get("goals")).To(Equal("RackAwareGoal,DiskUsageDistributionGoal"))
+	g.Expect(query.Has("replication_throttle")).To(BeFalse())
+
+	result, _, err = cc.rebalance(params, taskID)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).NotTo(BeNil())
+	g.Expect((*requests)[1].Header.Get(DefaultUserTaskIDHeader)).To(Equal("stub-task"))
+
+	proposal := getCruiseControlProposal(result)
+	g.Expect(proposal.PartitionMovements).To(BeEquivalentTo(2))
+	g.Expect(proposal.ReplicaMovements).To(BeEquivalentTo(2))
+	g.Expect(proposal.DataToMoveBytes).To(BeEquivalentTo(3 * 1024 * 1024))
+	g.Expect(proposal.Movements[0].Topic).To(Equal("events"))
+	g.Expect(proposal.Movements[1].From).To(Equal([]int32{0, 1}))
+	g.Expect(proposal.Movements[1].To).To(Equal([]int32{2, 1}))
+	g.Expect(proposal.Plan).To(HaveLen(3))
+	g.Expect(proposal.Plan[2].Before).To(BeEquivalentTo(0))
+	g.Expect(proposal.Plan[2].After).To(BeEquivalentTo(2))
+}
+
+func TestCruiseControlClientTasks(t *testing.T) {
+	g := NewWithT(t)
+	server, _ := newCruiseControlStub(t)
+	cc := newCruiseControlClient(server.URL+DefaultCruiseControlAPIPrefix, "stub-password")
+
+	task, err := cc.userTask("stub-task")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(task).NotTo(BeNil())
+	g.Expect(task.Status).To(Equal(UserTaskInExecution))
+
+	task, err = cc.userTask("unknown-task")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(task).To(BeNil())
+
+	state, err := cc.state()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.ExecutorState.State).To(Equal(ExecutorNoTaskInProgress))
+	g.Expect(state.AnalyzerState.IsProposalReady).To(BeTrue())
+
+	err = cc.stopProposalExecution()
+	g.Expect(err).To(MatchError(ContainSubstring("stub failure")))
+
+	_, err = newCruiseControlClient(server.URL+DefaultCruiseControlAPIPrefix, "wrong-password").state()
+	g.Expect(err).To(MatchError(ContainSubstring("401")))
+}
+
+func TestVerifyCruiseControlExecution(t *testing.T) {
+	g := NewWithT(t)
+	server, _ := newCruiseControlStub(t)
+	cc := newCruiseControlClient(server.URL+DefaultCruiseControlAPIPrefix, "stub-password")
+	result, _, err := cc.rebalance(url.Values{}, "stub-task")
+	g.Expect(err).NotTo(HaveOccurred())
+	r := &KafkaRebalanceReconciler{}
+
+	rebalance := &kafkav1.KafkaRebalance{Status: kafkav1.KafkaRebalanceStatus{
+		State:    kafkav1.RebalanceStateRebalancing,
+		Proposal: getCruiseControlProposal(result),
+	}}
+	g.Expect(r.verifyCruiseControlExecution(cc, rebalance, result, logr.Discard())).To(Succeed())
+	g.Expect(rebalance.Status.State).To(Equal(kafkav1.RebalanceStateRebalancing))
+
+	// a differing proposal is stopped, the stub fails the stop so the rebalance stays as it is
+	rebalance.Status.Proposal.Movements[0].To = []int32{1, 0}
+	g.Expect(r.verifyCruiseControlExecution(cc, rebalance, result, logr.Discard())).NotTo(Succeed())
+	g.Expect(rebalance.Status.State).To(Equal(kafkav1.RebalanceStateRebalancing))
+
+	stopped := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"message":"Proposal execution stopped.","version":1}`))
+	}))
+	t.Cleanup(stopped.Close)
+	rebalance.Status.UserTaskID = "stub-task"
+	g.Expect(r.verifyCruiseControlExecution(newCruiseControlClient(stopped.URL, "stub-password"), rebalance, result, logr.Discard())).To(Succeed())
+	g.Expect(rebalance.Status.State).To(Equal(kafkav1.RebalanceStateProposalReady))
+	g.Expect(rebalance.Status.UserTaskID).To(BeEmpty())
+	g.Expect(isSameProposal(rebalance.Status.Proposal, getCruiseControlProposal(result))).To(BeTrue())
+}
+
+func TestReconcileCruiseControlCredentials(t *testing.T) {
+	g := NewWithT(t)
+	cluster := &kafkav1.KafkaCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec: kafkav1.KafkaClusterSpec{CruiseControl: &kafkav1.CruiseControlConfig{
+			Conf: map[string]string{"webserver.security.enable": "false"},
+		}},
+	}
+	r := newFakeReconciler(cluster)
+
+	_, err := getCruiseControlClient(r.Client, cluster)
+	g.Expect(err).To(HaveOccurred())
+	password, err := r.reconcileCruiseControlCredentials(cluster, logr.Discard())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(password).NotTo(BeEmpty())
+
+	// the password is kept once generated
+	kept, err := r.reconcileCruiseControlCredentials(cluster, logr.Discard())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(kept).To(Equal(password))
+	secret := &corev1.Secret{}
+	g.Expect(r.Client.Get(context.TODO(), types.NamespacedName{Name: GetCruiseControlCredentialsSecretName(cluster), Namespace: cluster.Namespace}, secret)).To(Succeed())
+	g.Expect(string(secret.Data[DefaultCruiseControlCredentialsFileName])).To(Equal(DefaultCruiseControlUsername + ": " + password + ",ADMIN\n"))
+	g.Expect(metav1.IsControlledBy(secret, cluster)).To(BeTrue())
+
+	cc, err := getCruiseControlClient(r.Client, cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cc.password).To(Equal(password))
+
+	// the security of the rest api can not be disabled by the configs
+	conf := constructCruiseControlConfKeyValue(cluster)
+	g.Expect(conf["webserver.security.enable"]).To(Equal("true"))
+	g.Expect(conf["webserver.auth.credentials.file"]).To(Equal(DefaultCruiseControlCredentialsPath + "/" + DefaultCruiseControlCredentialsFileName))
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/IBM/sarama"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func GetBootstrapServers(cluster *kafkav1.KafkaCluster) string {
+	return fmt.Sprintf("%s:%d", GetFullSvcName(cluster), DefaultInternalPort)
+}
+
+// GetAdminBootstrapServers returns the bootstrap servers of the listener used by the operator, the replication
+// listener is only published by the headless service so it stays out of the reach of the clients of the service
+func GetAdminBootstrapServers(cluster *kafkav1.KafkaCluster) string {
+	if HasReplicationListener(cluster) {
+		return fmt.Sprintf("%s:%d", GetFullHeadlessSvcName(cluster), DefaultReplicationPort)
+	}
+	return GetBootstrapServers(cluster)
+}
+
+func getKafkaVersion(cluster *kafkav1.KafkaCluster) sarama.KafkaVersion {
+	version, err := sarama.ParseKafkaVersion(strings.TrimPrefix(getImageConfig(cluster).Tag, "v"))
+	if err != nil {
+		version, err = sarama.ParseKafkaVersion(strings.TrimPrefix(cluster.Spec.Version, "v"))
+		if err != nil {
+			return DefaultKafkaVersion
+		}
+	}
+	return version
+}
+
+// newKafkaAdmin connects to the internal listener of the cluster, or the replication listener if it exists.
+// The caller must close the admin
+func newKafkaAdmin(c client.Client, cluster *kafkav1.KafkaCluster) (sarama.ClusterAdmin, error) {
+	tlsConfig, err := getAdminTLSConfig(c, cluster)
+	if err != nil {
+		return nil, err
+	}
+	config := sarama.NewConfig()
+	if tlsConfig != nil {
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+	config.ClientID = DefaultAdminClientID
+	config.Version = getKafkaVersion(cluster)
+	config.Admin.Timeout = DefaultAdminTimeout
+	config.Net.DialTimeout = DefaultAdminTimeout
+	config.Metadata.Retry.Max = 1
+	return sarama.NewClusterAdmin([]string{GetAdminBootstrapServers(cluster)}, config)
+}
+
+// getBrokerIDOfPod finds the id of the broker advertising the address of the pod
+func getBrokerIDOfPod(brokers []*sarama.Broker, podName string) (int32, bool) {
+	for _, b := range brokers {
+		host := strings.Split(b.Addr(), ":")[0]
+		if host == podName || strings.HasPrefix(host, podName+".") {
+			return b.ID(), true
+		}
+	}
+	return 0, false
+}
+
+// describeAllTopics returns the metadata of all the topics in the cluster including the internal ones
+func describeAllTopics(admin sarama.ClusterAdmin) ([]*sarama.TopicMetadata, error) {
+	topics, err := admin.ListTopics()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	return admin.DescribeTopics(names)
+}
+
+func containsBroker(brokers []int32, id int32) bool {
+	for _, b := range brokers {
+		if b == id {
+			return true
+		}
+	}
+	return false
+}
+
+// getUnderReplicatedPartitions returns the num of the under replicated partitions
+// and the num of the partitions whose isr is missing the broker
+func getUnderReplicatedPartitions(topics []*sarama.TopicMetadata, brokerID int32) (int, int) {
+	underReplicated, outOfSync := 0, 0
+	for _, t := range topics {
+		for _, p := range t.Partitions {
+			if len(p.Isr) < len(p.Replicas) {
+				underReplicated++
+			}
+			if containsBroker(p.Replicas, brokerID) && !containsBroker(p.Isr, brokerID) {
+				outOfSync++
+			}
+		}
+	}
+	return underReplicated, outOfSync
+}
+
+// isKafkaError returns true if the error returned by the admin is the kafka error
+func isKafkaError(err error, kerr sarama.KError) bool {
+	return errors.Is(err, kerr)
+}
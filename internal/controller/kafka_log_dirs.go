@@ -0,0 +1,427 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/IBM/sarama"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+const (
+	alterReplicaLogDirsKey     int16 = 34
+	alterReplicaLogDirsVersion int16 = 1
+	// maxLogDirsResponseSize bounds the response read from a broker, which only lists the partitions of the request
+	maxLogDirsResponseSize = 16 * 1024 * 1024
+)
+
+// logDirAssignment is the partitions moved onto the log dirs of a broker, by the dirs and the topics
+type logDirAssignment map[string]map[string][]int32
+
+func (a logDirAssignment) add(dir string, topic string, partition int32) {
+	if _, ok := a[dir]; !ok {
+		a[dir] = make(map[string][]int32)
+	}
+	a[dir][topic] = append(a[dir][topic], partition)
+}
+
+// logDirsEncoder encodes the fields of the non flexible versions of the kafka protocol
+type logDirsEncoder struct {
+	bytes.Buffer
+}
+
+func (e *logDirsEncoder) putInt16(v int16) {
+	_ = binary.Write(&e.Buffer, binary.BigEndian, v)
+}
+
+func (e *logDirsEncoder) putInt32(v int32) {
+	_ = binary.Write(&e.Buffer, binary.BigEndian, v)
+}
+
+func (e *logDirsEncoder) putString(v string) {
+	e.putInt16(int16(len(v)))
+	e.WriteString(v)
+}
+
+// logDirsDecoder decodes the fields of the non flexible versions of the kafka protocol, the first error is kept
+type logDirsDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *logDirsDecoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.buf) {
+		d.err = fmt.Errorf("malformed AlterReplicaLogDirs response")
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *logDirsDecoder) getInt16() int16 {
+	if b := d.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *logDirsDecoder) getInt32() int32 {
+	if b := d.take(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *logDirsDecoder) getString() string {
+	return string(d.take(int(d.getInt16())))
+}
+
+// encodeAlterReplicaLogDirsRequest encodes the request with its size, the dirs and the topics are sorted
+func encodeAlterReplicaLogDirsRequest(correlationID int32, assignment logDirAssignment) []byte {
+	e := &logDirsEncoder{}
+	e.putInt16(alterReplicaLogDirsKey)
+	e.putInt16(alterReplicaLogDirsVersion)
+	e.putInt32(correlationID)
+	e.putString(DefaultAdminClientID)
+	e.putInt32(int32(len(assignment)))
+	for _, dir := range sortedKeys(assignment) {
+		e.putString(dir)
+		e.putInt32(int32(len(assignment[dir])))
+		for _, topic := range sortedKeys(assignment[dir]) {
+			e.putString(topic)
+			e.putInt32(int32(len(assignment[dir][topic])))
+			for _, partition := range assignment[dir][topic] {
+				e.putInt32(partition)
+			}
+		}
+	}
+	request := &logDirsEncoder{}
+	request.putInt32(int32(e.Len()))
+	request.Write(e.Bytes())
+	return request.Bytes()
+}
+
+// decodeAlterReplicaLogDirsResponse returns the errors of the partitions by the topics
+func decodeAlterReplicaLogDirsResponse(correlationID int32, body []byte) (map[string]map[int32]sarama.KError, error) {
+	d := &logDirsDecoder{buf: body}
+	if id := d.getInt32(); d.err == nil && id != correlationID {
+		return nil, fmt.Errorf("unexpected correlation id %d of the AlterReplicaLogDirs response", id)
+	}
+	// throttle time
+	d.getInt32()
+	results := make(map[string]map[int32]sarama.KError)
+	for i, topics := int32(0), d.getInt32(); i < topics && d.err == nil; i++ {
+		topic := d.getString()
+		if _, ok := results[topic]; !ok {
+			results[topic] = make(map[int32]sarama.KError)
+		}
+		for j, partitions := int32(0), d.getInt32(); j < partitions && d.err == nil; j++ {
+			partition := d.getInt32()
+			results[topic][partition] = sarama.KError(d.getInt16())
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return results, nil
+}
+
+// alterReplicaLogDirs moves the replicas of the broker onto the log dirs, a replica moved onto its current dir
+// cancels its movement. sarama has no AlterReplicaLogDirs, so the request is sent on a connection of its own
+func alterReplicaLogDirs(addr string, tlsConfig *tls.Config, assignment logDirAssignment) (map[string]map[int32]sarama.KError, error) {
+	dialer := &net.Dialer{Timeout: DefaultAdminTimeout}
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(DefaultAdminTimeout)); err != nil {
+		return nil, err
+	}
+	const correlationID = 1
+	if _, err = conn.Write(encodeAlterReplicaLogDirsRequest(correlationID, assignment)); err != nil {
+		return nil, err
+	}
+	var size int32
+	if err = binary.Read(conn, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size < 0 || size > maxLogDirsResponseSize {
+		return nil, fmt.Errorf("invalid size %d of the AlterReplicaLogDirs response", size)
+	}
+	body := make([]byte, size)
+	if _, err = io.ReadFull(conn, body); err != nil {
+		return nil, err
+	}
+	return decodeAlterReplicaLogDirsResponse(correlationID, body)
+}
+
+// replicaLogDir is the replica of the partition of the topic on the broker
+type replicaLogDir struct {
+	topic     string
+	partition int32
+	broker    int32
+}
+
+// logDirState is the log dir of a replica, and the one it is being moved onto
+type logDirState struct {
+	current string
+	future  string
+}
+
+// describeReplicaLogDirs returns the log dirs of the replicas on the brokers, the offline dirs are skipped
+func describeReplicaLogDirs(admin sarama.ClusterAdmin, brokers []int32) (map[replicaLogDir]logDirState, error) {
+	states := make(map[replicaLogDir]logDirState)
+	if len(brokers) == 0 {
+		return states, nil
+	}
+	logDirs, err := admin.DescribeLogDirs(brokers)
+	if err != nil {
+		return nil, err
+	}
+	for id, dirs := range logDirs {
+		for _, dir := range dirs {
+			if dir.ErrorCode != sarama.ErrNoError {
+				continue
+			}
+			for _, t := range dir.Topics {
+				for _, p := range t.Partitions {
+					ref := replicaLogDir{topic: t.Topic, partition: p.PartitionID, broker: id}
+					state := states[ref]
+					if p.IsTemporary {
+						state.future = dir.Path
+					} else {
+						state.current = dir.Path
+					}
+					states[ref] = state
+				}
+			}
+		}
+	}
+	return states, nil
+}
+
+// getMovedLogDir returns the log dir the replica of the movement is moved onto, empty if it stays on its disk
+func getMovedLogDir(m kafkav1.PartitionMovement, i int) string {
+	if i >= len(m.LogDirs) || m.LogDirs[i] == DefaultAnyLogDir {
+		return ""
+	}
+	return m.LogDirs[i]
+}
+
+// getLogDirBrokers returns the brokers whose replicas are moved between their disks by the movements
+func getLogDirBrokers(movements []kafkav1.PartitionMovement) []int32 {
+	brokers := make([]int32, 0)
+	for _, m := range movements {
+		for i, id := range m.To {
+			if getMovedLogDir(m, i) != "" && !containsBroker(brokers, id) {
+				brokers = append(brokers, id)
+			}
+		}
+	}
+	sort.Slice(brokers, func(i, j int) bool { return brokers[i] < brokers[j] })
+	return brokers
+}
+
+// isLogDirMoving returns true if any replica of the movement is being copied onto another disk
+func isLogDirMoving(m kafkav1.PartitionMovement, states map[replicaLogDir]logDirState) bool {
+	for i, id := range m.To {
+		if getMovedLogDir(m, i) != "" && states[replicaLogDir{topic: m.Topic, partition: m.Partition, broker: id}].future != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isLogDirMoved returns true if the replicas of the movement are on their log dirs
+func isLogDirMoved(m kafkav1.PartitionMovement, states map[replicaLogDir]logDirState) bool {
+	for i, id := range m.To {
+		dir := getMovedLogDir(m, i)
+		if dir != "" && states[replicaLogDir{topic: m.Topic, partition: m.Partition, broker: id}].current != dir {
+			return false
+		}
+	}
+	return true
+}
+
+// getLogDirAssignments returns the log dirs the replicas of the movements are moved onto by the brokers
+func getLogDirAssignments(movements []kafkav1.PartitionMovement) map[int32]logDirAssignment {
+	assignments := make(map[int32]logDirAssignment)
+	for _, m := range movements {
+		for i, id := range m.To {
+			dir := getMovedLogDir(m, i)
+			if dir == "" {
+				continue
+			}
+			if _, ok := assignments[id]; !ok {
+				assignments[id] = make(logDirAssignment)
+			}
+			assignments[id].add(dir, m.Topic, m.Partition)
+		}
+	}
+	return assignments
+}
+
+// getLogDirCancellations returns the current log dirs of the replicas of the movements being copied, by the brokers
+func getLogDirCancellations(movements []kafkav1.PartitionMovement, states map[replicaLogDir]logDirState) map[int32]logDirAssignment {
+	assignments := make(map[int32]logDirAssignment)
+	for _, m := range movements {
+		for i, id := range m.To {
+			state := states[replicaLogDir{topic: m.Topic, partition: m.Partition, broker: id}]
+			if getMovedLogDir(m, i) == "" || state.future == "" || state.current == "" {
+				continue
+			}
+			if _, ok := assignments[id]; !ok {
+				assignments[id] = make(logDirAssignment)
+			}
+			assignments[id].add(state.current, m.Topic, m.Partition)
+		}
+	}
+	return assignments
+}
+
+// planLogDirRebalance plans the movements balancing the load of the disks of each broker, the heaviest replica
+// lightening the fullest disk without making the emptiest one the fullest is moved until none is left. the
+// partitions whose replicas are being copied between the disks stay. it returns the movements, the load of the
+// disks before and after them, and the bytes copied by them
+func planLogDirRebalance(topics []*sarama.TopicMetadata, logDirs map[int32][]sarama.DescribeLogDirsResponseDirMetadata,
+	brokers []int32, bySize bool) ([]kafkav1.PartitionMovement, []kafkav1.BrokerLoadStatus, int64) {
+	currents := make(map[string]replicaAssignment)
+	for _, t := range topics {
+		currents[t.Name] = getCurrentAssignment(t)
+	}
+	weight := func(size int64) int64 {
+		if bySize {
+			return size + 1
+		}
+		return 1
+	}
+	type replica struct {
+		ref  replicaRef
+		size int64
+	}
+	targets := make(map[replicaRef]map[int32]string)
+	plan := make([]kafkav1.BrokerLoadStatus, 0)
+	moved := int64(0)
+	sorted := append([]int32{}, brokers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for _, id := range sorted {
+		copying := make(map[replicaRef]bool)
+		for _, dir := range logDirs[id] {
+			for _, t := range dir.Topics {
+				for _, p := range t.Partitions {
+					if p.IsTemporary {
+						copying[replicaRef{topic: t.Topic, partition: p.PartitionID}] = true
+					}
+				}
+			}
+		}
+		paths := make([]string, 0)
+		load := make(map[string]int64)
+		movable := make(map[string][]replica)
+		for _, dir := range logDirs[id] {
+			if dir.ErrorCode != sarama.ErrNoError {
+				continue
+			}
+			paths = append(paths, dir.Path)
+			load[dir.Path] = 0
+			for _, t := range dir.Topics {
+				for _, p := range t.Partitions {
+					if p.IsTemporary {
+						continue
+					}
+					ref := replicaRef{topic: t.Topic, partition: p.PartitionID}
+					load[dir.Path] += weight(p.Size)
+					if containsBroker(currents[t.Topic][p.PartitionID], id) && !copying[ref] {
+						movable[dir.Path] = append(movable[dir.Path], replica{ref: ref, size: p.Size})
+					}
+				}
+			}
+		}
+		if len(paths) < 2 {
+			continue
+		}
+		sort.Strings(paths)
+		before := make(map[string]int64)
+		for _, path := range paths {
+			before[path] = load[path]
+			sort.SliceStable(movable[path], func(i, j int) bool {
+				a, b := movable[path][i], movable[path][j]
+				if a.size != b.size {
+					return a.size > b.size
+				}
+				return a.ref.topic < b.ref.topic || a.ref.topic == b.ref.topic && a.ref.partition < b.ref.partition
+			})
+		}
+		for {
+			fullest, emptiest := paths[0], paths[0]
+			for _, path := range paths {
+				if load[path] > load[fullest] {
+					fullest = path
+				}
+				if load[path] < load[emptiest] {
+					emptiest = path
+				}
+			}
+			index := -1
+			for i, r := range movable[fullest] {
+				if weight(r.size) < load[fullest]-load[emptiest] {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				break
+			}
+			r := movable[fullest][index]
+			movable[fullest] = append(movable[fullest][:index], movable[fullest][index+1:]...)
+			load[fullest] -= weight(r.size)
+			load[emptiest] += weight(r.size)
+			if _, ok := targets[r.ref]; !ok {
+				targets[r.ref] = make(map[int32]string)
+			}
+			targets[r.ref][id] = emptiest
+			moved += r.size
+		}
+		for _, path := range paths {
+			plan = append(plan, kafkav1.BrokerLoadStatus{Broker: id, LogDir: path, Before: before[path], After: load[path]})
+		}
+	}
+	refs := make([]replicaRef, 0, len(targets))
+	for ref := range targets {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].topic < refs[j].topic || refs[i].topic == refs[j].topic && refs[i].partition < refs[j].partition
+	})
+	movements := make([]kafkav1.PartitionMovement, 0, len(refs))
+	for _, ref := range refs {
+		replicas := currents[ref.topic][ref.partition]
+		m := kafkav1.PartitionMovement{Topic: ref.topic, Partition: ref.partition, From: replicas, To: replicas}
+		for _, id := range replicas {
+			dir, ok := targets[ref][id]
+			if !ok {
+				dir = DefaultAnyLogDir
+			}
+			m.LogDirs = append(m.LogDirs, dir)
+		}
+		movements = append(movements, m)
+	}
+	return movements, plan, moved
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/IBM/sarama"
+	. "github.com/onsi/gomega"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+// serveAlterReplicaLogDirs answers one AlterReplicaLogDirs request with the errors of the partitions, and returns
+// the body of the request
+func serveAlterReplicaLogDirs(t *testing.T, listener net.Listener, errs map[int32]sarama.KError) <-chan []byte {
+	requests := make(chan []byte, 1)
+	go func() {
+		defer close(requests)
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		var size int32
+		if err = binary.Read(conn, binary.BigEndian, &size); err != nil {
+			t.Error(err)
+			return
+		}
+		request := make([]byte, size)
+		if _, err = io.ReadFull(conn, request); err != nil {
+			t.Error(err)
+			return
+		}
+		requests <- request
+		response := &logDirsEncoder{}
+		response.putInt32(int32(binary.BigEndian.Uint32(request[4:8])))
+		response.putInt32(0)
+		response.putInt32(1)
+		response.putString("orders")
+		response.putInt32(int32(len(errs)))
+		for _, partition := range []int32{1, 2} {
+			response.putInt32(partition)
+			response.putInt16(int16(errs[partition]))
+		}
+		_ = binary.Write(conn, binary.BigEndian, int32(response.Len()))
+		_, _ = conn.Write(response.Bytes())
+	}()
+	return requests
+}
+
+func TestAlterReplicaLogDirs(t *testing.T) {
+	g := NewWithT(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+	requests := serveAlterReplicaLogDirs(t, listener, map[int32]sarama.KError{1: sarama.ErrNoError, 2: sarama.ErrKafkaStorageError})
+
+	assignment := make(logDirAssignment)
+	assignment.add("/data/disk1", "orders", 1)
+	assignment.add("/data/disk1", "orders", 2)
+	results, err := alterReplicaLogDirs(listener.Addr().String(), nil, assignment)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(results).To(Equal(map[string]map[int32]sarama.KError{"orders": {1: sarama.ErrNoError, 2: sarama.ErrKafkaStorageError}}))
+
+	d := &logDirsDecoder{buf: <-requests}
+	g.Expect(d.getInt16()).To(Equal(alterReplicaLogDirsKey))
+	g.Expect(d.getInt16()).To(Equal(alterReplicaLogDirsVersion))
+	d.getInt32()
+	g.Expect(d.getString()).To(Equal(DefaultAdminClientID))
+	g.Expect(d.getInt32()).To(Equal(int32(1)))
+	g.Expect(d.getString()).To(Equal("/data/disk1"))
+	g.Expect(d.getInt32()).To(Equal(int32(1)))
+	g.Expect(d.getString()).To(Equal("orders"))
+	g.Expect(d.getInt32()).To(Equal(int32(2)))
+	g.Expect([]int32{d.getInt32(), d.getInt32()}).To(Equal([]int32{1, 2}))
+	g.Expect(d.err).NotTo(HaveOccurred())
+	g.Expect(d.buf).To(BeEmpty())
+
+	_, err = decodeAlterReplicaLogDirsResponse(1, []byte{0, 0, 0, 1, 0, 0})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestPlanLogDirRebalance(t *testing.T) {
+	g := NewWithT(t)
+	topics := []*sarama.TopicMetadata{
+		{Name: "orders", Partitions: []*sarama.PartitionMetadata{
+			{ID: 0, Replicas: []int32{0, 1}}, {ID: 1, Replicas: []int32{0, 1}}, {ID: 2, Replicas: []int32{1, 0}}, {ID: 3, Replicas: []int32{0, 1}},
+		}},
+	}
+	partitions := func(sizes map[int32]int64, temporary ...int32) []sarama.DescribeLogDirsResponseTopic {
+		t := sarama.DescribeLogDirsResponseTopic{Topic: "orders"}
+		for _, id := range []int32{0, 1, 2, 3} {
+			if size, ok := sizes[id]; ok {
+				t.Partitions = append(t.Partitions, sarama.DescribeLogDirsResponsePartition{PartitionID: id, Size: size})
+			}
+		}
+		for _, id := range temporary {
+			t.Partitions = append(t.Partitions, sarama.DescribeLogDirsResponsePartition{PartitionID: id, Size: 1, IsTemporary: true})
+		}
+		return []sarama.DescribeLogDirsResponseTopic{t}
+	}
+	logDirs := map[int32][]sarama.DescribeLogDirsResponseDirMetadata{
+		// all the replicas on the first disk
+		0: {
+			{Path: "/data/disk0", Topics: partitions(map[int32]int64{0: 400, 1: 300, 2: 200, 3: 100})},
+			{Path: "/data/disk1"},
+		},
+		// the replica of the partition 1 is being copied already
+		1: {
+			{Path: "/data/disk0", Topics: partitions(map[int32]int64{0: 400, 1: 300, 2: 200})},
+			{Path: "/data/disk1", Topics: partitions(map[int32]int64{3: 100}, 1)},
+			{Path: "/data/disk2", ErrorCode: sarama.ErrKafkaStorageError},
+		},
+	}
+
+	movements, plan, moved := planLogDirRebalance(topics, logDirs, []int32{0, 1}, true)
+	g.Expect(movements).To(Equal([]kafkav1.PartitionMovement{
+		{Topic: "orders", Partition: 0, From: []int32{0, 1}, To: []int32{0, 1}, LogDirs: []string{"/data/disk1", "/data/disk1"}},
+		{Topic: "orders", Partition: 2, From: []int32{1, 0}, To: []int32{1, 0}, LogDirs: []string{DefaultAnyLogDir, "/data/disk1"}},
+	}))
+	g.Expect(moved).To(Equal(int64(1000)))
+	g.Expect(plan).To(Equal([]kafkav1.BrokerLoadStatus{
+		{Broker: 0, LogDir: "/data/disk0", Before: 1004, After: 402},
+		{Broker: 0, LogDir: "/data/disk1", Before: 0, After: 602},
+		{Broker: 1, LogDir: "/data/disk0", Before: 903, After: 502},
+		{Broker: 1, LogDir: "/data/disk1", Before: 101, After: 502},
+	}))
+
+	// the disks balanced by the num of the replicas
+	movements, _, _ = planLogDirRebalance(topics, logDirs, []int32{0}, false)
+	g.Expect(movements).To(HaveLen(2))
+}
@@ -0,0 +1,599 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/IBM/sarama"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// replicaAssignment is the replicas of the partitions of a topic by the partition ids, the preferred leader first
+type replicaAssignment map[int32][]int32
+
+func getReassignmentThrottleRate(cluster *kafkav1.KafkaCluster) int64 {
+	if cluster.Spec.Reassignment != nil && cluster.Spec.Reassignment.ThrottleRate > 0 {
+		return cluster.Spec.Reassignment.ThrottleRate
+	}
+	return DefaultReassignmentThrottleRate
+}
+
+// isThrottleConfig returns true if the topic config is managed by the reassignments
+func isThrottleConfig(key string) bool {
+	return key == LeaderThrottledReplicasConfig || key == FollowerThrottledReplicasConfig
+}
+
+func getCurrentAssignment(metadata *sarama.TopicMetadata) replicaAssignment {
+	assignment := make(replicaAssignment)
+	for _, p := range metadata.Partitions {
+		assignment[p.ID] = append([]int32{}, p.Replicas...)
+	}
+	return assignment
+}
+
+func getBrokerRacks(brokers []*sarama.Broker) map[int32]string {
+	racks := make(map[int32]string)
+	for _, b := range brokers {
+		racks[b.ID()] = b.Rack()
+	}
+	return racks
+}
+
+// replicaSelector picks the replicas spreading them across the racks first and the brokers second
+type replicaSelector struct {
+	brokers []int32
+	racks   map[int32]string
+	load    map[int32]int
+}
+
+func newReplicaSelector(brokers []*sarama.Broker, assignments ...replicaAssignment) *replicaSelector {
+	s := &replicaSelector{
+		racks: getBrokerRacks(brokers),
+		load:  make(map[int32]int),
+	}
+	for _, b := range brokers {
+		s.brokers = append(s.brokers, b.ID())
+	}
+	sort.Slice(s.brokers, func(i, j int) bool { return s.brokers[i] < s.brokers[j] })
+	for _, assignment := range assignments {
+		for _, replicas := range assignment {
+			for _, id := range replicas {
+				s.load[id]++
+			}
+		}
+	}
+	return s
+}
+
+// rackUsage counts the replicas on the rack of the broker, the brokers without a rack share no rack
+func (s *replicaSelector) rackUsage(replicas []int32, id int32) int {
+	rack := s.racks[id]
+	if rack == "" {
+		return 0
+	}
+	count := 0
+	for _, r := range replicas {
+		if s.racks[r] == rack {
+			count++
+		}
+	}
+	return count
+}
+
+// better returns true if the broker a is a better replica than b for the replicas
+func (s *replicaSelector) better(replicas []int32, a int32, b int32) bool {
+	if ua, ub := s.rackUsage(replicas, a), s.rackUsage(replicas, b); ua != ub {
+		return ua < ub
+	}
+	if s.load[a] != s.load[b] {
+		return s.load[a] < s.load[b]
+	}
+	return a < b
+}
+
+// resize adds the least used brokers to the replicas or drops the most redundant ones but the leader
+func (s *replicaSelector) resize(replicas []int32, factor int) ([]int32, error) {
+	replicas = append([]int32{}, replicas...)
+	for len(replicas) < factor {
+		best := int32(-1)
+		for _, id := range s.brokers {
+			if containsBroker(replicas, id) {
+				continue
+			}
+			if best < 0 || s.better(replicas, id, best) {
+				best = id
+			}
+		}
+		if best < 0 {
+			return nil, fmt.Errorf("not enough brokers for %d replicas", factor)
+		}
+		replicas = append(replicas, best)
+		s.load[best]++
+	}
+	for len(replicas) > factor {
+		worst := 1
+		for i := 2; i < len(replicas); i++ {
+			if s.better(replicas, replicas[worst], replicas[i]) {
+				worst = i
+			}
+		}
+		s.load[replicas[worst]]--
+		replicas = append(replicas[:worst], replicas[worst+1:]...)
+	}
+	return replicas, nil
+}
+
+// planReplicationFactor returns the assignment of the topic with the replication factor, the existing
+// replicas are kept where possible so only the added replicas are copied
+func planReplicationFactor(metadata *sarama.TopicMetadata, brokers []*sarama.Broker, factor int) (replicaAssignment, error) {
+	if factor > len(brokers) {
+		return nil, fmt.Errorf("the replication factor %d is larger than the num of the brokers %d", factor, len(brokers))
+	}
+	current := getCurrentAssignment(metadata)
+	selector := newReplicaSelector(brokers, current)
+	target := make(replicaAssignment)
+	for _, id := range sortedPartitionIDs(current) {
+		replicas, err := selector.resize(current[id], factor)
+		if err != nil {
+			return nil, err
+		}
+		target[id] = replicas
+	}
+	return target, nil
+}
+
+// planBrokerRemoval returns the assignments of the topics hosting replicas on the removed brokers, the replicas
+// on the removed brokers are replaced by the least used remaining brokers and the others are kept in place
+func planBrokerRemoval(topics []*sarama.TopicMetadata, brokers []*sarama.Broker, removed []int32) (map[string]replicaAssignment, error) {
+	remaining := make([]*sarama.Broker, 0)
+	for _, b := range brokers {
+		if !containsBroker(removed, b.ID()) {
+			remaining = append(remaining, b)
+		}
+	}
+	currents := make(map[string]replicaAssignment)
+	for _, t := range topics {
+		currents[t.Name] = getCurrentAssignment(t)
+	}
+	loads := make([]replicaAssignment, 0, len(currents))
+	for _, current := range currents {
+		loads = append(loads, current)
+	}
+	selector := newReplicaSelector(remaining, loads...)
+	plans := make(map[string]replicaAssignment)
+	for _, name := range sortedKeys(currents) {
+		current := currents[name]
+		target := make(replicaAssignment)
+		moved := false
+		for _, id := range sortedPartitionIDs(current) {
+			kept := make([]int32, 0)
+			for _, b := range current[id] {
+				if !containsBroker(removed, b) {
+					kept = append(kept, b)
+				}
+			}
+			if len(kept) == len(current[id]) {
+				target[id] = current[id]
+				continue
+			}
+			replicas, err := selector.resize(kept, len(current[id]))
+			if err != nil {
+				return nil, fmt.Errorf("the partition %d of the topic %s can not be moved off the brokers %v: %v", id, name, removed, err)
+			}
+			target[id] = replicas
+			moved = true
+		}
+		if moved {
+			plans[name] = target
+		}
+	}
+	return plans, nil
+}
+
+// replicaRef is a replica of the partition of the topic
+type replicaRef struct {
+	topic     string
+	partition int32
+}
+
+// planRebalance returns the assignments of the topics moving replicas from the most loaded brokers to the least
+// loaded ones until no move makes the loads closer, along with the loads of the brokers before and after the moves.
+// a replica is not moved if that puts more replicas of the partition on the same rack
+func planRebalance(topics []*sarama.TopicMetadata, brokers []*sarama.Broker, weight func(string, int32) int64) (map[string]replicaAssignment, map[int32]int64, map[int32]int64) {
+	racks := getBrokerRacks(brokers)
+	// the topics are planned by the names so the same assignment results in the same plan
+	topics = append([]*sarama.TopicMetadata{}, topics...)
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Name < topics[j].Name })
+	load := make(map[int32]int64)
+	hosted := make(map[int32][]replicaRef)
+	// the replicas of each topic on each broker, so the replicas of a topic are spread too
+	spread := make(map[string]map[int32]int)
+	for _, b := range brokers {
+		load[b.ID()] = 0
+	}
+	assignments := make(map[string]replicaAssignment)
+	for _, t := range topics {
+		assignments[t.Name] = getCurrentAssignment(t)
+		spread[t.Name] = make(map[int32]int)
+		for _, p := range t.Partitions {
+			for _, id := range p.Replicas {
+				spread[t.Name][id]++
+				if _, ok := load[id]; ok {
+					load[id] += weight(t.Name, p.ID)
+					hosted[id] = append(hosted[id], replicaRef{topic: t.Name, partition: p.ID})
+				}
+			}
+		}
+	}
+	before := make(map[int32]int64)
+	for id, l := range load {
+		before[id] = l
+	}
+	rackUsage := func(replicas []int32, except int32, id int32) int {
+		count := 0
+		for _, r := range replicas {
+			if r != except && racks[id] != "" && racks[r] == racks[id] {
+				count++
+			}
+		}
+		return count
+	}
+	// findMove returns the index of the heaviest replica on the source which halves the difference at most,
+	// preferring the topics the source hosts more replicas of than the destination
+	findMove := func(src int32, dst int32) int {
+		best, bestWeight, bestSpread := -1, int64(0), 0
+		for i, ref := range hosted[src] {
+			replicas := assignments[ref.topic][ref.partition]
+			w := weight(ref.topic, ref.partition)
+			s := spread[ref.topic][src] - spread[ref.topic][dst]
+			if w < bestWeight || (w == bestWeight && s <= bestSpread) || 2*w > load[src]-load[dst] ||
+				containsBroker(replicas, dst) || rackUsage(replicas, src, dst) > rackUsage(replicas, src, src) {
+				continue
+			}
+			best, bestWeight, bestSpread = i, w, s
+		}
+		return best
+	}
+	moved := make(map[string]bool)
+	for {
+		ids := sortedKeysOfLoad(load)
+		found := false
+		for i := len(ids) - 1; i >= 0 && !found; i-- {
+			for j := 0; j < i && !found; j++ {
+				src, dst := ids[i], ids[j]
+				index := findMove(src, dst)
+				if index < 0 {
+					continue
+				}
+				ref := hosted[src][index]
+				replicas := assignments[ref.topic][ref.partition]
+				for k := range replicas {
+					if replicas[k] == src {
+						replicas[k] = dst
+					}
+				}
+				w := weight(ref.topic, ref.partition)
+				load[src] -= w
+				load[dst] += w
+				spread[ref.topic][src]--
+				spread[ref.topic][dst]++
+				hosted[src] = append(hosted[src][:index], hosted[src][index+1:]...)
+				hosted[dst] = append(hosted[dst], ref)
+				moved[ref.topic] = true
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	plans := make(map[string]replicaAssignment)
+	for name := range moved {
+		plans[name] = assignments[name]
+	}
+	return plans, before, load
+}
+
+// sortedKeysOfLoad returns the brokers from the least loaded to the most loaded one
+func sortedKeysOfLoad(load map[int32]int64) []int32 {
+	ids := make([]int32, 0, len(load))
+	for id := range load {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if load[ids[i]] != load[ids[j]] {
+			return load[ids[i]] < load[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+func sortedPartitionIDs(assignment replicaAssignment) []int32 {
+	ids := make([]int32, 0, len(assignment))
+	for id := range assignment {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// getThrottledReplicas returns the replicas throttled as leaders, which are the current ones,
+// and as followers, which are the added ones, in the format of the throttled replicas configs
+func getThrottledReplicas(current replicaAssignment, target replicaAssignment) (string, string) {
+	leaders, followers := make([]string, 0), make([]string, 0)
+	for _, id := range sortedPartitionIDs(target) {
+		if equalReplicas(current[id], target[id]) {
+			continue
+		}
+		for _, b := range current[id] {
+			leaders = append(leaders, fmt.Sprintf("%d:%d", id, b))
+		}
+		for _, b := range target[id] {
+			if !containsBroker(current[id], b) {
+				followers = append(followers, fmt.Sprintf("%d:%d", id, b))
+			}
+		}
+	}
+	return strings.Join(leaders, ","), strings.Join(followers, ",")
+}
+
+// getInvolvedBrokers returns the brokers sending or receiving the moved replicas
+func getInvolvedBrokers(current replicaAssignment, target replicaAssignment) []int32 {
+	involved := make([]int32, 0)
+	for id, replicas := range target {
+		if equalReplicas(current[id], replicas) {
+			continue
+		}
+		for _, b := range append(append([]int32{}, current[id]...), replicas...) {
+			if !containsBroker(involved, b) {
+				involved = append(involved, b)
+			}
+		}
+	}
+	sort.Slice(involved, func(i, j int) bool { return involved[i] < involved[j] })
+	return involved
+}
+
+func equalReplicas(a []int32, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// setReassignmentThrottles throttles the replication of the moved replicas on the involved brokers
+func setReassignmentThrottles(admin sarama.ClusterAdmin, topic string, current replicaAssignment, target replicaAssignment, rate int64) ([]int32, error) {
+	leaders, followers := getThrottledReplicas(current, target)
+	value := strconv.FormatInt(rate, 10)
+	brokers := getInvolvedBrokers(current, target)
+	for _, id := range brokers {
+		err := admin.IncrementalAlterConfig(sarama.BrokerResource, strconv.Itoa(int(id)), map[string]sarama.IncrementalAlterConfigsEntry{
+			LeaderThrottledRateConfig:   {Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &value},
+			FollowerThrottledRateConfig: {Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &value},
+		}, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := admin.IncrementalAlterConfig(sarama.TopicResource, topic, map[string]sarama.IncrementalAlterConfigsEntry{
+		LeaderThrottledReplicasConfig:   {Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &leaders},
+		FollowerThrottledReplicasConfig: {Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &followers},
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	return brokers, nil
+}
+
+// setLogDirThrottles throttles the copying of the replicas moved between the disks of the brokers
+func setLogDirThrottles(admin sarama.ClusterAdmin, brokers []int32, rate int64) error {
+	value := strconv.FormatInt(rate, 10)
+	for _, id := range brokers {
+		err := admin.IncrementalAlterConfig(sarama.BrokerResource, strconv.Itoa(int(id)), map[string]sarama.IncrementalAlterConfigsEntry{
+			LogDirThrottledRateConfig: {Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &value},
+		}, false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func clearTopicThrottles(admin sarama.ClusterAdmin, topic string) error {
+	return admin.IncrementalAlterConfig(sarama.TopicResource, topic, map[string]sarama.IncrementalAlterConfigsEntry{
+		LeaderThrottledReplicasConfig:   {Operation: sarama.IncrementalAlterConfigsOperationDelete},
+		FollowerThrottledReplicasConfig: {Operation: sarama.IncrementalAlterConfigsOperationDelete},
+	}, false)
+}
+
+func clearBrokerThrottles(admin sarama.ClusterAdmin, brokers []int32) error {
+	for _, id := range brokers {
+		err := admin.IncrementalAlterConfig(sarama.BrokerResource, strconv.Itoa(int(id)), map[string]sarama.IncrementalAlterConfigsEntry{
+			LeaderThrottledRateConfig:   {Operation: sarama.IncrementalAlterConfigsOperationDelete},
+			FollowerThrottledRateConfig: {Operation: sarama.IncrementalAlterConfigsOperationDelete},
+			LogDirThrottledRateConfig:   {Operation: sarama.IncrementalAlterConfigsOperationDelete},
+		}, false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// submitReassignment submits the assignment of all the partitions of the topic, since the admin
+// cancels the reassignments of the partitions left out
+func submitReassignment(admin sarama.ClusterAdmin, topic string, target replicaAssignment) error {
+	assignment := make([][]int32, len(target))
+	for id, replicas := range target {
+		if int(id) >= len(assignment) {
+			return fmt.Errorf("the assignment of the topic %s misses the partitions before %d", topic, id)
+		}
+		assignment[id] = replicas
+	}
+	return admin.AlterPartitionReassignments(topic, assignment)
+}
+
+func isSameObject(except client.Object, obj client.Object) bool {
+	return except != nil && reflect.TypeOf(except) == reflect.TypeOf(obj) && except.GetName() == obj.GetName()
+}
+
+// getUnusedThrottledBrokers returns the throttled brokers not used by the other reassignments of the cluster in
+// progress, which are the ones of the KafkaTopics and the KafkaRebalances except the caller, and the ones of
+// the drain and the rebalance of the cluster
+func getUnusedThrottledBrokers(c client.Client, cluster *kafkav1.KafkaCluster, throttled []int32, except client.Object) ([]int32, error) {
+	topics := &kafkav1.KafkaTopicList{}
+	if err := c.List(context.TODO(), topics, client.InNamespace(cluster.Namespace)); err != nil {
+		return nil, err
+	}
+	inUse := make([]int32, 0)
+	for i := range topics.Items {
+		t := &topics.Items[i]
+		if isSameObject(except, t) || t.Spec.ClusterRef != cluster.Name || t.Status.Reassignment == nil {
+			continue
+		}
+		inUse = append(inUse, t.Status.Reassignment.ThrottledBrokers...)
+	}
+	rebalances := &kafkav1.KafkaRebalanceList{}
+	if err := c.List(context.TODO(), rebalances, client.InNamespace(cluster.Namespace)); err != nil {
+		return nil, err
+	}
+	for i := range rebalances.Items {
+		rb := &rebalances.Items[i]
+		if isSameObject(except, rb) || rb.Spec.ClusterRef != cluster.Name || rb.Status.State != kafkav1.RebalanceStateRebalancing {
+			continue
+		}
+		inUse = append(inUse, rb.Status.ThrottledBrokers...)
+	}
+	if cluster.Status.Drain != nil {
+		inUse = append(inUse, cluster.Status.Drain.ThrottledBrokers...)
+	}
+	if cluster.Status.Rebalance != nil {
+		inUse = append(inUse, cluster.Status.Rebalance.ThrottledBrokers...)
+	}
+	brokers := make([]int32, 0)
+	for _, id := range throttled {
+		if !containsBroker(inUse, id) {
+			brokers = append(brokers, id)
+		}
+	}
+	return brokers, nil
+}
+
+// countMovingLogDirs returns the num of the partitions whose replicas are being copied between the disks
+func countMovingLogDirs(admin sarama.ClusterAdmin, movements []kafkav1.PartitionMovement) (int, error) {
+	states, err := describeReplicaLogDirs(admin, getLogDirBrokers(movements))
+	if err != nil {
+		return 0, err
+	}
+	moving := 0
+	for _, m := range movements {
+		if isLogDirMoving(m, states) {
+			moving++
+		}
+	}
+	return moving, nil
+}
+
+// countReassigningTopics returns the num of the partitions of the topics being reassigned, the deleted topics are skipped
+func countReassigningTopics(admin sarama.ClusterAdmin, topics []string) (int, error) {
+	reassigning := 0
+	for _, name := range topics {
+		metadata, err := describeTopic(admin, name)
+		if err != nil {
+			return 0, err
+		}
+		if metadata == nil {
+			continue
+		}
+		count, err := countReassigningPartitions(admin, name, sortedPartitionIDs(getCurrentAssignment(metadata)))
+		if err != nil {
+			return 0, err
+		}
+		reassigning += count
+	}
+	return reassigning, nil
+}
+
+// getReassigningTopic returns the first topic of the plans being reassigned, empty if none
+func getReassigningTopic(admin sarama.ClusterAdmin, plans map[string]replicaAssignment) (string, error) {
+	for _, name := range sortedKeys(plans) {
+		count, err := countReassigningPartitions(admin, name, sortedPartitionIDs(plans[name]))
+		if err != nil {
+			return "", err
+		}
+		if count > 0 {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// clearReassignmentThrottles removes the throttles of the topics and the ones of the brokers no other reassignment uses,
+// the throttled brokers of the drain or the rebalance of the cluster must be removed from its status beforehand
+func clearReassignmentThrottles(c client.Client, admin sarama.ClusterAdmin, cluster *kafkav1.KafkaCluster, topics []string, throttled []int32, except client.Object) error {
+	for _, name := range topics {
+		if err := clearTopicThrottles(admin, name); err != nil && !isKafkaError(err, sarama.ErrUnknownTopicOrPartition) {
+			return err
+		}
+	}
+	brokers, err := getUnusedThrottledBrokers(c, cluster, throttled, except)
+	if err != nil {
+		return err
+	}
+	return clearBrokerThrottles(admin, brokers)
+}
+
+// cancelReassignments cancels the reassignments of the partitions of the topics in progress, the others are
+// submitted with their current replicas which completes at once
+func cancelReassignments(admin sarama.ClusterAdmin, topics []string) error {
+	for _, name := range topics {
+		metadata, err := describeTopic(admin, name)
+		if err != nil {
+			return err
+		}
+		if metadata == nil {
+			continue
+		}
+		current := getCurrentAssignment(metadata)
+		status, err := admin.ListPartitionReassignments(name, sortedPartitionIDs(current))
+		if err != nil {
+			return err
+		}
+		if len(status[name]) == 0 {
+			continue
+		}
+		assignment := make([][]int32, len(current))
+		for id, replicas := range current {
+			if int(id) >= len(assignment) {
+				return fmt.Errorf("the assignment of the topic %s misses the partitions before %d", name, id)
+			}
+			if _, ok := status[name][id]; !ok {
+				assignment[id] = replicas
+			}
+		}
+		if err = admin.AlterPartitionReassignments(name, assignment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countReassigningPartitions returns the num of the partitions of the topic being reassigned
+func countReassigningPartitions(admin sarama.ClusterAdmin, topic string, partitions []int32) (int, error) {
+	status, err := admin.ListPartitionReassignments(topic, partitions)
+	if err != nil {
+		return 0, err
+	}
+	return len(status[topic]), nil
+}
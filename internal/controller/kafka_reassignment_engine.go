@@ -0,0 +1,327 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func getReassignmentBatchSize(cluster *kafkav1.KafkaCluster) int {
+	if cluster.Spec.Reassignment != nil && cluster.Spec.Reassignment.BatchSize > 0 {
+		return int(cluster.Spec.Reassignment.BatchSize)
+	}
+	return DefaultReassignmentBatchSize
+}
+
+// getPartitionMovements returns the movements of the partitions whose replicas change by the plans
+func getPartitionMovements(topics []*sarama.TopicMetadata, plans map[string]replicaAssignment) []kafkav1.PartitionMovement {
+	currents := make(map[string]replicaAssignment)
+	for _, t := range topics {
+		currents[t.Name] = getCurrentAssignment(t)
+	}
+	movements := make([]kafkav1.PartitionMovement, 0)
+	for _, name := range sortedKeys(plans) {
+		current, ok := currents[name]
+		if !ok {
+			continue
+		}
+		for _, id := range sortedPartitionIDs(plans[name]) {
+			if equalReplicas(current[id], plans[name][id]) {
+				continue
+			}
+			movements = append(movements, kafkav1.PartitionMovement{
+				Topic: name, Partition: id, From: current[id], To: plans[name][id],
+			})
+		}
+	}
+	return movements
+}
+
+// startReassignment records the movements of an operation, they are submitted by the next reconciliation of the engine
+func startReassignment(status *kafkav1.ReassignmentStatus, movements []kafkav1.PartitionMovement, rate int64) {
+	status.Pending = append([]kafkav1.PartitionMovement{}, movements...)
+	status.Topics = nil
+	status.Partitions = int32(len(movements))
+	status.CompletedPartitions = 0
+	status.ThrottleRate = rate
+}
+
+// reassignmentBatch is the movements submitted together, by the topics, and the movements between the disks
+type reassignmentBatch struct {
+	plans     map[string]replicaAssignment
+	movements map[string][]kafkav1.PartitionMovement
+	logDirs   []kafkav1.PartitionMovement
+}
+
+// nextReassignmentBatch takes the movements of the next batch out of the pending ones. the movements already done
+// are dropped, so are the ones of the partitions deleted or changed by others since the operation started, which
+// are planned again by the owner if needed. the movements between the disks are done once the replicas are on
+// their log dirs, and are dropped if the replicas have moved to other brokers. it returns the batch and the
+// movements left pending
+func nextReassignmentBatch(pending []kafkav1.PartitionMovement, currents map[string]replicaAssignment,
+	states map[replicaLogDir]logDirState, size int) (*reassignmentBatch, []kafkav1.PartitionMovement) {
+	batch := &reassignmentBatch{
+		plans:     make(map[string]replicaAssignment),
+		movements: make(map[string][]kafkav1.PartitionMovement),
+	}
+	left := make([]kafkav1.PartitionMovement, 0)
+	count := 0
+	for _, m := range pending {
+		replicas, ok := currents[m.Topic][m.Partition]
+		if len(m.LogDirs) > 0 {
+			if !ok || !equalReplicas(replicas, m.To) || isLogDirMoved(m, states) {
+				continue
+			}
+		} else if !ok || equalReplicas(replicas, m.To) || !equalReplicas(replicas, m.From) {
+			continue
+		}
+		if count >= size {
+			left = append(left, m)
+			continue
+		}
+		count++
+		if len(m.LogDirs) > 0 {
+			batch.logDirs = append(batch.logDirs, m)
+			continue
+		}
+		// the partitions left out of the batch are submitted with their current replicas, which completes at once
+		if _, ok = batch.plans[m.Topic]; !ok {
+			batch.plans[m.Topic] = make(replicaAssignment)
+			for id, r := range currents[m.Topic] {
+				batch.plans[m.Topic][id] = r
+			}
+		}
+		batch.plans[m.Topic][m.Partition] = m.To
+		batch.movements[m.Topic] = append(batch.movements[m.Topic], m)
+	}
+	return batch, left
+}
+
+// reassignmentEngine moves the partitions of the drains, the replication factor changes and the rebalances. the
+// movements are submitted in batches with the replication of the moved replicas throttled, and the progress is kept
+// in the status of the owner, so the owner only records the movements and reconciles the engine until it is done.
+// the replicas are moved between the brokers by the partition reassignments, and between the disks of a broker by
+// AlterReplicaLogDirs
+type reassignmentEngine struct {
+	client  client.Client
+	admin   sarama.ClusterAdmin
+	cluster *kafkav1.KafkaCluster
+	// owner is skipped when looking for the throttled brokers in use, nil for the ones in the status of the cluster
+	owner client.Object
+}
+
+func newReassignmentEngine(c client.Client, admin sarama.ClusterAdmin, cluster *kafkav1.KafkaCluster, owner client.Object) *reassignmentEngine {
+	return &reassignmentEngine{client: c, admin: admin, cluster: cluster, owner: owner}
+}
+
+// reconcile polls the batch in progress and submits the next one once it is done, it returns true once all the
+// movements are done and the throttles are cleared
+func (e *reassignmentEngine) reconcile(status *kafkav1.ReassignmentStatus, logger logr.Logger) (bool, error) {
+	if len(status.Topics) > 0 || len(status.LogDirs) > 0 {
+		reassigning, err := countReassigningTopics(e.admin, status.Topics)
+		if err != nil {
+			return false, err
+		}
+		moving, err := countMovingLogDirs(e.admin, status.LogDirs)
+		if err != nil {
+			return false, err
+		}
+		status.CompletedPartitions = status.Partitions - int32(len(status.Pending)) - int32(reassigning+moving)
+		if reassigning+moving > 0 {
+			logger.Info(fmt.Sprintf("Waiting for the replicas of %d partitions to be moved, %d of %d partitions are done",
+				reassigning+moving, status.CompletedPartitions, status.Partitions))
+			return false, nil
+		}
+		for _, name := range status.Topics {
+			if err = clearTopicThrottles(e.admin, name); err != nil && !isKafkaError(err, sarama.ErrUnknownTopicOrPartition) {
+				return false, err
+			}
+		}
+		status.Topics = nil
+		status.LogDirs = nil
+	}
+	if len(status.Pending) > 0 {
+		if err := e.submitBatch(status, logger); err != nil {
+			return false, err
+		}
+		if status.IsReassigning() {
+			return false, nil
+		}
+	}
+	status.CompletedPartitions = status.Partitions
+	return true, e.clearThrottles(status)
+}
+
+// submitBatch throttles and submits the movements of the next batch, the batch waits for the topics being
+// reassigned by others since their reassignments would be cancelled by the submission
+func (e *reassignmentEngine) submitBatch(status *kafkav1.ReassignmentStatus, logger logr.Logger) error {
+	currents := make(map[string]replicaAssignment)
+	for _, m := range status.Pending {
+		if _, ok := currents[m.Topic]; ok {
+			continue
+		}
+		metadata, err := describeTopic(e.admin, m.Topic)
+		if err != nil {
+			return err
+		}
+		currents[m.Topic] = nil
+		if metadata != nil {
+			currents[m.Topic] = getCurrentAssignment(metadata)
+		}
+	}
+	states, err := describeReplicaLogDirs(e.admin, getLogDirBrokers(status.Pending))
+	if err != nil {
+		return err
+	}
+	batch, left := nextReassignmentBatch(status.Pending, currents, states, getReassignmentBatchSize(e.cluster))
+	if name, err := getReassigningTopic(e.admin, batch.plans); err != nil || name != "" {
+		if name != "" {
+			logger.Info(fmt.Sprintf("Waiting for the reassignment of the topic %s before moving its replicas", name))
+		}
+		return err
+	}
+	rate := status.ThrottleRate
+	if rate <= 0 {
+		rate = getReassignmentThrottleRate(e.cluster)
+	}
+	names := sortedKeys(batch.plans)
+	for i, name := range names {
+		throttled, err := setReassignmentThrottles(e.admin, name, currents[name], batch.plans[name], rate)
+		// the throttles are recorded before the submission so they are cleared even if the submission fails
+		for _, id := range throttled {
+			if !containsBroker(status.ThrottledBrokers, id) {
+				status.ThrottledBrokers = append(status.ThrottledBrokers, id)
+			}
+		}
+		if err == nil {
+			err = submitReassignment(e.admin, name, batch.plans[name])
+		}
+		if err != nil {
+			// the movements of the topics not submitted are kept for the next batch
+			status.Pending = make([]kafkav1.PartitionMovement, 0, len(left))
+			for _, n := range names[i:] {
+				status.Pending = append(status.Pending, batch.movements[n]...)
+			}
+			status.Pending = append(append(status.Pending, batch.logDirs...), left...)
+			return err
+		}
+		status.Topics = append(status.Topics, name)
+	}
+	if len(batch.logDirs) > 0 {
+		if err = e.submitLogDirs(status, batch.logDirs, rate); err != nil {
+			status.Pending = append(append([]kafkav1.PartitionMovement{}, batch.logDirs...), left...)
+			return err
+		}
+		status.LogDirs = batch.logDirs
+	}
+	sort.Slice(status.ThrottledBrokers, func(i, j int) bool { return status.ThrottledBrokers[i] < status.ThrottledBrokers[j] })
+	status.Pending = left
+	if len(names) > 0 {
+		count := 0
+		for _, name := range names {
+			count += len(batch.movements[name])
+		}
+		logger.Info(fmt.Sprintf("Moving the replicas of %d partitions of the topics %v, %d partitions are pending", count, names, len(left)))
+	}
+	if len(batch.logDirs) > 0 {
+		logger.Info(fmt.Sprintf("Moving the replicas of %d partitions between the disks of the brokers %v, %d partitions are pending",
+			len(batch.logDirs), getLogDirBrokers(batch.logDirs), len(left)))
+	}
+	return nil
+}
+
+// submitLogDirs throttles the copying between the disks of the brokers and moves the replicas onto their log dirs
+func (e *reassignmentEngine) submitLogDirs(status *kafkav1.ReassignmentStatus, movements []kafkav1.PartitionMovement, rate int64) error {
+	brokers := getLogDirBrokers(movements)
+	// the throttles are recorded before the submission so they are cleared even if the submission fails
+	for _, id := range brokers {
+		if !containsBroker(status.ThrottledBrokers, id) {
+			status.ThrottledBrokers = append(status.ThrottledBrokers, id)
+		}
+	}
+	if err := setLogDirThrottles(e.admin, brokers, rate); err != nil {
+		return err
+	}
+	return e.alterLogDirs(getLogDirAssignments(movements))
+}
+
+// alterLogDirs sends the log dirs of the replicas to their brokers
+func (e *reassignmentEngine) alterLogDirs(assignments map[int32]logDirAssignment) error {
+	if len(assignments) == 0 {
+		return nil
+	}
+	brokers, _, err := e.admin.DescribeCluster()
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := getAdminTLSConfig(e.client, e.cluster)
+	if err != nil {
+		return err
+	}
+	ids := make([]int32, 0, len(assignments))
+	for id := range assignments {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		addr := ""
+		for _, b := range brokers {
+			if b.ID() == id {
+				addr = b.Addr()
+			}
+		}
+		if addr == "" {
+			return fmt.Errorf("the broker %d is not found in the cluster", id)
+		}
+		results, err := alterReplicaLogDirs(addr, tlsConfig, assignments[id])
+		if err != nil {
+			return err
+		}
+		for _, topic := range sortedKeys(results) {
+			for partition, kerr := range results[topic] {
+				if kerr != sarama.ErrNoError {
+					return fmt.Errorf("failed to move the replica of the partition %d of the topic %s on the broker %d: %w", partition, topic, id, kerr)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// clearThrottles removes the throttles of the topics of the batch in progress and the ones of the brokers no other
+// operation uses, the brokers are removed from the status beforehand so the status of the cluster skips them too
+func (e *reassignmentEngine) clearThrottles(status *kafkav1.ReassignmentStatus) error {
+	topics, throttled := status.Topics, status.ThrottledBrokers
+	status.ThrottledBrokers = nil
+	if err := clearReassignmentThrottles(e.client, e.admin, e.cluster, topics, throttled, e.owner); err != nil {
+		status.ThrottledBrokers = throttled
+		return err
+	}
+	status.Topics = nil
+	return nil
+}
+
+// cancel cancels the movements of the batch in progress and drops the pending ones, the partitions already moved are kept
+func (e *reassignmentEngine) cancel(status *kafkav1.ReassignmentStatus) error {
+	if err := cancelReassignments(e.admin, status.Topics); err != nil {
+		return err
+	}
+	// the replicas being copied onto another disk are moved back onto their current log dirs
+	states, err := describeReplicaLogDirs(e.admin, getLogDirBrokers(status.LogDirs))
+	if err != nil {
+		return err
+	}
+	if err = e.alterLogDirs(getLogDirCancellations(status.LogDirs, states)); err != nil {
+		return err
+	}
+	status.LogDirs = nil
+	if err := e.clearThrottles(status); err != nil {
+		return err
+	}
+	status.Pending = nil
+	return nil
+}
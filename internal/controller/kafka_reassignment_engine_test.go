@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+func TestNextReassignmentBatch(t *testing.T) {
+	g := NewWithT(t)
+	currents := map[string]replicaAssignment{
+		"orders": {0: {0, 1}, 1: {1, 2}, 2: {2, 0}},
+		"events": {0: {2, 1}, 1: {1, 0}},
+	}
+	pending := []kafkav1.PartitionMovement{
+		// already moved before a restart of the operator
+		{Topic: "events", Partition: 0, From: []int32{1, 0}, To: []int32{2, 1}},
+		// changed by others since the operation started
+		{Topic: "events", Partition: 1, From: []int32{0, 1}, To: []int32{0, 2}},
+		// deleted since the operation started
+		{Topic: "payments", Partition: 0, From: []int32{0, 1}, To: []int32{2, 1}},
+		{Topic: "orders", Partition: 0, From: []int32{0, 1}, To: []int32{3, 1}},
+		{Topic: "orders", Partition: 1, From: []int32{1, 2}, To: []int32{1, 3}},
+		{Topic: "orders", Partition: 2, From: []int32{2, 0}, To: []int32{3, 0}},
+	}
+
+	batch, left := nextReassignmentBatch(pending, currents, nil, 2)
+	g.Expect(batch.plans).To(HaveLen(1))
+	g.Expect(batch.plans["orders"]).To(Equal(replicaAssignment{0: {3, 1}, 1: {1, 3}, 2: {2, 0}}))
+	g.Expect(batch.movements["orders"]).To(Equal(pending[3:5]))
+	g.Expect(left).To(Equal(pending[5:]))
+
+	batch, left = nextReassignmentBatch(left, currents, nil, 2)
+	g.Expect(batch.plans["orders"][2]).To(Equal([]int32{3, 0}))
+	g.Expect(left).To(BeEmpty())
+}
+
+func TestNextReassignmentBatchLogDirs(t *testing.T) {
+	g := NewWithT(t)
+	currents := map[string]replicaAssignment{"orders": {0: {0, 1}, 1: {1, 2}, 2: {2, 0}}}
+	states := map[replicaLogDir]logDirState{
+		{topic: "orders", partition: 0, broker: 0}: {current: "/data/disk1"},
+		{topic: "orders", partition: 1, broker: 1}: {current: "/data/disk0"},
+	}
+	pending := []kafkav1.PartitionMovement{
+		// already on its log dir
+		{Topic: "orders", Partition: 0, From: []int32{0, 1}, To: []int32{0, 1}, LogDirs: []string{"/data/disk1", DefaultAnyLogDir}},
+		{Topic: "orders", Partition: 1, From: []int32{1, 2}, To: []int32{1, 2}, LogDirs: []string{"/data/disk1", DefaultAnyLogDir}},
+		// moved to other brokers since the operation started
+		{Topic: "orders", Partition: 2, From: []int32{0, 1}, To: []int32{0, 1}, LogDirs: []string{DefaultAnyLogDir, "/data/disk1"}},
+	}
+
+	batch, left := nextReassignmentBatch(pending, currents, states, 2)
+	g.Expect(batch.plans).To(BeEmpty())
+	g.Expect(batch.logDirs).To(Equal(pending[1:2]))
+	g.Expect(left).To(BeEmpty())
+	g.Expect(getLogDirAssignments(batch.logDirs)).To(Equal(map[int32]logDirAssignment{1: {"/data/disk1": {"orders": {1}}}}))
+}
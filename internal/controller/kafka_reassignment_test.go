@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/IBM/sarama"
+	. "github.com/onsi/gomega"
+)
+
+func newTestBrokers(ids ...int32) []*sarama.Broker {
+	metadata := &sarama.MetadataResponse{}
+	for _, id := range ids {
+		metadata.AddBroker(fmt.Sprintf("broker-%d:9092", id), id)
+	}
+	return metadata.Brokers
+}
+
+func newTestTopic(name string, assignment replicaAssignment) *sarama.TopicMetadata {
+	metadata := &sarama.TopicMetadata{Name: name}
+	for _, id := range sortedPartitionIDs(assignment) {
+		metadata.Partitions = append(metadata.Partitions, &sarama.PartitionMetadata{ID: id, Replicas: assignment[id]})
+	}
+	return metadata
+}
+
+func TestPlanReplicationFactor(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		current  replicaAssignment
+		brokers  []int32
+		factor   int
+		expected replicaAssignment
+		err      string
+	}{
+		{
+			name:     "increase keeps the existing replicas and fills the least used brokers",
+			current:  replicaAssignment{0: {0, 1}, 1: {1, 2}, 2: {2, 0}, 3: {0, 1}},
+			brokers:  []int32{0, 1, 2, 3},
+			factor:   3,
+			expected: replicaAssignment{0: {0, 1, 3}, 1: {1, 2, 3}, 2: {2, 0, 3}, 3: {0, 1, 2}},
+		},
+		{
+			name:     "decrease keeps the leaders",
+			current:  replicaAssignment{0: {0, 1, 2}, 1: {1, 2, 3}},
+			brokers:  []int32{0, 1, 2, 3},
+			factor:   1,
+			expected: replicaAssignment{0: {0}, 1: {1}},
+		},
+		{
+			name:     "unchanged factor",
+			current:  replicaAssignment{0: {0, 1}, 1: {1, 0}},
+			brokers:  []int32{0, 1},
+			factor:   2,
+			expected: replicaAssignment{0: {0, 1}, 1: {1, 0}},
+		},
+		{
+			name:    "factor larger than the brokers",
+			current: replicaAssignment{0: {0, 1}},
+			brokers: []int32{0, 1},
+			factor:  3,
+			err:     "larger than the num of the brokers",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			plan, err := planReplicationFactor(newTestTopic("orders", tc.current), newTestBrokers(tc.brokers...), tc.factor)
+			if tc.err != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.err)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(plan).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestPlanBrokerRemoval(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		topics   map[string]replicaAssignment
+		brokers  []int32
+		removed  []int32
+		expected map[string]replicaAssignment
+		err      string
+	}{
+		{
+			name: "replicas on the removed broker move to the least used brokers",
+			topics: map[string]replicaAssignment{
+				"orders": {0: {0, 3}, 1: {3, 1}, 2: {1, 2}},
+				"events": {0: {0, 1}},
+			},
+			brokers:  []int32{0, 1, 2, 3},
+			removed:  []int32{3},
+			expected: map[string]replicaAssignment{"orders": {0: {0, 2}, 1: {1, 0}, 2: {1, 2}}},
+		},
+		{
+			name:     "no replicas on the removed broker",
+			topics:   map[string]replicaAssignment{"orders": {0: {0, 1}}},
+			brokers:  []int32{0, 1, 2},
+			removed:  []int32{2},
+			expected: map[string]replicaAssignment{},
+		},
+		{
+			name:    "not enough remaining brokers",
+			topics:  map[string]replicaAssignment{"orders": {0: {0, 1, 2}}},
+			brokers: []int32{0, 1, 2},
+			removed: []int32{2},
+			err:     "can not be moved off the brokers [2]",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			topics := make([]*sarama.TopicMetadata, 0)
+			for _, name := range sortedKeys(tc.topics) {
+				topics = append(topics, newTestTopic(name, tc.topics[name]))
+			}
+			plans, err := planBrokerRemoval(topics, newTestBrokers(tc.brokers...), tc.removed)
+			if tc.err != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.err)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(plans).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestPlanRebalance(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		topics   map[string]replicaAssignment
+		brokers  []int32
+		expected map[string]replicaAssignment
+		before   map[int32]int64
+		after    map[int32]int64
+	}{
+		{
+			name:     "replicas move onto the added broker",
+			topics:   map[string]replicaAssignment{"orders": {0: {0, 1}, 1: {1, 2}, 2: {2, 0}, 3: {0, 1}, 4: {1, 2}, 5: {2, 0}}},
+			brokers:  []int32{0, 1, 2, 3},
+			expected: map[string]replicaAssignment{"orders": {0: {0, 3}, 1: {1, 3}, 2: {2, 3}, 3: {0, 1}, 4: {1, 2}, 5: {2, 0}}},
+			before:   map[int32]int64{0: 4, 1: 4, 2: 4, 3: 0},
+			after:    map[int32]int64{0: 3, 1: 3, 2: 3, 3: 3},
+		},
+		{
+			name:     "balanced brokers",
+			topics:   map[string]replicaAssignment{"orders": {0: {0, 1}, 1: {2, 3}}},
+			brokers:  []int32{0, 1, 2, 3},
+			expected: map[string]replicaAssignment{},
+			before:   map[int32]int64{0: 1, 1: 1, 2: 1, 3: 1},
+			after:    map[int32]int64{0: 1, 1: 1, 2: 1, 3: 1},
+		},
+		{
+			name:     "replicas are not doubled on a broker",
+			topics:   map[string]replicaAssignment{"orders": {0: {0, 1}, 1: {0, 1}}},
+			brokers:  []int32{0, 1, 2},
+			expected: map[string]replicaAssignment{"orders": {0: {0, 2}, 1: {0, 1}}},
+			before:   map[int32]int64{0: 2, 1: 2, 2: 0},
+			after:    map[int32]int64{0: 2, 1: 1, 2: 1},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			topics := make([]*sarama.TopicMetadata, 0)
+			for _, name := range sortedKeys(tc.topics) {
+				topics = append(topics, newTestTopic(name, tc.topics[name]))
+			}
+			plans, before, after := planRebalance(topics, newTestBrokers(tc.brokers...), func(string, int32) int64 { return 1 })
+			g.Expect(plans).To(Equal(tc.expected))
+			g.Expect(before).To(Equal(tc.before))
+			g.Expect(after).To(Equal(tc.after))
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+func getInternalAuthentication(cluster *kafkav1.KafkaCluster) kafkav1.ListenerAuthenticationType {
+	if cluster.Spec.Listeners == nil || cluster.Spec.Listeners.Internal == nil {
+		return ""
+	}
+	return cluster.Spec.Listeners.Internal.Authentication
+}
+
+func getExternalAuthentication(cluster *kafkav1.KafkaCluster) kafkav1.ListenerAuthenticationType {
+	if !IsExternalListenerEnabled(cluster) {
+		return ""
+	}
+	return cluster.Spec.Listeners.External.Authentication
+}
+
+func IsInternalAuthenticationEnabled(cluster *kafkav1.KafkaCluster) bool {
+	return getInternalAuthentication(cluster) != ""
+}
+
+func IsExternalAuthenticationEnabled(cluster *kafkav1.KafkaCluster) bool {
+	return getExternalAuthentication(cluster) != ""
+}
+
+// IsTLSAuthenticationEnabled returns true if any listener authenticates the clients by their certificates
+func IsTLSAuthenticationEnabled(cluster *kafkav1.KafkaCluster) bool {
+	return getInternalAuthentication(cluster) == kafkav1.ListenerAuthenticationTLS ||
+		getExternalAuthentication(cluster) == kafkav1.ListenerAuthenticationTLS
+}
+
+// HasReplicationListener returns true if the brokers replicate and the operator administrates through a dedicated
+// listener, which is the case once any listener is authenticated. the replication listener authenticates the
+// brokers and the operator by their certificates if the internal tls is enabled
+func HasReplicationListener(cluster *kafkav1.KafkaCluster) bool {
+	return IsInternalAuthenticationEnabled(cluster) || IsExternalAuthenticationEnabled(cluster)
+}
+
+// getSecurityProtocol returns the protocol of a listener, the tls authentication is a part of SSL
+func getSecurityProtocol(tls bool, authentication kafkav1.ListenerAuthenticationType) string {
+	sasl := authentication == kafkav1.ListenerAuthenticationScramSHA512
+	switch {
+	case tls && sasl:
+		return "SASL_SSL"
+	case sasl:
+		return "SASL_PLAINTEXT"
+	case tls:
+		return "SSL"
+	}
+	return "PLAINTEXT"
+}
+
+func getReplicationSecurityProtocol(cluster *kafkav1.KafkaCluster) string {
+	return getSecurityProtocol(IsInternalTLSEnabled(cluster), "")
+}
+
+// getControllerSecurityProtocol returns the protocol of the controller listener of the kraft mode, the controllers
+// and the brokers authenticate each other by their certificates if the internal tls is enabled at the bootstrap
+func getControllerSecurityProtocol(cluster *kafkav1.KafkaCluster) string {
+	if cluster.Status.ControllerSecurityProtocol != "" {
+		return cluster.Status.ControllerSecurityProtocol
+	}
+	return getSecurityProtocol(IsInternalTLSEnabled(cluster), "")
+}
+
+func isControllerTLSEnabled(cluster *kafkav1.KafkaCluster) bool {
+	return IsKraftMode(cluster) && getControllerSecurityProtocol(cluster) == "SSL"
+}
+
+func getInterBrokerListenerName(cluster *kafkav1.KafkaCluster) string {
+	if HasReplicationListener(cluster) {
+		return DefaultReplicationPortName
+	}
+	return DefaultInternalPortName
+}
+
+// constructAuthenticationScript renders the sasl and the client auth configs of the authenticated listeners into the config
+func constructAuthenticationScript(cluster *kafkav1.KafkaCluster) string {
+	var sb strings.Builder
+	listeners := map[string]kafkav1.ListenerAuthenticationType{
+		DefaultInternalPortName: getInternalAuthentication(cluster),
+		DefaultExternalPortName: getExternalAuthentication(cluster),
+	}
+	for _, listener := range sortedKeys(listeners) {
+		switch listeners[listener] {
+		case kafkav1.ListenerAuthenticationScramSHA512:
+			sb.WriteString(fmt.Sprintf("echo \"listener.name.%s.sasl.enabled.mechanisms=%s\" >> ${CONF}\n", listener, DefaultScramMechanism))
+			sb.WriteString(fmt.Sprintf("echo \"listener.name.%s.%s.sasl.jaas.config=org.apache.kafka.common.security.scram.ScramLoginModule required;\" >> ${CONF}\n",
+				listener, strings.ToLower(DefaultScramMechanism)))
+		case kafkav1.ListenerAuthenticationTLS:
+			sb.WriteString(fmt.Sprintf("echo \"listener.name.%s.ssl.client.auth=required\" >> ${CONF}\n", listener))
+			sb.WriteString(fmt.Sprintf("echo \"listener.name.%s.ssl.truststore.type=PEM\" >> ${CONF}\n", listener))
+			sb.WriteString(fmt.Sprintf("echo \"listener.name.%s.ssl.truststore.location=%s/%s\" >> ${CONF}\n",
+				listener, DefaultClientsCAPath, DefaultCACertKey))
+		}
+	}
+	if HasReplicationListener(cluster) && IsInternalTLSEnabled(cluster) {
+		sb.WriteString(fmt.Sprintf("echo \"listener.name.%s.ssl.client.auth=required\" >> ${CONF}\n", DefaultReplicationPortName))
+	}
+	if isControllerTLSEnabled(cluster) {
+		sb.WriteString(fmt.Sprintf("echo \"listener.name.%s.ssl.client.auth=required\" >> ${CONF}\n", DefaultControllerPortName))
+	}
+	if _, ok := cluster.Spec.Conf["ssl.principal.mapping.rules"]; !ok && isCertificatePrincipalUsed(cluster) {
+		// single quoted, the rules are full of the shell specials
+		sb.WriteString(fmt.Sprintf("echo 'ssl.principal.mapping.rules=%s' >> ${CONF}\n", getPrincipalMappingRules(cluster)))
+	}
+	return sb.String()
+}
+
+// isCertificatePrincipalUsed returns true if any listener authenticates the connections by their certificates
+func isCertificatePrincipalUsed(cluster *kafkav1.KafkaCluster) bool {
+	return IsTLSAuthenticationEnabled(cluster) || (HasReplicationListener(cluster) && IsInternalTLSEnabled(cluster)) ||
+		isControllerTLSEnabled(cluster)
+}
+
+func GetClientsCASecretName(cluster *kafkav1.KafkaCluster) string {
+	return ClusterResourceName(cluster, DefaultClientsCASecretSuffix)
+}
+
+func GetClientsCACertSecretName(cluster *kafkav1.KafkaCluster) string {
+	return ClusterResourceName(cluster, DefaultClientsCACertSecretSuffix)
+}
+
+// reconcileClientsCA maintains the clients CA signing the certificates of the tls users. the brokers keep
+// trusting a replaced CA until it expires, so the users move to the new CA as their certificates are renewed
+func (r *KafkaClusterReconciler) reconcileClientsCA(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	if !IsTLSAuthenticationEnabled(cluster) {
+		cluster.Status.ClientsCAHash = ""
+		cluster.Status.ClientsCACertificates = nil
+		return nil
+	}
+	ca, err := r.reconcileCA(cluster, GetClientsCASecretName(cluster), logger)
+	if err != nil {
+		return err
+	}
+	truststore := ca.certPEM
+	secret, err := r.getSecret(cluster, GetClientsCACertSecretName(cluster))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if secret != nil {
+		for _, cert := range parseCertificatesPEM(secret.Data[DefaultCACertKey]) {
+			if time.Now().Before(cert.NotAfter) {
+				truststore = mergeCertificatesPEM(truststore, encodeCertificatePEM(cert.Raw))
+			}
+		}
+	}
+	desiredSecret, err := r.constructSecret(cluster, GetClientsCACertSecretName(cluster), map[string][]byte{
+		DefaultCACertKey: truststore,
+	})
+	if err != nil {
+		return err
+	}
+	if err = r.applySecret(desiredSecret, logger); err != nil {
+		return err
+	}
+	hash, err := hashObject(truststore)
+	if err != nil {
+		return err
+	}
+	cluster.Status.ClientsCAHash = hash
+	cluster.Status.ClientsCACertificates = getCertificatesStatus(truststore)
+	return nil
+}
+
+// IsAuthorizationEnabled returns true if the acls are enforced. the kraft controllers authorize the brokers by their
+// certificates, so the clusters bootstrapped with a plaintext controller listener never enable the authorizer,
+// which the webhook rejects
+func IsAuthorizationEnabled(cluster *kafkav1.KafkaCluster) bool {
+	if IsKraftMode(cluster) && !isControllerTLSEnabled(cluster) {
+		return false
+	}
+	return cluster.Spec.Authorization != nil
+}
+
+// getBrokersPrincipal returns the name of the principal shared by the brokers, so the super users do not change with the replicas
+func getBrokersPrincipal(cluster *kafkav1.KafkaCluster) string {
+	return ClusterResourceName(cluster, "-brokers")
+}
+
+// getBrokerCommonNamePattern matches the common names of the certificates of the brokers
+func getBrokerCommonNamePattern(cluster *kafkav1.KafkaCluster) string {
+	return fmt.Sprintf("^%s-[0-9]+\\.%s$", regexp.QuoteMeta(ClusterResourceName(cluster)),
+		regexp.QuoteMeta(fmt.Sprintf("%s.%s.svc.%s", ClusterResourceName(cluster, DefaultHeadlessSvcNameSuffix),
+			cluster.Namespace, GetClusterDomain(cluster))))
+}
+
+// getPrincipalMappingRules maps the certificates of the brokers to the principal of the brokers and the others to their common names
+func getPrincipalMappingRules(cluster *kafkav1.KafkaCluster) string {
+	brokerRule := fmt.Sprintf("RULE:^CN=%s(,.*)?$/%s/", strings.TrimSuffix(strings.TrimPrefix(getBrokerCommonNamePattern(cluster), "^"), "$"),
+		getBrokersPrincipal(cluster))
+	return strings.Join([]string{brokerRule, DefaultCommonNameMappingRule, "DEFAULT"}, ",")
+}
+
+// isReservedPrincipal returns true if the user name would be mapped to the principal of the brokers or the operator
+func isReservedPrincipal(cluster *kafkav1.KafkaCluster, name string) bool {
+	if name == getBrokersPrincipal(cluster) || name == getOperatorCommonName(cluster) || "User:"+name == DefaultAnonymousPrincipal {
+		return true
+	}
+	matched, err := regexp.MatchString(getBrokerCommonNamePattern(cluster), name)
+	return err != nil || matched
+}
+
+func getAuthorizerClassName(cluster *kafkav1.KafkaCluster) string {
+	if IsKraftMode(cluster) {
+		return DefaultKraftAuthorizer
+	}
+	return DefaultZookeeperAuthorizer
+}
+
+// getSuperUsers returns the principals bypassing the acls. the brokers and the operator are identified by their
+// certificates on the replication listener and the controller listener of the kraft mode
+func getSuperUsers(cluster *kafkav1.KafkaCluster) string {
+	superUsers := []string{
+		"User:" + getBrokersPrincipal(cluster),
+		"User:" + getOperatorCommonName(cluster),
+	}
+	superUsers = append(superUsers, cluster.Spec.Authorization.SuperUsers...)
+	return strings.Join(superUsers, ";")
+}
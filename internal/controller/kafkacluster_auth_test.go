@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+// TestKraftControllerListenerTLS authenticates the brokers on the controller listener by their certificates,
+// the authorizer is never enabled with a plaintext controller listener
+func TestKraftControllerListenerTLS(t *testing.T) {
+	g := NewWithT(t)
+	cluster := &kafkav1.KafkaCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec: kafkav1.KafkaClusterSpec{
+			Kraft: &kafkav1.KraftConfig{},
+			Listeners: &kafkav1.ListenersConfig{
+				Internal: &kafkav1.InternalListenerConfig{TLS: true, Authentication: kafkav1.ListenerAuthenticationTLS},
+			},
+			Authorization: &kafkav1.AuthorizationConfig{},
+		},
+		Status: kafkav1.KafkaClusterStatus{ControllerSecurityProtocol: "SSL"},
+	}
+	conf := constructClusterConfKeyValue(cluster)
+	g.Expect(conf["listener.security.protocol.map"]).To(ContainSubstring(DefaultControllerPortName + ":SSL"))
+	g.Expect(strings.Split(conf["super.users"], ";")).NotTo(ContainElement(DefaultAnonymousPrincipal))
+	g.Expect(constructAuthenticationScript(cluster)).To(ContainSubstring("listener.name." + DefaultControllerPortName + ".ssl.client.auth=required"))
+
+	// the clusters bootstrapped with a plaintext controller listener keep it
+	cluster.Status.ControllerSecurityProtocol = "PLAINTEXT"
+	conf = constructClusterConfKeyValue(cluster)
+	g.Expect(conf["listener.security.protocol.map"]).To(ContainSubstring(DefaultControllerPortName + ":PLAINTEXT"))
+	g.Expect(conf).NotTo(HaveKey("super.users"))
+	g.Expect(IsAuthorizationEnabled(cluster)).To(BeFalse())
+}
@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func IsCertManagerEnabled(cluster *kafkav1.KafkaCluster) bool {
+	return cluster.Spec.TLS != nil && cluster.Spec.TLS.IssuerRef != nil
+}
+
+func certificateGVK(kind string) schema.GroupVersionKind {
+	return schema.GroupVersionKind{
+		Group:   CertManagerGroup,
+		Version: CertManagerVersion,
+		Kind:    kind,
+	}
+}
+
+func GetBrokerCertificateName(cluster *kafkav1.KafkaCluster, ordinal int) string {
+	return fmt.Sprintf("%s-%d%s", ClusterResourceName(cluster), ordinal, DefaultBrokerCertificateSuffix)
+}
+
+func toInterfaceSlice(values []string) []interface{} {
+	slice := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		slice = append(slice, v)
+	}
+	return slice
+}
+
+// constructCertificate requests a certificate from cert-manager. the key is encoded in PKCS#8,
+// so the secret can be used as a PEM keystore as is
+func (r *KafkaClusterReconciler) constructCertificate(cluster *kafkav1.KafkaCluster, name string, commonName string, sans []string, usages []string) (*unstructured.Unstructured, error) {
+	issuerRef := cluster.Spec.TLS.IssuerRef
+	issuerKind := issuerRef.Kind
+	if issuerKind == "" {
+		issuerKind = DefaultCertManagerIssuer
+	}
+	issuerGroup := issuerRef.Group
+	if issuerGroup == "" {
+		issuerGroup = CertManagerGroup
+	}
+	dnsNames, ips := splitSANs(sans)
+	ipAddresses := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		ipAddresses = append(ipAddresses, ip.String())
+	}
+	secretLabels := make(map[string]interface{})
+	for k, v := range ClusterResourceLabels(cluster) {
+		secretLabels[k] = v
+	}
+	spec := map[string]interface{}{
+		"secretName": name,
+		"secretTemplate": map[string]interface{}{
+			"labels": secretLabels,
+		},
+		"commonName":  commonName,
+		"duration":    DefaultCertValidity.String(),
+		"renewBefore": getCertRenewBefore(cluster).String(),
+		"usages":      toInterfaceSlice(usages),
+		"privateKey": map[string]interface{}{
+			"algorithm":      "RSA",
+			"encoding":       "PKCS8",
+			"size":           int64(DefaultKeySize),
+			"rotationPolicy": "Always",
+		},
+		"issuerRef": map[string]interface{}{
+			"name":  issuerRef.Name,
+			"kind":  issuerKind,
+			"group": issuerGroup,
+		},
+	}
+	if len(dnsNames) > 0 {
+		spec["dnsNames"] = toInterfaceSlice(dnsNames)
+	}
+	if len(ipAddresses) > 0 {
+		spec["ipAddresses"] = toInterfaceSlice(ipAddresses)
+	}
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(certificateGVK(CertificateKind))
+	certificate.SetName(name)
+	certificate.SetNamespace(cluster.Namespace)
+	certificate.SetLabels(ClusterResourceLabels(cluster))
+	certificate.Object["spec"] = spec
+	if err := ctrl.SetControllerReference(cluster, certificate, r.Scheme); err != nil {
+		return certificate, err
+	}
+	return certificate, nil
+}
+
+func (r *KafkaClusterReconciler) constructBrokerCertificate(cluster *kafkav1.KafkaCluster, ordinal int) (*unstructured.Unstructured, error) {
+	return r.constructCertificate(cluster, GetBrokerCertificateName(cluster, ordinal), GetPodFullName(cluster, ordinal),
+		getBrokerSANs(cluster, ordinal), []string{"server auth", "client auth"})
+}
+
+func (r *KafkaClusterReconciler) constructOperatorCertificate(cluster *kafkav1.KafkaCluster) (*unstructured.Unstructured, error) {
+	return r.constructCertificate(cluster, GetOperatorTLSSecretName(cluster), getOperatorCommonName(cluster),
+		nil, []string{"client auth"})
+}
+
+func (r *KafkaClusterReconciler) applyCertificate(desiredCertificate *unstructured.Unstructured, logger logr.Logger) error {
+	existsCertificate := &unstructured.Unstructured{}
+	existsCertificate.SetGroupVersionKind(desiredCertificate.GroupVersionKind())
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: desiredCertificate.GetName(), Namespace: desiredCertificate.GetNamespace()}, existsCertificate)
+	if err != nil && errors.IsNotFound(err) {
+		logger.Info(fmt.Sprintf("Creating a new certificate %s", desiredCertificate.GetName()))
+		return r.Client.Create(context.TODO(), desiredCertificate)
+	} else if err != nil {
+		return err
+	}
+	// the certificates are watched, so an update of an unchanged one would trigger the reconciliation again
+	if equality.Semantic.DeepEqual(existsCertificate.GetLabels(), desiredCertificate.GetLabels()) &&
+		equality.Semantic.DeepEqual(existsCertificate.Object["spec"], desiredCertificate.Object["spec"]) {
+		return nil
+	}
+	logger.Info(fmt.Sprintf("Updating the certificate %s", desiredCertificate.GetName()))
+	existsCertificate.SetLabels(desiredCertificate.GetLabels())
+	existsCertificate.Object["spec"] = desiredCertificate.Object["spec"]
+	return r.Client.Update(context.TODO(), existsCertificate)
+}
+
+// deleteStaleCertificates deletes the certificates which are not desired any more.
+// nothing is done if cert-manager is not installed
+func (r *KafkaClusterReconciler) deleteStaleCertificates(cluster *kafkav1.KafkaCluster, desired map[string]bool, logger logr.Logger) error {
+	existsCertificates := &unstructured.UnstructuredList{}
+	existsCertificates.SetGroupVersionKind(certificateGVK(CertificateListKind))
+	listOps := &client.ListOptions{
+		Namespace:     cluster.Namespace,
+		LabelSelector: labels.SelectorFromSet(ClusterResourceLabels(cluster)),
+	}
+	if err := r.Client.List(context.TODO(), existsCertificates, listOps); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	for i := range existsCertificates.Items {
+		if desired[existsCertificates.Items[i].GetName()] {
+			continue
+		}
+		logger.Info(fmt.Sprintf("Deleting stale certificate %s", existsCertificates.Items[i].GetName()))
+		if err := r.Client.Delete(context.TODO(), &existsCertificates.Items[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileCertManagerTLS requests the certificates of the brokers from cert-manager and copies them into
+// the secret mounted by the brokers, so the brokers are rolled once cert-manager renews a certificate. the keystores
+// are left as they are until all the certificates are issued, which is waited for by the certificates pending condition
+func (r *KafkaClusterReconciler) reconcileCertManagerTLS(cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	desired := make(map[string]bool)
+	pending := make([]string, 0)
+	secrets := make(map[int]*corev1.Secret)
+	var caPEM []byte
+	for i := 0; i < int(getWorkloadReplicas(cluster)); i++ {
+		certificate, err := r.constructBrokerCertificate(cluster, i)
+		if err != nil {
+			return err
+		}
+		if err = r.applyCertificate(certificate, logger); err != nil {
+			return err
+		}
+		desired[certificate.GetName()] = true
+
+		secret, err := r.getSecret(cluster, certificate.GetName())
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if secret == nil || len(secret.Data[corev1.TLSCertKey]) == 0 || len(secret.Data[corev1.TLSPrivateKeyKey]) == 0 {
+			pending = append(pending, certificate.GetName())
+			continue
+		}
+		if caPEM == nil && len(secret.Data[DefaultCACertKey]) > 0 {
+			caPEM = secret.Data[DefaultCACertKey]
+		}
+		secrets[i] = secret
+	}
+	if isOperatorCertificateRequired(cluster) {
+		certificate, err := r.constructOperatorCertificate(cluster)
+		if err != nil {
+			return err
+		}
+		if err = r.applyCertificate(certificate, logger); err != nil {
+			return err
+		}
+		desired[certificate.GetName()] = true
+	}
+	if err := r.deleteStaleCertificates(cluster, desired, logger); err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		logger.Info(fmt.Sprintf("Waiting for cert-manager to issue the certificates %v", pending))
+		cluster.Status.SetCertificatesPendingConditionTrue(kafkav1.CertificateNotIssuedReason,
+			fmt.Sprintf("waiting for cert-manager to issue the certificates %s", strings.Join(pending, ",")))
+		return nil
+	}
+	cluster.Status.SetCertificatesPendingConditionFalse()
+	if caPEM == nil {
+		return fmt.Errorf("the issuer %s does not provide the CA certificate", cluster.Spec.TLS.IssuerRef.Name)
+	}
+	_, err := r.reconcileKeystores(cluster, caPEM, func(ordinal int, exists []byte) ([]byte, error) {
+		secret := secrets[ordinal]
+		return joinKeystore(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]), nil
+	}, logger)
+	return err
+}
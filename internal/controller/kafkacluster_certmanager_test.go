@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+// TestCertManagerTLSPending waits for the certificates not issued yet without failing the reconciliation
+func TestCertManagerTLSPending(t *testing.T) {
+	g := NewWithT(t)
+	cluster := &kafkav1.KafkaCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec: kafkav1.KafkaClusterSpec{
+			TLS: &kafkav1.TLSConfig{IssuerRef: &kafkav1.IssuerReference{Name: "issuer"}},
+		},
+	}
+	r := newFakeReconciler(cluster)
+
+	g.Expect(r.reconcileCertManagerTLS(cluster, logr.Discard())).To(Succeed())
+	g.Expect(cluster.Status.IsCertificatesPending()).To(BeTrue())
+}
+
+func TestApplyCertificateUnchanged(t *testing.T) {
+	g := NewWithT(t)
+	cluster := &kafkav1.KafkaCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec: kafkav1.KafkaClusterSpec{
+			TLS: &kafkav1.TLSConfig{IssuerRef: &kafkav1.IssuerReference{Name: "issuer"}},
+		},
+	}
+	r := newFakeReconciler(cluster)
+	key := types.NamespacedName{Name: GetOperatorTLSSecretName(cluster), Namespace: cluster.Namespace}
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(certificateGVK(CertificateKind))
+
+	desired, err := r.constructOperatorCertificate(cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(r.applyCertificate(desired, logr.Discard())).To(Succeed())
+	g.Expect(r.Client.Get(context.TODO(), key, certificate)).To(Succeed())
+	created := certificate.GetResourceVersion()
+
+	desired, _ = r.constructOperatorCertificate(cluster)
+	g.Expect(r.applyCertificate(desired, logr.Discard())).To(Succeed())
+	g.Expect(r.Client.Get(context.TODO(), key, certificate)).To(Succeed())
+	g.Expect(certificate.GetResourceVersion()).To(Equal(created))
+
+	cluster.Spec.TLS.IssuerRef.Name = "other"
+	desired, _ = r.constructOperatorCertificate(cluster)
+	g.Expect(r.applyCertificate(desired, logr.Discard())).To(Succeed())
+	g.Expect(r.Client.Get(context.TODO(), key, certificate)).To(Succeed())
+	g.Expect(certificate.GetResourceVersion()).NotTo(Equal(created))
+}
@@ -22,7 +22,10 @@ import (
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"strings"
@@ -46,6 +49,14 @@ type reconcileFun func(ctx context.Context, cluster *kafkav1.KafkaCluster, logge
 //+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkaclusters,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkaclusters/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkaclusters/finalizers,verbs=update
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups=core,resources=services;configmaps;secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=zookeeper.nineinfra.tech,resources=zookeeperclusters,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=tlsroutes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -79,6 +90,18 @@ func (r *KafkaClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 			logger.Error(err, "Error occurred during create or update clusters")
 			return ctrl.Result{}, err
 		}
+		if cluster.Status.IsClusterInUpgradingState() || cluster.Status.HasPendingDynamicConfigs() ||
+			cluster.Status.IsExternalAddressPending() || cluster.Status.IsCARotationInProgress() ||
+			cluster.Status.IsDrainInProgress() || cluster.Status.IsRebalanceInProgress() ||
+			cluster.Status.IsCruiseControlMetricsTopicPending() || cluster.Status.IsCertificatesPending() {
+			return ctrl.Result{RequeueAfter: DefaultRequeueInterval}, nil
+		}
+		// the certificates are renewed by the reconciliation once they enter their renewal windows
+		requeueAfter := getCertificatesRenewAfter(&cluster)
+		if IsTopicSyncEnabled(&cluster) && (requeueAfter == 0 || getTopicSyncInterval(&cluster) < requeueAfter) {
+			requeueAfter = getTopicSyncInterval(&cluster)
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 
 	return ctrl.Result{}, nil
@@ -115,9 +138,12 @@ func (r *KafkaClusterReconciler) reconcileClusterStatus(ctx context.Context, clu
 	}
 	cluster.Status.Members.Ready = readyMembers
 	cluster.Status.Members.Unready = unreadyMembers
+	cluster.Status.Replicas = getWorkloadReplicas(cluster)
+	cluster.Status.InternalClientEndpoint = GetBootstrapServers(cluster)
+	cluster.Status.ReadyReplicas = int32(len(readyMembers))
 
 	logger.Info("Updating cluster status")
-	if cluster.Status.ReadyReplicas == cluster.Spec.Resource.Replicas {
+	if cluster.Status.ReadyReplicas == cluster.Status.Replicas {
 		cluster.Status.SetPodsReadyConditionTrue()
 	} else {
 		cluster.Status.SetPodsReadyConditionFalse()
@@ -148,6 +174,7 @@ func (r *KafkaClusterReconciler) reconcileHeadlessService(ctx context.Context, c
 		logger.Info("Updating existing headless service")
 		existsSvc.Spec.Ports = desiredSvc.Spec.Ports
 		existsSvc.Spec.Type = desiredSvc.Spec.Type
+		existsSvc.Spec.PublishNotReadyAddresses = desiredSvc.Spec.PublishNotReadyAddresses
 		err = r.Client.Update(context.TODO(), existsSvc)
 		if err != nil {
 			return err
@@ -184,6 +211,41 @@ func (r *KafkaClusterReconciler) reconcileService(ctx context.Context, cluster *
 	return nil
 }
 
+// updateStatusWithError persists the conditions set by a failed step and returns the error of the step
+func (r *KafkaClusterReconciler) updateStatusWithError(ctx context.Context, cluster *kafkav1.KafkaCluster, err error) error {
+	if updateErr := r.Client.Status().Update(context.TODO(), cluster); updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+func (r *KafkaClusterReconciler) reconcileClusterID(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) (err error) {
+	if !IsKraftMode(cluster) || (cluster.Status.ClusterID != "" && cluster.Status.KraftControllers != 0 &&
+		cluster.Status.ControllerSecurityProtocol != "") {
+		return nil
+	}
+	if cluster.Status.ControllerSecurityProtocol == "" {
+		// the clusters bootstrapped before the protocol was pinned keep their plaintext controller listener
+		tls := cluster.Status.ClusterID == "" && IsInternalTLSEnabled(cluster)
+		cluster.Status.ControllerSecurityProtocol = getSecurityProtocol(tls, "")
+	}
+	if cluster.Status.ClusterID == "" {
+		clusterID := cluster.Spec.Kraft.ClusterID
+		if clusterID == "" {
+			clusterID, err = newKafkaClusterID()
+			if err != nil {
+				return err
+			}
+		}
+		logger.Info(fmt.Sprintf("Initializing the kraft cluster id %s", clusterID))
+		cluster.Status.ClusterID = clusterID
+	}
+	// the clusters bootstrapped before the voters were pinned keep the voters they run with
+	cluster.Status.KraftControllers = GetKraftControllers(cluster)
+	// the cluster id, the voters and the protocol must be persisted before the storage of any broker is formatted with them
+	return r.Client.Status().Update(context.TODO(), cluster)
+}
+
 func (r *KafkaClusterReconciler) reconcileConfigMap(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) (err error) {
 	desiredCm, err := r.constructConfigMap(cluster)
 	if err != nil {
@@ -199,7 +261,7 @@ func (r *KafkaClusterReconciler) reconcileConfigMap(ctx context.Context, cluster
 		}
 	} else if err != nil {
 		return err
-	} else {
+	} else if !equality.Semantic.DeepEqual(existsCm.Data, desiredCm.Data) {
 		logger.Info("Updating existing ConfigMap")
 		existsCm.Data = desiredCm.Data
 		err = r.Client.Update(context.TODO(), existsCm)
@@ -227,18 +289,100 @@ func (r *KafkaClusterReconciler) reconcileWorkload(ctx context.Context, cluster
 	} else if err != nil {
 		return err
 	} else {
-		//Todo
+		return r.updateWorkload(ctx, cluster, existsSts, desiredSts, logger)
 	}
 	logger.Info("Creating a new KafkaCluster successfully")
 	return nil
 }
 
+// checkWorkloadImmutableFields returns an error describing the first change of the fields
+// which can not be updated after the creation of the StatefulSet
+func checkWorkloadImmutableFields(existsSts *appsv1.StatefulSet, desiredSts *appsv1.StatefulSet) error {
+	if existsSts.Spec.ServiceName != desiredSts.Spec.ServiceName {
+		return fmt.Errorf("serviceName of the StatefulSet %s can not be changed from %s to %s",
+			existsSts.Name, existsSts.Spec.ServiceName, desiredSts.Spec.ServiceName)
+	}
+	if !equality.Semantic.DeepEqual(existsSts.Spec.Selector, desiredSts.Spec.Selector) {
+		return fmt.Errorf("selector of the StatefulSet %s can not be changed", existsSts.Name)
+	}
+	existsPvcs := existsSts.Spec.VolumeClaimTemplates
+	desiredPvcs := desiredSts.Spec.VolumeClaimTemplates
+	if len(existsPvcs) != len(desiredPvcs) {
+		return fmt.Errorf("num of the volumeClaimTemplates of the StatefulSet %s can not be changed from %d to %d",
+			existsSts.Name, len(existsPvcs), len(desiredPvcs))
+	}
+	for i := range desiredPvcs {
+		existsPvc, desiredPvc := existsPvcs[i], desiredPvcs[i]
+		if existsPvc.Name != desiredPvc.Name ||
+			!equality.Semantic.DeepEqual(existsPvc.Spec.StorageClassName, desiredPvc.Spec.StorageClassName) ||
+			!equality.Semantic.DeepEqual(existsPvc.Spec.AccessModes, desiredPvc.Spec.AccessModes) ||
+			!equality.Semantic.DeepEqual(existsPvc.Spec.Resources.Requests, desiredPvc.Spec.Resources.Requests) {
+			return fmt.Errorf("volumeClaimTemplate %s of the StatefulSet %s can not be changed", desiredPvc.Name, existsSts.Name)
+		}
+	}
+	return nil
+}
+
+// isLegacyWorkload returns true if the StatefulSet was created by the operator before the pods were resolved by
+// the headless service, whose service name is the one of the client service
+func isLegacyWorkload(cluster *kafkav1.KafkaCluster, existsSts *appsv1.StatefulSet, desiredSts *appsv1.StatefulSet) bool {
+	return existsSts.Spec.ServiceName == ClusterResourceName(cluster) && desiredSts.Spec.ServiceName != existsSts.Spec.ServiceName
+}
+
+func (r *KafkaClusterReconciler) updateWorkload(ctx context.Context, cluster *kafkav1.KafkaCluster, existsSts *appsv1.StatefulSet, desiredSts *appsv1.StatefulSet, logger logr.Logger) error {
+	if isLegacyWorkload(cluster, existsSts, desiredSts) {
+		// the service name can not be updated, so the StatefulSet is deleted leaving the pods and the volumes behind,
+		// the one created next adopts them and the rolling restart recreates the pods with the headless service
+		logger.Info(fmt.Sprintf("Recreating the Kafka StatefulSet to change its service name from %s to %s",
+			existsSts.Spec.ServiceName, desiredSts.Spec.ServiceName))
+		return r.Client.Delete(ctx, existsSts, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+	}
+	if err := checkWorkloadImmutableFields(existsSts, desiredSts); err != nil {
+		// retrying does not help until the spec is changed back, so only the condition is reported
+		logger.Error(err, "Refusing to update the Kafka StatefulSet")
+		cluster.Status.SetErrorConditionTrue(kafkav1.ImmutableFieldChangedReason, err.Error())
+		return nil
+	}
+	if cluster.Status.IsClusterInErrorState(kafkav1.ImmutableFieldChangedReason) {
+		cluster.Status.SetErrorConditionFalse()
+	}
+	desiredHash := desiredSts.Annotations[DefaultSpecHashAnnotation]
+	if existsSts.Annotations[DefaultSpecHashAnnotation] == desiredHash {
+		return nil
+	}
+
+	logger.Info("Updating existing Kafka StatefulSet")
+	patch := client.MergeFrom(existsSts.DeepCopy())
+	if existsSts.Annotations == nil {
+		existsSts.Annotations = make(map[string]string)
+	}
+	existsSts.Annotations[DefaultSpecHashAnnotation] = desiredHash
+	existsSts.Labels = desiredSts.Labels
+	existsSts.Spec.Replicas = desiredSts.Spec.Replicas
+	existsSts.Spec.Template = desiredSts.Spec.Template
+	existsSts.Spec.UpdateStrategy = desiredSts.Spec.UpdateStrategy
+	existsSts.Spec.MinReadySeconds = desiredSts.Spec.MinReadySeconds
+	existsSts.Spec.PersistentVolumeClaimRetentionPolicy = desiredSts.Spec.PersistentVolumeClaimRetentionPolicy
+	return r.Client.Patch(context.TODO(), existsSts, patch)
+}
+
 func (r *KafkaClusterReconciler) reconcileClusters(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
 	for _, fun := range []reconcileFun{
+		r.reconcileClusterID,
+		r.reconcileZookeeper,
+		r.reconcileScaleDown,
+		r.reconcileExternalServices,
+		r.reconcileTLS,
+		r.reconcileClientsCA,
 		r.reconcileConfigMap,
 		r.reconcileWorkload,
+		r.reconcileRollingRestart,
 		r.reconcileService,
 		r.reconcileHeadlessService,
+		r.reconcileDynamicConfigs,
+		r.reconcileRebalance,
+		r.reconcileCruiseControl,
+		r.reconcileTopicSync,
 		r.reconcileClusterStatus,
 	} {
 		if err := fun(ctx, cluster, logger); err != nil {
@@ -251,7 +395,17 @@ func (r *KafkaClusterReconciler) reconcileClusters(ctx context.Context, cluster
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *KafkaClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&kafkav1.KafkaCluster{}).
-		Complete(r)
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Secret{})
+	// the certificates are watched only if cert-manager is installed, so the keystores follow its renewals
+	gvk := certificateGVK(CertificateKind)
+	if _, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err == nil {
+		certificate := &unstructured.Unstructured{}
+		certificate.SetGroupVersionKind(gvk)
+		builder = builder.Owns(certificate)
+	}
+	return builder.Complete(r)
 }
@@ -0,0 +1,680 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func IsCruiseControlEnabled(cluster *kafkav1.KafkaCluster) bool {
+	return cluster.Spec.CruiseControl != nil
+}
+
+func CruiseControlResourceName(cluster *kafkav1.KafkaCluster) string {
+	return ClusterResourceName(cluster, DefaultCruiseControlNameSuffix)
+}
+
+// CruiseControlResourceLabels differ from the labels of the brokers, so the cruise control is never counted as a broker
+func CruiseControlResourceLabels(cluster *kafkav1.KafkaCluster) map[string]string {
+	return map[string]string{
+		"cluster": cluster.Name,
+		"app":     DefaultCruiseControlSign,
+	}
+}
+
+func GetCruiseControlCredentialsSecretName(cluster *kafkav1.KafkaCluster) string {
+	return ClusterResourceName(cluster, DefaultCruiseControlCredentialsSecretSuffix)
+}
+
+// getCruiseControlClient returns the client of the rest api authenticated by the password of the operator
+func getCruiseControlClient(c client.Client, cluster *kafkav1.KafkaCluster) (*cruiseControlClient, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: GetCruiseControlCredentialsSecretName(cluster), Namespace: cluster.Namespace}, secret)
+	if err != nil {
+		return nil, err
+	}
+	password := string(secret.Data[DefaultCruiseControlPasswordKey])
+	if password == "" {
+		return nil, fmt.Errorf("secret %s has no password of the cruise control", secret.Name)
+	}
+	return newCruiseControlClient(GetCruiseControlURL(cluster), password), nil
+}
+
+// GetCruiseControlURL returns the url of the rest api of the cruise control
+func GetCruiseControlURL(cluster *kafkav1.KafkaCluster) string {
+	return fmt.Sprintf("http://%s.%s.svc.%s:%d%s", CruiseControlResourceName(cluster), cluster.Namespace,
+		GetClusterDomain(cluster), DefaultCruiseControlPort, DefaultCruiseControlAPIPrefix)
+}
+
+func getCruiseControlImageConfig(cluster *kafkav1.KafkaCluster) kafkav1.ImageConfig {
+	// the tag is required by the webhook
+	ic := cluster.Spec.CruiseControl.Image
+	if ic.PullPolicy == "" {
+		ic.PullPolicy = string(corev1.PullIfNotPresent)
+	}
+	return ic
+}
+
+func getMetricsReporterJar(cluster *kafkav1.KafkaCluster) string {
+	if cluster.Spec.CruiseControl.MetricsReporterJar != "" {
+		return cluster.Spec.CruiseControl.MetricsReporterJar
+	}
+	return DefaultMetricsReporterJar
+}
+
+// getCruiseControlTopicReplicationFactor returns the replication factor of the metrics topic and the sample store topics
+func getCruiseControlTopicReplicationFactor(cluster *kafkav1.KafkaCluster) int16 {
+	if replicas := getReplicas(cluster); replicas < DefaultCruiseControlMaxReplicationFactor {
+		return int16(replicas)
+	}
+	return DefaultCruiseControlMaxReplicationFactor
+}
+
+// getAdminSecurityProtocol returns the protocol of the listener used by the operator, which is never sasl authenticated
+func getAdminSecurityProtocol(cluster *kafkav1.KafkaCluster) string {
+	return getSecurityProtocol(IsInternalTLSEnabled(cluster), "")
+}
+
+// constructMetricsReporterConfKeyValue returns the configs of the metrics reporter of the brokers, which reports to the
+// listener used by the operator. the keystore of the pod is rendered by the start script
+func constructMetricsReporterConfKeyValue(cluster *kafkav1.KafkaCluster) map[string]string {
+	reporters := DefaultCruiseControlMetricsReporter
+	if value, ok := cluster.Spec.Conf["metric.reporters"]; ok && value != "" {
+		reporters = value + "," + reporters
+	}
+	conf := map[string]string{
+		"metric.reporters": reporters,
+		"cruise.control.metrics.reporter.bootstrap.servers": GetAdminBootstrapServers(cluster),
+		"cruise.control.metrics.reporter.security.protocol": getAdminSecurityProtocol(cluster),
+		"cruise.control.metrics.topic":                      DefaultCruiseControlMetricsTopic,
+		"cruise.control.metrics.topic.auto.create":          "false",
+	}
+	if IsInternalTLSEnabled(cluster) {
+		conf["cruise.control.metrics.reporter.ssl.truststore.type"] = "PEM"
+		conf["cruise.control.metrics.reporter.ssl.truststore.location"] = fmt.Sprintf("%s/%s", DefaultTLSPath, DefaultCACertKey)
+	}
+	return conf
+}
+
+// constructMetricsReporterScript renders the keystore of the pod into the config of the metrics reporter
+func constructMetricsReporterScript(cluster *kafkav1.KafkaCluster) string {
+	if !IsCruiseControlEnabled(cluster) || !isOperatorCertificateRequired(cluster) {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("echo \"cruise.control.metrics.reporter.ssl.keystore.type=PEM\" >> ${CONF}\n")
+	sb.WriteString(fmt.Sprintf("echo \"cruise.control.metrics.reporter.ssl.keystore.location=%s/${POD_NAME}%s\" >> ${CONF}\n",
+		DefaultTLSPath, DefaultKeystoreSuffix))
+	return sb.String()
+}
+
+// constructMetricsReporterInitContainer copies the jar of the metrics reporter from the image of the cruise control for the brokers
+func constructMetricsReporterInitContainer(cluster *kafkav1.KafkaCluster) corev1.Container {
+	ic := getCruiseControlImageConfig(cluster)
+	return corev1.Container{
+		Name:            DefaultMetricsReporterVolumeName,
+		Image:           ic.Repository + ":" + ic.Tag,
+		ImagePullPolicy: corev1.PullPolicy(ic.PullPolicy),
+		Command:         []string{"cp", getMetricsReporterJar(cluster), DefaultMetricsReporterPath + "/"},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      DefaultMetricsReporterVolumeName,
+				MountPath: DefaultMetricsReporterPath,
+			},
+		},
+	}
+}
+
+func constructCruiseControlConfKeyValue(cluster *kafkav1.KafkaCluster) map[string]string {
+	conf := make(map[string]string)
+	for k, v := range DefaultCruiseControlConfKeyValue {
+		conf[k] = v
+	}
+	for k, v := range cluster.Spec.CruiseControl.Conf {
+		conf[k] = v
+	}
+	factor := strconv.Itoa(int(getCruiseControlTopicReplicationFactor(cluster)))
+	if _, ok := cluster.Spec.CruiseControl.Conf["sample.store.topic.replication.factor"]; !ok {
+		conf["sample.store.topic.replication.factor"] = factor
+	}
+	conf["bootstrap.servers"] = GetAdminBootstrapServers(cluster)
+	conf["metric.reporter.topic"] = DefaultCruiseControlMetricsTopic
+	conf["capacity.config.file"] = fmt.Sprintf("%s/%s", DefaultCruiseControlConfPath, DefaultCruiseControlCapacityFileName)
+	conf["webserver.http.port"] = strconv.Itoa(DefaultCruiseControlPort)
+	// the rest api executes the proposals as a super user of the cluster, so only the operator can call it
+	conf["webserver.security.enable"] = "true"
+	conf["webserver.security.provider"] = DefaultCruiseControlSecurityProvider
+	conf["webserver.auth.credentials.file"] = fmt.Sprintf("%s/%s", DefaultCruiseControlCredentialsPath, DefaultCruiseControlCredentialsFileName)
+	if !IsKraftMode(cluster) && cluster.Status.ZookeeperConnect != "" {
+		conf["zookeeper.connect"] = cluster.Status.ZookeeperConnect
+	}
+	conf["security.protocol"] = getAdminSecurityProtocol(cluster)
+	if IsInternalTLSEnabled(cluster) {
+		conf["ssl.truststore.type"] = "PEM"
+		conf["ssl.truststore.location"] = fmt.Sprintf("%s/%s", DefaultCruiseControlTLSPath, DefaultCACertKey)
+	}
+	if isOperatorCertificateRequired(cluster) {
+		conf["ssl.keystore.type"] = "PEM"
+		conf["ssl.keystore.location"] = fmt.Sprintf("%s/%s", DefaultCruiseControlRuntimePath, DefaultCruiseControlKeystoreFileName)
+	}
+	return conf
+}
+
+type brokerCapacityResources struct {
+	Disk  map[string]string `json:"DISK"`
+	CPU   map[string]string `json:"CPU"`
+	NwIn  string            `json:"NW_IN"`
+	NwOut string            `json:"NW_OUT"`
+}
+
+type brokerCapacity struct {
+	BrokerID string                  `json:"brokerId"`
+	Capacity brokerCapacityResources `json:"capacity"`
+	Doc      string                  `json:"doc,omitempty"`
+}
+
+type brokerCapacities struct {
+	BrokerCapacities []brokerCapacity `json:"brokerCapacities"`
+}
+
+// getBrokerCPUCapacity returns the num of the cpu cores of a broker, rounded up
+func getBrokerCPUCapacity(cluster *kafkav1.KafkaCluster) int64 {
+	if c := cluster.Spec.CruiseControl.Capacity; c != nil && c.CPU != nil {
+		return (c.CPU.MilliValue() + 999) / 1000
+	}
+	resources := cluster.Spec.Resource.ResourceRequirements
+	for _, list := range []corev1.ResourceList{resources.Limits, resources.Requests} {
+		if q, ok := list[corev1.ResourceCPU]; ok && !q.IsZero() {
+			return (q.MilliValue() + 999) / 1000
+		}
+	}
+	return DefaultCruiseControlCPUCapacity
+}
+
+func getBrokerNetworkCapacity(cluster *kafkav1.KafkaCluster) (int64, int64) {
+	in, out := int64(DefaultCruiseControlNetworkCapacity), int64(DefaultCruiseControlNetworkCapacity)
+	if c := cluster.Spec.CruiseControl.Capacity; c != nil {
+		if c.InboundNetwork > 0 {
+			in = c.InboundNetwork
+		}
+		if c.OutboundNetwork > 0 {
+			out = c.OutboundNetwork
+		}
+	}
+	return in, out
+}
+
+// constructCruiseControlCapacity renders the capacity shared by all the brokers, the disks in MiB and the network in KiB per second
+func (r *KafkaClusterReconciler) constructCruiseControlCapacity(cluster *kafkav1.KafkaCluster) (string, error) {
+	q, err := r.getStorageRequests(cluster)
+	if err != nil {
+		return "", err
+	}
+	disks := make(map[string]string)
+	for i := 0; i < int(getDiskNum(cluster)); i++ {
+		disks[fmt.Sprintf("%s/%s%d", DefaultDataPath, DefaultDiskPathPrefix, i)] = strconv.FormatInt(q.Value()/(1024*1024), 10)
+	}
+	in, out := getBrokerNetworkCapacity(cluster)
+	capacities := brokerCapacities{
+		BrokerCapacities: []brokerCapacity{
+			{
+				BrokerID: "-1",
+				Capacity: brokerCapacityResources{
+					Disk:  disks,
+					CPU:   map[string]string{"num.cores": strconv.FormatInt(getBrokerCPUCapacity(cluster), 10)},
+					NwIn:  strconv.FormatInt(in/1024, 10),
+					NwOut: strconv.FormatInt(out/1024, 10),
+				},
+				Doc: "the default capacity of the brokers, derived from the resources of the cluster",
+			},
+		},
+	}
+	data, err := json.MarshalIndent(capacities, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// constructCruiseControlStartScript renders the keystore from the client certificate of the operator before starting the cruise control
+func constructCruiseControlStartScript(cluster *kafkav1.KafkaCluster) string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/bash\n")
+	sb.WriteString("set -e\n")
+	if isOperatorCertificateRequired(cluster) {
+		sb.WriteString(fmt.Sprintf("cat %s/%s %s/%s > %s/%s\n",
+			DefaultCruiseControlTLSPath, corev1.TLSPrivateKeyKey,
+			DefaultCruiseControlTLSPath, corev1.TLSCertKey,
+			DefaultCruiseControlRuntimePath, DefaultCruiseControlKeystoreFileName))
+	}
+	sb.WriteString(fmt.Sprintf("cd %s\n", DefaultCruiseControlHome))
+	sb.WriteString(fmt.Sprintf("exec ./kafka-cruise-control-start.sh %s/%s\n", DefaultCruiseControlConfPath, DefaultCruiseControlConfigFileName))
+	return sb.String()
+}
+
+// constructCruiseControlCredentials renders the credentials file of the rest api, the operator is its only user
+func constructCruiseControlCredentials(password string) string {
+	return fmt.Sprintf("%s: %s,ADMIN\n", DefaultCruiseControlUsername, password)
+}
+
+// reconcileCruiseControlCredentials generates the password of the operator once, it returns the password
+func (r *KafkaClusterReconciler) reconcileCruiseControlCredentials(cluster *kafkav1.KafkaCluster, logger logr.Logger) (string, error) {
+	secret := &corev1.Secret{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: GetCruiseControlCredentialsSecretName(cluster), Namespace: cluster.Namespace}, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	exists := err == nil
+	if exists && len(secret.Data[DefaultCruiseControlPasswordKey]) > 0 {
+		password := string(secret.Data[DefaultCruiseControlPasswordKey])
+		credentials := constructCruiseControlCredentials(password)
+		if string(secret.Data[DefaultCruiseControlCredentialsFileName]) == credentials {
+			return password, nil
+		}
+		logger.Info("Updating the credentials of the cruise control")
+		secret.Data[DefaultCruiseControlCredentialsFileName] = []byte(credentials)
+		return password, r.Client.Update(context.TODO(), secret)
+	}
+	password, err := newRandomPassword()
+	if err != nil {
+		return "", err
+	}
+	data := map[string][]byte{
+		DefaultCruiseControlPasswordKey:         []byte(password),
+		DefaultCruiseControlCredentialsFileName: []byte(constructCruiseControlCredentials(password)),
+	}
+	if exists {
+		logger.Info("Generating the password of the cruise control")
+		secret.Data = data
+		return password, r.Client.Update(context.TODO(), secret)
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetCruiseControlCredentialsSecretName(cluster),
+			Namespace: cluster.Namespace,
+			Labels:    CruiseControlResourceLabels(cluster),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	if err = ctrl.SetControllerReference(cluster, secret, r.Scheme); err != nil {
+		return "", err
+	}
+	logger.Info("Creating the credentials of the cruise control")
+	return password, r.Client.Create(context.TODO(), secret)
+}
+
+func (r *KafkaClusterReconciler) constructCruiseControlConfigMap(cluster *kafkav1.KafkaCluster) (*corev1.ConfigMap, error) {
+	capacity, err := r.constructCruiseControlCapacity(cluster)
+	if err != nil {
+		return nil, err
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CruiseControlResourceName(cluster),
+			Namespace: cluster.Namespace,
+			Labels:    CruiseControlResourceLabels(cluster),
+		},
+		Data: map[string]string{
+			DefaultCruiseControlConfigFileName:      map2String(constructCruiseControlConfKeyValue(cluster)),
+			DefaultCruiseControlCapacityFileName:    capacity,
+			DefaultCruiseControlStartScriptFileName: constructCruiseControlStartScript(cluster),
+		},
+	}
+	if err := ctrl.SetControllerReference(cluster, cm, r.Scheme); err != nil {
+		return cm, err
+	}
+	return cm, nil
+}
+
+func constructCruiseControlVolumes(cluster *kafkav1.KafkaCluster) ([]corev1.Volume, []corev1.VolumeMount) {
+	volumes := []corev1.Volume{
+		{
+			Name: DefaultCruiseControlConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: CruiseControlResourceName(cluster)},
+				},
+			},
+		},
+		{
+			Name: DefaultRuntimeVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+		{
+			Name: DefaultCruiseControlCredentialsVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: GetCruiseControlCredentialsSecretName(cluster),
+					Items:      []corev1.KeyToPath{{Key: DefaultCruiseControlCredentialsFileName, Path: DefaultCruiseControlCredentialsFileName}},
+				},
+			},
+		},
+	}
+	volumeMounts := []corev1.VolumeMount{
+		{
+			Name:      DefaultCruiseControlConfigVolumeName,
+			MountPath: DefaultCruiseControlConfPath,
+		},
+		{
+			Name:      DefaultRuntimeVolumeName,
+			MountPath: DefaultCruiseControlRuntimePath,
+		},
+		{
+			Name:      DefaultCruiseControlCredentialsVolumeName,
+			MountPath: DefaultCruiseControlCredentialsPath,
+			ReadOnly:  true,
+		},
+	}
+	if !IsInternalTLSEnabled(cluster) {
+		return volumes, volumeMounts
+	}
+	// the cluster CA and the client certificate of the operator are projected into a single directory
+	sources := []corev1.VolumeProjection{
+		{
+			Secret: &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: GetClusterCACertSecretName(cluster)},
+				Items:                []corev1.KeyToPath{{Key: DefaultCACertKey, Path: DefaultCACertKey}},
+			},
+		},
+	}
+	if isOperatorCertificateRequired(cluster) {
+		sources = append(sources, corev1.VolumeProjection{
+			Secret: &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: GetOperatorTLSSecretName(cluster)},
+				Items: []corev1.KeyToPath{
+					{Key: corev1.TLSCertKey, Path: corev1.TLSCertKey},
+					{Key: corev1.TLSPrivateKeyKey, Path: corev1.TLSPrivateKeyKey},
+				},
+			},
+		})
+	}
+	volumes = append(volumes, corev1.Volume{
+		Name: DefaultOperatorTLSVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{Sources: sources},
+		},
+	})
+	volumeMounts = append(volumeMounts, corev1.VolumeMount{
+		Name:      DefaultOperatorTLSVolumeName,
+		MountPath: DefaultCruiseControlTLSPath,
+		ReadOnly:  true,
+	})
+	return volumes, volumeMounts
+}
+
+func (r *KafkaClusterReconciler) constructCruiseControlDeployment(cluster *kafkav1.KafkaCluster, cm *corev1.ConfigMap, password string) (*appsv1.Deployment, error) {
+	// the cruise control only reads the configs on start, so a change of the configs, the certificates or the
+	// credentials must restart it
+	configHash, err := hashObject([]interface{}{cm.Data, cluster.Status.CertificatesHash, password})
+	if err != nil {
+		return nil, err
+	}
+	ic := getCruiseControlImageConfig(cluster)
+	var pullSecrets []corev1.LocalObjectReference
+	if ic.PullSecrets != "" {
+		pullSecrets = []corev1.LocalObjectReference{{Name: ic.PullSecrets}}
+	}
+	volumes, volumeMounts := constructCruiseControlVolumes(cluster)
+	// the rest api requires the credentials, which the probe does not have
+	probe := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{
+				Port: intstr.FromInt32(DefaultCruiseControlPort),
+			},
+		},
+		InitialDelaySeconds: DefaultReadinessProbeInitialDelaySeconds,
+		PeriodSeconds:       DefaultReadinessProbePeriodSeconds,
+		TimeoutSeconds:      DefaultReadinessProbeTimeoutSeconds,
+		FailureThreshold:    DefaultReadinessProbeFailureThreshold,
+	}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CruiseControlResourceName(cluster),
+			Namespace: cluster.Namespace,
+			Labels:    CruiseControlResourceLabels(cluster),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(1),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: CruiseControlResourceLabels(cluster),
+			},
+			// a single cruise control must run at a time, since it executes the proposals
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RecreateDeploymentStrategyType,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: CruiseControlResourceLabels(cluster),
+					Annotations: map[string]string{
+						DefaultCruiseControlConfigHashAnnotation: configHash,
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:            DefaultCruiseControlSign,
+							Image:           ic.Repository + ":" + ic.Tag,
+							ImagePullPolicy: corev1.PullPolicy(ic.PullPolicy),
+							Command: []string{"/bin/bash", fmt.Sprintf("%s/%s", DefaultCruiseControlConfPath,
+								DefaultCruiseControlStartScriptFileName)},
+							Ports: []corev1.ContainerPort{
+								{
+									Name:          DefaultCruiseControlPortName,
+									ContainerPort: DefaultCruiseControlPort,
+								},
+							},
+							Resources:      cluster.Spec.CruiseControl.ResourceRequirements,
+							ReadinessProbe: probe,
+							VolumeMounts:   volumeMounts,
+						},
+					},
+					ImagePullSecrets: pullSecrets,
+					Volumes:          volumes,
+				},
+			},
+		},
+	}
+	specHash, err := hashObject(deploy.Spec)
+	if err != nil {
+		return nil, err
+	}
+	deploy.Annotations = map[string]string{
+		DefaultSpecHashAnnotation: specHash,
+	}
+	if err := ctrl.SetControllerReference(cluster, deploy, r.Scheme); err != nil {
+		return deploy, err
+	}
+	return deploy, nil
+}
+
+func (r *KafkaClusterReconciler) constructCruiseControlService(cluster *kafkav1.KafkaCluster) (*corev1.Service, error) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CruiseControlResourceName(cluster),
+			Namespace: cluster.Namespace,
+			Labels:    CruiseControlResourceLabels(cluster),
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name: DefaultCruiseControlPortName,
+					Port: DefaultCruiseControlPort,
+				},
+			},
+			Selector: CruiseControlResourceLabels(cluster),
+			Type:     corev1.ServiceTypeClusterIP,
+		},
+	}
+	if err := ctrl.SetControllerReference(cluster, svc, r.Scheme); err != nil {
+		return svc, err
+	}
+	return svc, nil
+}
+
+// createCruiseControlMetricsTopic creates the topic the brokers report their metrics to, since the reporters do not create it
+func (r *KafkaClusterReconciler) createCruiseControlMetricsTopic(cluster *kafkav1.KafkaCluster) error {
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	retention := strconv.Itoa(DefaultCruiseControlMetricsRetention)
+	cleanupPolicy := "delete"
+	err = admin.CreateTopic(DefaultCruiseControlMetricsTopic, &sarama.TopicDetail{
+		NumPartitions:     1,
+		ReplicationFactor: getCruiseControlTopicReplicationFactor(cluster),
+		ConfigEntries: map[string]*string{
+			"retention.ms":   &retention,
+			"cleanup.policy": &cleanupPolicy,
+		},
+	}, false)
+	if err != nil && !isKafkaError(err, sarama.ErrTopicAlreadyExists) {
+		return err
+	}
+	return nil
+}
+
+func (r *KafkaClusterReconciler) applyCruiseControlConfigMap(desiredCm *corev1.ConfigMap, logger logr.Logger) error {
+	existsCm := &corev1.ConfigMap{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: desiredCm.Name, Namespace: desiredCm.Namespace}, existsCm)
+	if err != nil && errors.IsNotFound(err) {
+		logger.Info("Creating the ConfigMap of the cruise control")
+		return r.Client.Create(context.TODO(), desiredCm)
+	} else if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existsCm.Data, desiredCm.Data) {
+		return nil
+	}
+	logger.Info("Updating the ConfigMap of the cruise control")
+	existsCm.Data = desiredCm.Data
+	return r.Client.Update(context.TODO(), existsCm)
+}
+
+// applyCruiseControlDeployment creates or updates the deployment, it returns true if the cruise control is available
+func (r *KafkaClusterReconciler) applyCruiseControlDeployment(desiredDeploy *appsv1.Deployment, logger logr.Logger) (bool, error) {
+	existsDeploy := &appsv1.Deployment{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: desiredDeploy.Name, Namespace: desiredDeploy.Namespace}, existsDeploy)
+	if err != nil && errors.IsNotFound(err) {
+		logger.Info("Creating the Deployment of the cruise control")
+		return false, r.Client.Create(context.TODO(), desiredDeploy)
+	} else if err != nil {
+		return false, err
+	}
+	desiredHash := desiredDeploy.Annotations[DefaultSpecHashAnnotation]
+	if existsDeploy.Annotations[DefaultSpecHashAnnotation] != desiredHash {
+		logger.Info("Updating the Deployment of the cruise control")
+		if existsDeploy.Annotations == nil {
+			existsDeploy.Annotations = make(map[string]string)
+		}
+		existsDeploy.Annotations[DefaultSpecHashAnnotation] = desiredHash
+		existsDeploy.Spec.Replicas = desiredDeploy.Spec.Replicas
+		existsDeploy.Spec.Strategy = desiredDeploy.Spec.Strategy
+		existsDeploy.Spec.Template = desiredDeploy.Spec.Template
+		return false, r.Client.Update(context.TODO(), existsDeploy)
+	}
+	return existsDeploy.Status.ObservedGeneration == existsDeploy.Generation && existsDeploy.Status.AvailableReplicas > 0, nil
+}
+
+func (r *KafkaClusterReconciler) applyCruiseControlService(desiredSvc *corev1.Service, logger logr.Logger) error {
+	existsSvc := &corev1.Service{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: desiredSvc.Name, Namespace: desiredSvc.Namespace}, existsSvc)
+	if err != nil && errors.IsNotFound(err) {
+		logger.Info("Creating the Service of the cruise control")
+		return r.Client.Create(context.TODO(), desiredSvc)
+	} else if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existsSvc.Spec.Ports, desiredSvc.Spec.Ports) {
+		return nil
+	}
+	existsSvc.Spec.Ports = desiredSvc.Spec.Ports
+	return r.Client.Update(context.TODO(), existsSvc)
+}
+
+// deleteCruiseControl removes the cruise control once it is disabled
+func (r *KafkaClusterReconciler) deleteCruiseControl(cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	meta := metav1.ObjectMeta{Name: CruiseControlResourceName(cluster), Namespace: cluster.Namespace}
+	for _, obj := range []client.Object{
+		&appsv1.Deployment{ObjectMeta: meta},
+		&corev1.Service{ObjectMeta: meta},
+		&corev1.ConfigMap{ObjectMeta: meta},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: GetCruiseControlCredentialsSecretName(cluster), Namespace: cluster.Namespace}},
+	} {
+		if err := r.Client.Delete(context.TODO(), obj); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	logger.Info("Deleted the cruise control")
+	return nil
+}
+
+// reconcileCruiseControl deploys the cruise control next to the cluster. the metrics topic is created once the cluster is ready
+func (r *KafkaClusterReconciler) reconcileCruiseControl(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	if !IsCruiseControlEnabled(cluster) {
+		if cluster.Status.CruiseControl == nil {
+			return nil
+		}
+		if err := r.deleteCruiseControl(cluster, logger); err != nil {
+			return err
+		}
+		cluster.Status.CruiseControl = nil
+		return nil
+	}
+	if cluster.Status.CruiseControl == nil {
+		cluster.Status.CruiseControl = &kafkav1.CruiseControlStatus{}
+	}
+	status := cluster.Status.CruiseControl
+	status.Endpoint = GetCruiseControlURL(cluster)
+	if !status.MetricsTopicReady && cluster.Status.IsClusterInReadyState() {
+		// the cruise control is deployed regardless of the topic, it waits for the metrics anyway
+		if err := r.createCruiseControlMetricsTopic(cluster); err != nil {
+			logger.Error(err, "Error occurred during creating the metrics topic of the cruise control")
+		} else {
+			logger.Info(fmt.Sprintf("Created the metrics topic %s of the cruise control", DefaultCruiseControlMetricsTopic))
+			status.MetricsTopicReady = true
+		}
+	}
+
+	cm, err := r.constructCruiseControlConfigMap(cluster)
+	if err != nil {
+		return err
+	}
+	if err = r.applyCruiseControlConfigMap(cm, logger); err != nil {
+		return err
+	}
+	password, err := r.reconcileCruiseControlCredentials(cluster, logger)
+	if err != nil {
+		return err
+	}
+	deploy, err := r.constructCruiseControlDeployment(cluster, cm, password)
+	if err != nil {
+		return err
+	}
+	if status.Ready, err = r.applyCruiseControlDeployment(deploy, logger); err != nil {
+		return err
+	}
+	svc, err := r.constructCruiseControlService(cluster)
+	if err != nil {
+		return err
+	}
+	return r.applyCruiseControlService(svc, logger)
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+// getConfigUpdateMode returns how a config of the brokers can be updated.
+// the listener specific configs such as listener.name.external.ssl.keystore.location are per-broker
+func getConfigUpdateMode(key string) string {
+	if mode, ok := DynamicConfigUpdateModes[key]; ok {
+		return mode
+	}
+	if strings.HasPrefix(key, "listener.name.") {
+		parts := strings.SplitN(key, ".", 4)
+		if len(parts) == 4 {
+			config := parts[3]
+			// sasl configs may be prefixed with the mechanism, such as scram-sha-512.sasl.jaas.config
+			if i := strings.Index(config, "sasl."); i > 0 {
+				config = config[i:]
+			}
+			if DynamicConfigUpdateModes[config] == ConfigUpdateModePerBroker {
+				return ConfigUpdateModePerBroker
+			}
+		}
+	}
+	return ConfigUpdateModeReadOnly
+}
+
+func getReadOnlyConfigs(kv map[string]string) map[string]string {
+	configs := make(map[string]string)
+	for k, v := range kv {
+		if getConfigUpdateMode(k) == ConfigUpdateModeReadOnly {
+			configs[k] = v
+		}
+	}
+	return configs
+}
+
+func getDynamicConfigs(kv map[string]string) map[string]string {
+	configs := make(map[string]string)
+	for k, v := range kv {
+		if getConfigUpdateMode(k) != ConfigUpdateModeReadOnly {
+			configs[k] = v
+		}
+	}
+	return configs
+}
+
+// isSecretConfig returns true if the value of the config must not be shown in the status
+func isSecretConfig(key string) bool {
+	return strings.HasSuffix(key, "password") || strings.HasSuffix(key, "sasl.jaas.config") || strings.HasSuffix(key, "ssl.keystore.key")
+}
+
+// newDynamicConfigStatus returns the status of the value of the config, the value of a secret is only kept as its hash
+func newDynamicConfigStatus(key string, value string) kafkav1.DynamicConfigStatus {
+	sum := sha256.Sum256([]byte(value))
+	status := kafkav1.DynamicConfigStatus{
+		Key:        key,
+		Hash:       hex.EncodeToString(sum[:]),
+		UpdateMode: getConfigUpdateMode(key),
+	}
+	if !isSecretConfig(key) {
+		status.Value = value
+	}
+	return status
+}
+
+// applyDynamicConfig sets the config, or deletes it if the value is nil. cluster-wide configs are
+// set as the cluster default while per-broker configs are set on every broker
+func applyDynamicConfig(admin sarama.ClusterAdmin, brokers []*sarama.Broker, key string, value *string) error {
+	entry := sarama.IncrementalAlterConfigsEntry{
+		Operation: sarama.IncrementalAlterConfigsOperationSet,
+		Value:     value,
+	}
+	if value == nil {
+		entry.Operation = sarama.IncrementalAlterConfigsOperationDelete
+	}
+	entries := map[string]sarama.IncrementalAlterConfigsEntry{key: entry}
+	if getConfigUpdateMode(key) == ConfigUpdateModeClusterWide {
+		return admin.IncrementalAlterConfig(sarama.BrokerResource, "", entries, false)
+	}
+	failed := make([]string, 0)
+	var lastErr error
+	for _, b := range brokers {
+		id := strconv.Itoa(int(b.ID()))
+		if err := admin.IncrementalAlterConfig(sarama.BrokerResource, id, entries, false); err != nil {
+			failed = append(failed, id)
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return fmt.Errorf("failed to apply to the brokers %s: %w", strings.Join(failed, ","), lastErr)
+	}
+	return nil
+}
+
+// reconcileDynamicConfigs applies the changes of the dynamic configs to the running brokers through the admin api.
+// changes of the read-only configs are rolled out by restarting the brokers instead
+func (r *KafkaClusterReconciler) reconcileDynamicConfigs(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	desired := getDynamicConfigs(constructClusterConfKeyValue(cluster))
+	now := time.Now().Format(time.RFC3339)
+	if cluster.Status.DynamicConfigs == nil {
+		// the brokers are started with the dynamic configs in the static config file
+		for _, key := range sortedKeys(desired) {
+			status := newDynamicConfigStatus(key, desired[key])
+			status.Applied = true
+			status.LastAppliedTime = now
+			cluster.Status.DynamicConfigs = append(cluster.Status.DynamicConfigs, status)
+		}
+		return nil
+	}
+
+	current := make(map[string]kafkav1.DynamicConfigStatus)
+	for _, c := range cluster.Status.DynamicConfigs {
+		current[c.Key] = c
+	}
+	changed := make([]string, 0)
+	for key, value := range desired {
+		// the configs recorded before their hashes are applied again, which drops their values of secrets
+		if c, ok := current[key]; !ok || c.Hash != newDynamicConfigStatus(key, value).Hash || !c.Applied {
+			changed = append(changed, key)
+		}
+	}
+	for key := range current {
+		if _, ok := desired[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+
+	var brokers []*sarama.Broker
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err == nil {
+		defer admin.Close()
+		brokers, _, err = admin.DescribeCluster()
+	}
+	for _, key := range changed {
+		value, keep := desired[key]
+		status := newDynamicConfigStatus(key, value)
+		if !keep {
+			status.Hash = ""
+		}
+		if err == nil {
+			var v *string
+			if keep {
+				v = &value
+			}
+			if applyErr := applyDynamicConfig(admin, brokers, key, v); applyErr != nil {
+				logger.Error(applyErr, fmt.Sprintf("Failed to apply the dynamic config %s", key))
+				status.Message = applyErr.Error()
+			} else {
+				logger.Info(fmt.Sprintf("Applied the dynamic config %s", key))
+				status.Applied = true
+				status.LastAppliedTime = now
+			}
+		} else {
+			status.Message = fmt.Sprintf("failed to connect to the cluster: %s", err.Error())
+		}
+		if !keep && status.Applied {
+			delete(current, key)
+		} else {
+			current[key] = status
+		}
+	}
+
+	cluster.Status.DynamicConfigs = make([]kafkav1.DynamicConfigStatus, 0, len(current))
+	for _, key := range sortedKeys(current) {
+		cluster.Status.DynamicConfigs = append(cluster.Status.DynamicConfigs, current[key])
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestNewDynamicConfigStatus(t *testing.T) {
+	g := NewWithT(t)
+	for _, key := range []string{
+		"ssl.keystore.password",
+		"listener.name.external.ssl.key.password",
+		"listener.name.internal.scram-sha-512.sasl.jaas.config",
+		"listener.name.external.ssl.keystore.key",
+	} {
+		status := newDynamicConfigStatus(key, "secret")
+		g.Expect(status.Value).To(BeEmpty(), key)
+		g.Expect(status.Hash).NotTo(BeEmpty(), key)
+		g.Expect(status.Hash).NotTo(Equal(newDynamicConfigStatus(key, "rotated").Hash), key)
+	}
+	status := newDynamicConfigStatus("log.retention.ms", "1000")
+	g.Expect(status.Value).To(Equal("1000"))
+}
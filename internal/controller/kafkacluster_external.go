@@ -0,0 +1,322 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func IsExternalListenerEnabled(cluster *kafkav1.KafkaCluster) bool {
+	return cluster.Spec.Listeners != nil && cluster.Spec.Listeners.External != nil
+}
+
+func getExternalListenerType(cluster *kafkav1.KafkaCluster) kafkav1.ExternalListenerType {
+	return cluster.Spec.Listeners.External.Type
+}
+
+func externalServiceLabels(cluster *kafkav1.KafkaCluster) map[string]string {
+	svcLabels := ClusterResourceLabels(cluster)
+	svcLabels[DefaultListenerLabel] = DefaultExternalPortName
+	return svcLabels
+}
+
+func GetBrokerExternalSvcName(cluster *kafkav1.KafkaCluster, ordinal int) string {
+	return ClusterResourceName(cluster, DefaultExternalSvcNameSuffix, strconv.Itoa(ordinal))
+}
+
+func GetExternalBootstrapSvcName(cluster *kafkav1.KafkaCluster) string {
+	return ClusterResourceName(cluster, DefaultExternalSvcNameSuffix+DefaultBootstrapNameSuffix)
+}
+
+// isExternalRouted returns true if the external connections are routed by SNI through ingresses or gateways
+func isExternalRouted(cluster *kafkav1.KafkaCluster) bool {
+	if !IsExternalListenerEnabled(cluster) {
+		return false
+	}
+	switch getExternalListenerType(cluster) {
+	case kafkav1.ExternalListenerTypeIngress, kafkav1.ExternalListenerTypeGateway:
+		return true
+	}
+	return false
+}
+
+// getExternalSecurityProtocol returns the security protocol of the external listener.
+// the SNI routing requires the tls to be terminated by the brokers
+func getExternalSecurityProtocol(cluster *kafkav1.KafkaCluster) string {
+	return getSecurityProtocol(IsExternalTLSEnabled(cluster), getExternalAuthentication(cluster))
+}
+
+func getExternalHost(cluster *kafkav1.KafkaCluster, ordinal int) string {
+	return strings.ReplaceAll(cluster.Spec.Listeners.External.HostTemplate, kafkav1.HostTemplateOrdinalPlaceholder, strconv.Itoa(ordinal))
+}
+
+func getExternalBootstrapHost(cluster *kafkav1.KafkaCluster) string {
+	if cluster.Spec.Listeners.External.BootstrapHost != "" {
+		return cluster.Spec.Listeners.External.BootstrapHost
+	}
+	return strings.ReplaceAll(cluster.Spec.Listeners.External.HostTemplate, kafkav1.HostTemplateOrdinalPlaceholder, DefaultBootstrapHostOrdinal)
+}
+
+func getExternalRoutePort(cluster *kafkav1.KafkaCluster) int32 {
+	if cluster.Spec.Listeners.External.Port != 0 {
+		return cluster.Spec.Listeners.External.Port
+	}
+	return DefaultExternalRoutePort
+}
+
+func (r *KafkaClusterReconciler) constructExternalService(cluster *kafkav1.KafkaCluster, name string, selector map[string]string) (*corev1.Service, error) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cluster.Namespace,
+			Labels:    externalServiceLabels(cluster),
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       DefaultExternalPortName,
+					Protocol:   corev1.ProtocolTCP,
+					Port:       DefaultExternalPort,
+					TargetPort: intstr.FromInt32(DefaultExternalPort),
+				},
+			},
+			Selector: selector,
+		},
+	}
+	external := cluster.Spec.Listeners.External
+	if !isExternalRouted(cluster) {
+		svc.Annotations = copyAnnotations(external.Annotations)
+	}
+	switch getExternalListenerType(cluster) {
+	case kafkav1.ExternalListenerTypeLoadBalancer:
+		svc.Spec.Type = corev1.ServiceTypeLoadBalancer
+		svc.Spec.LoadBalancerSourceRanges = external.LoadBalancerSourceRanges
+	case kafkav1.ExternalListenerTypeIngress, kafkav1.ExternalListenerTypeGateway:
+		// the ingresses and the routes are the entrance, the services only back them
+		svc.Spec.Type = corev1.ServiceTypeClusterIP
+	default:
+		svc.Spec.Type = corev1.ServiceTypeNodePort
+	}
+	if err := ctrl.SetControllerReference(cluster, svc, r.Scheme); err != nil {
+		return svc, err
+	}
+	return svc, nil
+}
+
+func copyAnnotations(annotations map[string]string) map[string]string {
+	if len(annotations) == 0 {
+		return nil
+	}
+	copied := make(map[string]string)
+	for k, v := range annotations {
+		copied[k] = v
+	}
+	return copied
+}
+
+// applyExternalService creates or updates the service and returns the live one.
+// the node ports allocated before are kept unless the service becomes a ClusterIP one
+func (r *KafkaClusterReconciler) applyExternalService(desiredSvc *corev1.Service, logger logr.Logger) (*corev1.Service, error) {
+	existsSvc := &corev1.Service{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: desiredSvc.Name, Namespace: desiredSvc.Namespace}, existsSvc)
+	if err != nil && errors.IsNotFound(err) {
+		logger.Info(fmt.Sprintf("Creating a new external service %s", desiredSvc.Name))
+		err = r.Client.Create(context.TODO(), desiredSvc)
+		return desiredSvc, err
+	} else if err != nil {
+		return nil, err
+	}
+	for i := range desiredSvc.Spec.Ports {
+		if desiredSvc.Spec.Type == corev1.ServiceTypeClusterIP {
+			break
+		}
+		for _, p := range existsSvc.Spec.Ports {
+			if p.Name == desiredSvc.Spec.Ports[i].Name && desiredSvc.Spec.Ports[i].NodePort == 0 {
+				desiredSvc.Spec.Ports[i].NodePort = p.NodePort
+			}
+		}
+	}
+	if equality.Semantic.DeepEqual(existsSvc.Labels, desiredSvc.Labels) &&
+		equality.Semantic.DeepEqual(existsSvc.Annotations, desiredSvc.Annotations) &&
+		equality.Semantic.DeepEqual(existsSvc.Spec.LoadBalancerSourceRanges, desiredSvc.Spec.LoadBalancerSourceRanges) &&
+		equality.Semantic.DeepEqual(existsSvc.Spec.Ports, desiredSvc.Spec.Ports) &&
+		existsSvc.Spec.Type == desiredSvc.Spec.Type &&
+		equality.Semantic.DeepEqual(existsSvc.Spec.Selector, desiredSvc.Spec.Selector) {
+		return existsSvc, nil
+	}
+	logger.Info(fmt.Sprintf("Updating the external service %s", desiredSvc.Name))
+	existsSvc.Labels = desiredSvc.Labels
+	existsSvc.Annotations = desiredSvc.Annotations
+	existsSvc.Spec.LoadBalancerSourceRanges = desiredSvc.Spec.LoadBalancerSourceRanges
+	existsSvc.Spec.Ports = desiredSvc.Spec.Ports
+	existsSvc.Spec.Type = desiredSvc.Spec.Type
+	existsSvc.Spec.Selector = desiredSvc.Spec.Selector
+	if err = r.Client.Update(context.TODO(), existsSvc); err != nil {
+		return nil, err
+	}
+	return existsSvc, nil
+}
+
+// deleteStaleExternalServices deletes the external services which are not desired any more
+func (r *KafkaClusterReconciler) deleteStaleExternalServices(cluster *kafkav1.KafkaCluster, desired map[string]bool, logger logr.Logger) error {
+	existsSvcs := &corev1.ServiceList{}
+	listOps := &client.ListOptions{
+		Namespace:     cluster.Namespace,
+		LabelSelector: labels.SelectorFromSet(externalServiceLabels(cluster)),
+	}
+	if err := r.Client.List(context.TODO(), existsSvcs, listOps); err != nil {
+		return err
+	}
+	for i := range existsSvcs.Items {
+		if desired[existsSvcs.Items[i].Name] {
+			continue
+		}
+		logger.Info(fmt.Sprintf("Deleting stale external service %s", existsSvcs.Items[i].Name))
+		if err := r.Client.Delete(context.TODO(), &existsSvcs.Items[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// getLoadBalancerAddress returns the ip or the hostname assigned to the load balancer, empty if not assigned yet
+func getLoadBalancerAddress(svc *corev1.Service) string {
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			return ingress.IP
+		}
+		if ingress.Hostname != "" {
+			return ingress.Hostname
+		}
+	}
+	return ""
+}
+
+// getExternalServiceAddress returns the address advertised for the external service, false if not assigned yet
+func getExternalServiceAddress(cluster *kafkav1.KafkaCluster, svc *corev1.Service, ordinal int) (kafkav1.ExternalAddressStatus, bool) {
+	addr := kafkav1.ExternalAddressStatus{Ordinal: int32(ordinal)}
+	switch getExternalListenerType(cluster) {
+	case kafkav1.ExternalListenerTypeLoadBalancer:
+		addr.Host = getLoadBalancerAddress(svc)
+		addr.Port = DefaultExternalPort
+		return addr, addr.Host != ""
+	case kafkav1.ExternalListenerTypeIngress, kafkav1.ExternalListenerTypeGateway:
+		addr.Host = getExternalHost(cluster, ordinal)
+		addr.Port = getExternalRoutePort(cluster)
+		return addr, true
+	default:
+		addr.Port = svc.Spec.Ports[0].NodePort
+		return addr, addr.Port != 0
+	}
+}
+
+// deleteStaleExternalResources deletes the external services and routes which are not desired any more
+func (r *KafkaClusterReconciler) deleteStaleExternalResources(cluster *kafkav1.KafkaCluster, desired map[string]bool, logger logr.Logger) error {
+	if err := r.deleteStaleExternalServices(cluster, desired, logger); err != nil {
+		return err
+	}
+	if !isExternalRouted(cluster) {
+		desired = map[string]bool{}
+	}
+	return r.deleteStaleExternalRoutes(cluster, desired, logger)
+}
+
+// reconcileExternalServices exposes every broker through its own service along with a bootstrap service,
+// and records the addresses the brokers have to advertise on the external listener.
+// the addresses are only recorded once all of them are assigned, so the brokers are rolled only once
+func (r *KafkaClusterReconciler) reconcileExternalServices(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	desired := make(map[string]bool)
+	if !IsExternalListenerEnabled(cluster) {
+		cluster.Status.ExternalAddresses = nil
+		cluster.Status.ExternalClientEndpoint = ""
+		cluster.Status.SetExternalAddressPendingConditionFalse()
+		return r.deleteStaleExternalResources(cluster, desired, logger)
+	}
+
+	addresses := make([]kafkav1.ExternalAddressStatus, 0)
+	pending := make([]string, 0)
+	for i := 0; i < int(getWorkloadReplicas(cluster)); i++ {
+		selector := ClusterResourceLabels(cluster)
+		selector[DefaultPodNameLabel] = fmt.Sprintf("%s-%d", ClusterResourceName(cluster), i)
+		desiredSvc, err := r.constructExternalService(cluster, GetBrokerExternalSvcName(cluster, i), selector)
+		if err != nil {
+			return err
+		}
+		svc, err := r.applyExternalService(desiredSvc, logger)
+		if err != nil {
+			return err
+		}
+		desired[svc.Name] = true
+		if isExternalRouted(cluster) {
+			if err = r.applyExternalRoute(cluster, svc.Name, getExternalHost(cluster, i), logger); err != nil {
+				return err
+			}
+		}
+		addr, ok := getExternalServiceAddress(cluster, svc, i)
+		if !ok {
+			pending = append(pending, svc.Name)
+			continue
+		}
+		addresses = append(addresses, addr)
+	}
+
+	desiredSvc, err := r.constructExternalService(cluster, GetExternalBootstrapSvcName(cluster), ClusterResourceLabels(cluster))
+	if err != nil {
+		return err
+	}
+	bootstrapSvc, err := r.applyExternalService(desiredSvc, logger)
+	if err != nil {
+		return err
+	}
+	desired[bootstrapSvc.Name] = true
+
+	switch getExternalListenerType(cluster) {
+	case kafkav1.ExternalListenerTypeIngress, kafkav1.ExternalListenerTypeGateway:
+		if err = r.applyExternalRoute(cluster, bootstrapSvc.Name, getExternalBootstrapHost(cluster), logger); err != nil {
+			return err
+		}
+		cluster.Status.ExternalClientEndpoint = fmt.Sprintf("%s:%d", getExternalBootstrapHost(cluster), getExternalRoutePort(cluster))
+	case kafkav1.ExternalListenerTypeLoadBalancer:
+		if host := getLoadBalancerAddress(bootstrapSvc); host != "" {
+			cluster.Status.ExternalClientEndpoint = fmt.Sprintf("%s:%d", host, DefaultExternalPort)
+		} else {
+			pending = append(pending, bootstrapSvc.Name)
+		}
+	default:
+		pods, err := r.listClusterPods(cluster)
+		if err != nil {
+			return err
+		}
+		for _, pod := range pods {
+			if pod.Status.HostIP != "" {
+				cluster.Status.ExternalClientEndpoint = fmt.Sprintf("%s:%d", pod.Status.HostIP, bootstrapSvc.Spec.Ports[0].NodePort)
+				break
+			}
+		}
+	}
+
+	if len(pending) > 0 {
+		logger.Info(fmt.Sprintf("Waiting for the addresses of the external services %s", strings.Join(pending, ",")))
+		cluster.Status.SetExternalAddressPendingConditionTrue(kafkav1.LoadBalancerPendingReason,
+			fmt.Sprintf("waiting for the addresses of %s", strings.Join(pending, ",")))
+	} else {
+		cluster.Status.SetExternalAddressPendingConditionFalse()
+	}
+	if len(addresses) == int(getWorkloadReplicas(cluster)) {
+		cluster.Status.ExternalAddresses = addresses
+	}
+	return r.deleteStaleExternalResources(cluster, desired, logger)
+}
@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func tlsRouteGVK(kind string) schema.GroupVersionKind {
+	return schema.GroupVersionKind{
+		Group:   GatewayAPIGroup,
+		Version: TLSRouteVersion,
+		Kind:    kind,
+	}
+}
+
+// constructExternalIngress routes the tls connections of the host to the service without terminating them
+func (r *KafkaClusterReconciler) constructExternalIngress(cluster *kafkav1.KafkaCluster, svcName string, host string) (*networkingv1.Ingress, error) {
+	annotations := copyAnnotations(cluster.Spec.Listeners.External.Annotations)
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[DefaultSSLPassthroughAnnotation] = "true"
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        svcName,
+			Namespace:   cluster.Namespace,
+			Labels:      externalServiceLabels(cluster),
+			Annotations: annotations,
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: cluster.Spec.Listeners.External.IngressClassName,
+			TLS: []networkingv1.IngressTLS{
+				{
+					Hosts: []string{host},
+				},
+			},
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     "/",
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: svcName,
+											Port: networkingv1.ServiceBackendPort{
+												Number: DefaultExternalPort,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if err := ctrl.SetControllerReference(cluster, ingress, r.Scheme); err != nil {
+		return ingress, err
+	}
+	return ingress, nil
+}
+
+// constructExternalTLSRoute routes the tls connections of the host to the service through the gateway
+func (r *KafkaClusterReconciler) constructExternalTLSRoute(cluster *kafkav1.KafkaCluster, svcName string, host string) (*unstructured.Unstructured, error) {
+	gatewayRef := cluster.Spec.Listeners.External.GatewayRef
+	parentRef := map[string]interface{}{
+		"group": GatewayAPIGroup,
+		"kind":  "Gateway",
+		"name":  gatewayRef.Name,
+	}
+	if gatewayRef.Namespace != "" {
+		parentRef["namespace"] = gatewayRef.Namespace
+	}
+	if gatewayRef.SectionName != "" {
+		parentRef["sectionName"] = gatewayRef.SectionName
+	}
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(tlsRouteGVK(TLSRouteKind))
+	route.SetName(svcName)
+	route.SetNamespace(cluster.Namespace)
+	route.SetLabels(externalServiceLabels(cluster))
+	route.SetAnnotations(copyAnnotations(cluster.Spec.Listeners.External.Annotations))
+	route.Object["spec"] = map[string]interface{}{
+		"parentRefs": []interface{}{parentRef},
+		"hostnames":  []interface{}{host},
+		"rules": []interface{}{
+			map[string]interface{}{
+				// the defaults of the gateway api are set, so the route read back equals the desired one
+				"backendRefs": []interface{}{
+					map[string]interface{}{
+						"group":  "",
+						"kind":   "Service",
+						"name":   svcName,
+						"port":   int64(DefaultExternalPort),
+						"weight": int64(1),
+					},
+				},
+			},
+		},
+	}
+	if err := ctrl.SetControllerReference(cluster, route, r.Scheme); err != nil {
+		return route, err
+	}
+	return route, nil
+}
+
+func (r *KafkaClusterReconciler) applyExternalIngress(desiredIngress *networkingv1.Ingress, logger logr.Logger) error {
+	existsIngress := &networkingv1.Ingress{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: desiredIngress.Name, Namespace: desiredIngress.Namespace}, existsIngress)
+	if err != nil && errors.IsNotFound(err) {
+		logger.Info(fmt.Sprintf("Creating a new external ingress %s", desiredIngress.Name))
+		return r.Client.Create(context.TODO(), desiredIngress)
+	} else if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existsIngress.Labels, desiredIngress.Labels) &&
+		equality.Semantic.DeepEqual(existsIngress.Annotations, desiredIngress.Annotations) &&
+		equality.Semantic.DeepEqual(existsIngress.Spec, desiredIngress.Spec) {
+		return nil
+	}
+	logger.Info(fmt.Sprintf("Updating the external ingress %s", desiredIngress.Name))
+	existsIngress.Labels = desiredIngress.Labels
+	existsIngress.Annotations = desiredIngress.Annotations
+	existsIngress.Spec = desiredIngress.Spec
+	return r.Client.Update(context.TODO(), existsIngress)
+}
+
+func (r *KafkaClusterReconciler) applyExternalTLSRoute(desiredRoute *unstructured.Unstructured, logger logr.Logger) error {
+	existsRoute := &unstructured.Unstructured{}
+	existsRoute.SetGroupVersionKind(desiredRoute.GroupVersionKind())
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: desiredRoute.GetName(), Namespace: desiredRoute.GetNamespace()}, existsRoute)
+	if err != nil && errors.IsNotFound(err) {
+		logger.Info(fmt.Sprintf("Creating a new external tls route %s", desiredRoute.GetName()))
+		return r.Client.Create(context.TODO(), desiredRoute)
+	} else if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existsRoute.GetLabels(), desiredRoute.GetLabels()) &&
+		equality.Semantic.DeepEqual(existsRoute.GetAnnotations(), desiredRoute.GetAnnotations()) &&
+		equality.Semantic.DeepEqual(existsRoute.Object["spec"], desiredRoute.Object["spec"]) {
+		return nil
+	}
+	logger.Info(fmt.Sprintf("Updating the external tls route %s", desiredRoute.GetName()))
+	existsRoute.SetLabels(desiredRoute.GetLabels())
+	existsRoute.SetAnnotations(desiredRoute.GetAnnotations())
+	existsRoute.Object["spec"] = desiredRoute.Object["spec"]
+	return r.Client.Update(context.TODO(), existsRoute)
+}
+
+// applyExternalRoute maps the host to the service by an ingress or a tls route
+func (r *KafkaClusterReconciler) applyExternalRoute(cluster *kafkav1.KafkaCluster, svcName string, host string, logger logr.Logger) error {
+	switch getExternalListenerType(cluster) {
+	case kafkav1.ExternalListenerTypeGateway:
+		route, err := r.constructExternalTLSRoute(cluster, svcName, host)
+		if err != nil {
+			return err
+		}
+		return r.applyExternalTLSRoute(route, logger)
+	default:
+		ingress, err := r.constructExternalIngress(cluster, svcName, host)
+		if err != nil {
+			return err
+		}
+		return r.applyExternalIngress(ingress, logger)
+	}
+}
+
+// deleteStaleExternalRoutes deletes the ingresses and the tls routes which are not desired any more.
+// the tls routes are skipped if the gateway api is not installed
+func (r *KafkaClusterReconciler) deleteStaleExternalRoutes(cluster *kafkav1.KafkaCluster, desired map[string]bool, logger logr.Logger) error {
+	listOps := &client.ListOptions{
+		Namespace:     cluster.Namespace,
+		LabelSelector: labels.SelectorFromSet(externalServiceLabels(cluster)),
+	}
+	isGateway := isExternalRouted(cluster) && getExternalListenerType(cluster) == kafkav1.ExternalListenerTypeGateway
+
+	existsIngresses := &networkingv1.IngressList{}
+	if err := r.Client.List(context.TODO(), existsIngresses, listOps); err != nil {
+		return err
+	}
+	for i := range existsIngresses.Items {
+		if desired[existsIngresses.Items[i].Name] && !isGateway {
+			continue
+		}
+		logger.Info(fmt.Sprintf("Deleting stale external ingress %s", existsIngresses.Items[i].Name))
+		if err := r.Client.Delete(context.TODO(), &existsIngresses.Items[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	existsRoutes := &unstructured.UnstructuredList{}
+	existsRoutes.SetGroupVersionKind(tlsRouteGVK(TLSRouteListKind))
+	if err := r.Client.List(context.TODO(), existsRoutes, listOps); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	for i := range existsRoutes.Items {
+		if desired[existsRoutes.Items[i].GetName()] && isGateway {
+			continue
+		}
+		logger.Info(fmt.Sprintf("Deleting stale external tls route %s", existsRoutes.Items[i].GetName()))
+		if err := r.Client.Delete(context.TODO(), &existsRoutes.Items[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+func TestApplyExternalServiceUnchanged(t *testing.T) {
+	g := NewWithT(t)
+	cluster := &kafkav1.KafkaCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec: kafkav1.KafkaClusterSpec{Listeners: &kafkav1.ListenersConfig{
+			External: &kafkav1.ExternalListenerConfig{Type: kafkav1.ExternalListenerTypeNodePort},
+		}},
+	}
+	r := newFakeReconciler()
+	desired, err := r.constructExternalService(cluster, "kafka-external", ClusterResourceLabels(cluster))
+	g.Expect(err).NotTo(HaveOccurred())
+	created, err := r.applyExternalService(desired, logr.Discard())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	desired, _ = r.constructExternalService(cluster, "kafka-external", ClusterResourceLabels(cluster))
+	applied, err := r.applyExternalService(desired, logr.Discard())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applied.ResourceVersion).To(Equal(created.ResourceVersion))
+
+	cluster.Spec.Listeners.External.Annotations = map[string]string{"team": "kafka"}
+	desired, _ = r.constructExternalService(cluster, "kafka-external", ClusterResourceLabels(cluster))
+	applied, err = r.applyExternalService(desired, logr.Discard())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applied.ResourceVersion).NotTo(Equal(created.ResourceVersion))
+}
+
+func TestRoutedExternalListenerTLS(t *testing.T) {
+	g := NewWithT(t)
+	cluster := &kafkav1.KafkaCluster{Spec: kafkav1.KafkaClusterSpec{Listeners: &kafkav1.ListenersConfig{
+		External: &kafkav1.ExternalListenerConfig{Type: kafkav1.ExternalListenerTypeIngress, HostTemplate: "kafka-{ordinal}.example.com"},
+	}}}
+	// the keystores are issued for the SNI routing without the tls in the spec
+	g.Expect(IsTLSEnabled(cluster)).To(BeTrue())
+	g.Expect(getExternalSecurityProtocol(cluster)).To(Equal("SSL"))
+
+	cluster.Spec.Listeners.External.Type = kafkav1.ExternalListenerTypeNodePort
+	g.Expect(IsTLSEnabled(cluster)).To(BeFalse())
+}
+
+func TestApplyExternalRouteUnchanged(t *testing.T) {
+	g := NewWithT(t)
+	cluster := &kafkav1.KafkaCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec: kafkav1.KafkaClusterSpec{Listeners: &kafkav1.ListenersConfig{
+			External: &kafkav1.ExternalListenerConfig{
+				Type:       kafkav1.ExternalListenerTypeGateway,
+				GatewayRef: &kafkav1.GatewayReference{Name: "gateway"},
+			},
+		}},
+	}
+	r := newFakeReconciler()
+	for _, listenerType := range []kafkav1.ExternalListenerType{kafkav1.ExternalListenerTypeGateway, kafkav1.ExternalListenerTypeIngress} {
+		cluster.Spec.Listeners.External.Type = listenerType
+		cluster.Spec.Listeners.External.Annotations = nil
+		obj := &unstructured.Unstructured{}
+		if listenerType == kafkav1.ExternalListenerTypeGateway {
+			obj.SetGroupVersionKind(tlsRouteGVK(TLSRouteKind))
+		} else {
+			obj.SetGroupVersionKind(networkingv1.SchemeGroupVersion.WithKind("Ingress"))
+		}
+		key := types.NamespacedName{Name: "kafka-external-0", Namespace: cluster.Namespace}
+		g.Expect(r.applyExternalRoute(cluster, key.Name, "kafka-0.example.com", logr.Discard())).To(Succeed())
+		g.Expect(r.Client.Get(context.TODO(), key, obj)).To(Succeed())
+		created := obj.GetResourceVersion()
+
+		g.Expect(r.applyExternalRoute(cluster, key.Name, "kafka-0.example.com", logr.Discard())).To(Succeed())
+		g.Expect(r.Client.Get(context.TODO(), key, obj)).To(Succeed())
+		g.Expect(obj.GetResourceVersion()).To(Equal(created), string(listenerType))
+
+		cluster.Spec.Listeners.External.Annotations = map[string]string{"team": "kafka"}
+		g.Expect(r.applyExternalRoute(cluster, key.Name, "kafka-0.example.com", logr.Discard())).To(Succeed())
+		g.Expect(r.Client.Get(context.TODO(), key, obj)).To(Succeed())
+		g.Expect(obj.GetResourceVersion()).NotTo(Equal(created), string(listenerType))
+	}
+}
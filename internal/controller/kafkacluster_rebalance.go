@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+func IsRebalanceOnScaleUpEnabled(cluster *kafkav1.KafkaCluster) bool {
+	return cluster.Spec.Rebalance != nil && cluster.Spec.Rebalance.OnScaleUp
+}
+
+func getRebalanceLoadType(cluster *kafkav1.KafkaCluster) kafkav1.RebalanceLoadType {
+	if cluster.Spec.Rebalance != nil && cluster.Spec.Rebalance.BalanceBy != "" {
+		return cluster.Spec.Rebalance.BalanceBy
+	}
+	return kafkav1.RebalanceLoadPartitions
+}
+
+// describePartitionSizes returns the bytes of the largest replica of the partitions on the brokers
+func describePartitionSizes(admin sarama.ClusterAdmin, brokers []*sarama.Broker) (map[replicaRef]int64, error) {
+	ids := make([]int32, 0, len(brokers))
+	for _, b := range brokers {
+		ids = append(ids, b.ID())
+	}
+	logDirs, err := admin.DescribeLogDirs(ids)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[replicaRef]int64)
+	for _, dirs := range logDirs {
+		for _, dir := range dirs {
+			for _, t := range dir.Topics {
+				for _, p := range t.Partitions {
+					ref := replicaRef{topic: t.Topic, partition: p.PartitionID}
+					if p.Size > sizes[ref] {
+						sizes[ref] = p.Size
+					}
+				}
+			}
+		}
+	}
+	return sizes, nil
+}
+
+// getReplicaWeight returns the weight of the replicas of the partitions, one for each replica when balancing
+// the partitions, or the bytes of the largest replica plus one when balancing the sizes so the empty ones are spread too
+func getReplicaWeight(loadType kafkav1.RebalanceLoadType, sizes map[replicaRef]int64) func(string, int32) int64 {
+	if loadType != kafkav1.RebalanceLoadSize {
+		return func(string, int32) int64 { return 1 }
+	}
+	return func(topic string, partition int32) int64 {
+		return sizes[replicaRef{topic: topic, partition: partition}] + 1
+	}
+}
+
+func getRebalancePlanStatus(before map[int32]int64, after map[int32]int64) []kafkav1.BrokerLoadStatus {
+	plan := make([]kafkav1.BrokerLoadStatus, 0, len(before))
+	for id := range before {
+		plan = append(plan, kafkav1.BrokerLoadStatus{Broker: id, Before: before[id], After: after[id]})
+	}
+	sort.Slice(plan, func(i, j int) bool { return plan[i].Broker < plan[j].Broker })
+	return plan
+}
+
+// reconcileRebalance moves the replicas onto the brokers added by a scale up once they are ready, so all the brokers
+// host about the same load. The scale up is detected by the replicas growing beyond the ones of the last rebalance,
+// or the ones in the status if the cluster has never been rebalanced
+func (r *KafkaClusterReconciler) reconcileRebalance(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	rebalance := cluster.Status.Rebalance
+	if !IsRebalanceOnScaleUpEnabled(cluster) && (rebalance == nil || !rebalance.IsReassigning()) {
+		return nil
+	}
+	replicas := getWorkloadReplicas(cluster)
+	if rebalance == nil {
+		if cluster.Status.Replicas == 0 || replicas <= cluster.Status.Replicas {
+			return nil
+		}
+		logger.Info(fmt.Sprintf("Waiting for the brokers added to rebalance the replicas across %d brokers", replicas))
+		rebalance = &kafkav1.ClusterRebalanceStatus{Replicas: cluster.Status.Replicas}
+		cluster.Status.Rebalance = rebalance
+	}
+	// the rebalance is best effort, the cluster is reconciled regardless of it
+	if err := r.rebalanceBrokers(cluster, replicas, logger); err != nil {
+		logger.Error(err, "Error occurred during rebalancing the brokers")
+	}
+	return nil
+}
+
+func (r *KafkaClusterReconciler) rebalanceBrokers(cluster *kafkav1.KafkaCluster, replicas int32, logger logr.Logger) error {
+	rebalance := cluster.Status.Rebalance
+	if !rebalance.IsReassigning() && replicas <= rebalance.Replicas {
+		// the brokers are scaled down, or back down before the brokers added are ready
+		rebalance.Replicas = replicas
+		if rebalance.CompletionTime == "" {
+			rebalance.CompletionTime = time.Now().Format(time.RFC3339)
+		}
+		return nil
+	}
+	if !rebalance.IsReassigning() && (!cluster.Status.IsClusterInReadyState() || cluster.Status.IsClusterInUpgradingState() ||
+		cluster.Status.IsDrainInProgress() || cluster.Status.ReadyReplicas < replicas) {
+		return nil
+	}
+
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	engine := newReassignmentEngine(r.Client, admin, cluster, nil)
+	if rebalance.IsReassigning() {
+		done, err := engine.reconcile(&rebalance.ReassignmentStatus, logger)
+		if err != nil || !done {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Rebalanced the replicas across %d brokers", rebalance.Replicas))
+		rebalance.CompletionTime = time.Now().Format(time.RFC3339)
+		return nil
+	}
+
+	brokers, _, err := admin.DescribeCluster()
+	if err != nil {
+		return err
+	}
+	if len(brokers) < int(replicas) {
+		logger.Info(fmt.Sprintf("Waiting for %d brokers to join the cluster before rebalancing", int(replicas)-len(brokers)))
+		return nil
+	}
+	topics, err := describeAllTopics(admin)
+	if err != nil {
+		return err
+	}
+	loadType := getRebalanceLoadType(cluster)
+	sizes := make(map[replicaRef]int64)
+	if loadType == kafkav1.RebalanceLoadSize {
+		if sizes, err = describePartitionSizes(admin, brokers); err != nil {
+			return err
+		}
+	}
+	plans, before, after := planRebalance(topics, brokers, getReplicaWeight(loadType, sizes))
+	now := time.Now().Format(time.RFC3339)
+	rebalance = &kafkav1.ClusterRebalanceStatus{
+		Replicas:  replicas,
+		BalanceBy: string(loadType),
+		Plan:      getRebalancePlanStatus(before, after),
+		StartTime: now,
+	}
+	if len(plans) == 0 {
+		logger.Info(fmt.Sprintf("The replicas are balanced across %d brokers", replicas))
+		rebalance.CompletionTime = now
+		cluster.Status.Rebalance = rebalance
+		return nil
+	}
+	startReassignment(&rebalance.ReassignmentStatus, getPartitionMovements(topics, plans), getReassignmentThrottleRate(cluster))
+	cluster.Status.Rebalance = rebalance
+	logger.Info(fmt.Sprintf("Moving the replicas of %d partitions to rebalance across %d brokers", rebalance.Partitions, replicas))
+	_, err = engine.reconcile(&rebalance.ReassignmentStatus, logger)
+	return err
+}
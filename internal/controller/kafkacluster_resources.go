@@ -33,6 +33,14 @@ func getReplicas(cluster *kafkav1.KafkaCluster) int32 {
 	return DefaultReplicas
 }
 
+// getWorkloadReplicas returns the num of the brokers to run, the brokers being drained are kept until the drain completes
+func getWorkloadReplicas(cluster *kafkav1.KafkaCluster) int32 {
+	if cluster.Status.Drain != nil && cluster.Status.Drain.Replicas > getReplicas(cluster) {
+		return cluster.Status.Drain.Replicas
+	}
+	return getReplicas(cluster)
+}
+
 func getDiskNum(cluster *kafkav1.KafkaCluster) int32 {
 	if cluster.Spec.Resource.Disks != 0 {
 		return cluster.Spec.Resource.Disks
@@ -51,6 +59,10 @@ func getClusterConfigValue(cluster *kafkav1.KafkaCluster, key string, value stri
 }
 
 func constructClusterConfig(cluster *kafkav1.KafkaCluster) string {
+	return map2String(constructClusterConfKeyValue(cluster))
+}
+
+func constructClusterConfKeyValue(cluster *kafkav1.KafkaCluster) map[string]string {
 	clusterConf := make(map[string]string)
 	for k, v := range DefaultClusterConfKeyValue {
 		clusterConf[k] = getClusterConfigValue(cluster, k, v)
@@ -68,19 +80,155 @@ func constructClusterConfig(cluster *kafkav1.KafkaCluster) string {
 		volumeNames = append(volumeNames, fmt.Sprintf("%s/%s", DefaultDataPath, fmt.Sprintf("%s%d", DefaultDiskPathPrefix, i)))
 	}
 	clusterConf["log.dirs"] = strings.Join(volumeNames, ",")
-	if _, ok := clusterConf["listeners"]; !ok {
-		clusterConf["listeners"] = fmt.Sprintf("%s://0.0.0.0:%d,%s://0.0.0.0:%d",
+	if _, ok := cluster.Spec.Conf["listeners"]; !ok {
+		if !IsKraftMode(cluster) {
+			// listeners of the kraft mode depend on the process roles, which are rendered by the start script
+			clusterConf["listeners"] = getListeners(cluster, false)
+		}
+		clusterConf["inter.broker.listener.name"] = getInterBrokerListenerName(cluster)
+		clusterConf["listener.security.protocol.map"] = fmt.Sprintf("%s:%s,%s:%s",
 			DefaultInternalPortName,
-			DefaultInternalPort,
+			getInternalSecurityProtocol(cluster),
 			DefaultExternalPortName,
-			DefaultExternalPort)
-		clusterConf["inter.broker.listener.name"] = DefaultInternalPortName
-		clusterConf["listener.security.protocol.map"] = fmt.Sprintf("%s:PLAINTEXT,%s:PLAINTEXT",
-			DefaultInternalPortName,
-			DefaultExternalPortName)
+			getExternalSecurityProtocol(cluster))
+		if HasReplicationListener(cluster) {
+			clusterConf["listener.security.protocol.map"] += fmt.Sprintf(",%s:%s", DefaultReplicationPortName, getReplicationSecurityProtocol(cluster))
+		}
+		if IsKraftMode(cluster) {
+			clusterConf["listener.security.protocol.map"] += fmt.Sprintf(",%s:%s", DefaultControllerPortName, getControllerSecurityProtocol(cluster))
+		}
+	}
+	if IsKraftMode(cluster) {
+		for k, v := range DefaultKraftConfKeyValue {
+			clusterConf[k] = getClusterConfigValue(cluster, k, v)
+		}
+		clusterConf["controller.quorum.voters"] = GetControllerQuorumVoters(cluster)
+	} else {
+		for k, v := range DefaultZookeeperConfKeyValue {
+			clusterConf[k] = getClusterConfigValue(cluster, k, v)
+		}
+		if cluster.Status.ZookeeperConnect != "" {
+			clusterConf["zookeeper.connect"] = cluster.Status.ZookeeperConnect
+		}
+	}
+	if IsAuthorizationEnabled(cluster) {
+		if _, ok := cluster.Spec.Conf["authorizer.class.name"]; !ok {
+			clusterConf["authorizer.class.name"] = getAuthorizerClassName(cluster)
+		}
+		if _, ok := cluster.Spec.Conf["super.users"]; !ok {
+			clusterConf["super.users"] = getSuperUsers(cluster)
+		}
+	}
+	if IsCruiseControlEnabled(cluster) {
+		for k, v := range constructMetricsReporterConfKeyValue(cluster) {
+			clusterConf[k] = v
+		}
+	}
+
+	return clusterConf
+}
+
+func getListeners(cluster *kafkav1.KafkaCluster, withController bool) string {
+	listeners := fmt.Sprintf("%s://0.0.0.0:%d,%s://0.0.0.0:%d",
+		DefaultInternalPortName,
+		DefaultInternalPort,
+		DefaultExternalPortName,
+		DefaultExternalPort)
+	if HasReplicationListener(cluster) {
+		listeners += fmt.Sprintf(",%s://0.0.0.0:%d", DefaultReplicationPortName, DefaultReplicationPort)
+	}
+	if withController {
+		listeners += fmt.Sprintf(",%s://0.0.0.0:%d", DefaultControllerPortName, DefaultControllerPort)
+	}
+	return listeners
+}
+
+func getAdvertisedListeners(cluster *kafkav1.KafkaCluster, internalHost string, externalHost string, externalPort string) string {
+	listeners := fmt.Sprintf("%s://%s:%d,%s://%s:%s",
+		DefaultInternalPortName,
+		internalHost,
+		DefaultInternalPort,
+		DefaultExternalPortName,
+		externalHost,
+		externalPort)
+	if HasReplicationListener(cluster) {
+		listeners += fmt.Sprintf(",%s://%s:%d", DefaultReplicationPortName, internalHost, DefaultReplicationPort)
+	}
+	return listeners
+}
+
+// constructExternalAddressScript renders the host and the port advertised by each broker on the external listener
+func constructExternalAddressScript(cluster *kafkav1.KafkaCluster) string {
+	var sb strings.Builder
+	sb.WriteString("EXTERNAL_HOST=${POD_HOST}\n")
+	sb.WriteString(fmt.Sprintf("EXTERNAL_PORT=%d\n", DefaultExternalPort))
+	if len(cluster.Status.ExternalAddresses) == 0 {
+		return sb.String()
+	}
+	sb.WriteString("case ${ORDINAL} in\n")
+	for _, addr := range cluster.Status.ExternalAddresses {
+		host := addr.Host
+		if host == "" {
+			// the node port is opened on the node running the pod
+			host = "${HOST_IP}"
+		}
+		sb.WriteString(fmt.Sprintf("  %d) EXTERNAL_HOST=%s; EXTERNAL_PORT=%d ;;\n", addr.Ordinal, host, addr.Port))
 	}
+	sb.WriteString("esac\n")
+	return sb.String()
+}
 
-	return map2String(clusterConf)
+// constructStartScript renders the script which completes the per pod configs
+// from the ordinal of the pod before starting the broker
+func constructStartScript(cluster *kafkav1.KafkaCluster) string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/bash\n")
+	sb.WriteString("set -e\n")
+	sb.WriteString("ORDINAL=${POD_NAME##*-}\n")
+	sb.WriteString(fmt.Sprintf("BROKER_ID=$((%d + ORDINAL))\n", getBrokerIDBase(cluster)))
+	sb.WriteString(fmt.Sprintf("POD_HOST=${POD_NAME}.%s.${NAMESPACE}.svc.%s\n",
+		ClusterResourceName(cluster, DefaultHeadlessSvcNameSuffix),
+		GetClusterDomain(cluster)))
+	sb.WriteString(fmt.Sprintf("CONF=%s/%s\n", DefaultRuntimePath, DefaultKafkaConfigFileName))
+	sb.WriteString(fmt.Sprintf("cp %s/%s ${CONF}\n", DefaultConfPath, DefaultKafkaConfigFileName))
+	if IsKraftMode(cluster) {
+		_, customListeners := cluster.Spec.Conf["listeners"]
+		sb.WriteString("echo \"node.id=${BROKER_ID}\" >> ${CONF}\n")
+		sb.WriteString(fmt.Sprintf("if [ ${ORDINAL} -lt %d ]; then\n", GetKraftControllers(cluster)))
+		sb.WriteString("  echo \"process.roles=broker,controller\" >> ${CONF}\n")
+		if !customListeners {
+			sb.WriteString(fmt.Sprintf("  echo \"listeners=%s\" >> ${CONF}\n", getListeners(cluster, true)))
+		}
+		sb.WriteString("else\n")
+		sb.WriteString("  echo \"process.roles=broker\" >> ${CONF}\n")
+		if !customListeners {
+			sb.WriteString(fmt.Sprintf("  echo \"listeners=%s\" >> ${CONF}\n", getListeners(cluster, false)))
+		}
+		sb.WriteString("fi\n")
+	} else {
+		// brokers created with an auto generated id keep it, since it is stored along with the data
+		sb.WriteString(fmt.Sprintf("META=%s/%s0/meta.properties\n", DefaultDataPath, DefaultDiskPathPrefix))
+		sb.WriteString("if [ -f ${META} ] && grep -q \"^broker.id=\" ${META}; then\n")
+		sb.WriteString("  BROKER_ID=$(grep \"^broker.id=\" ${META} | cut -d= -f2)\n")
+		sb.WriteString("fi\n")
+		sb.WriteString("echo \"broker.id=${BROKER_ID}\" >> ${CONF}\n")
+	}
+	sb.WriteString(constructTLSScript(cluster))
+	sb.WriteString(constructMetricsReporterScript(cluster))
+	sb.WriteString(constructAuthenticationScript(cluster))
+	if _, ok := cluster.Spec.Conf["advertised.listeners"]; !ok {
+		sb.WriteString(constructExternalAddressScript(cluster))
+		sb.WriteString(fmt.Sprintf("echo \"advertised.listeners=%s\" >> ${CONF}\n",
+			getAdvertisedListeners(cluster, "${POD_HOST}", "${EXTERNAL_HOST}", "${EXTERNAL_PORT}")))
+	}
+	if IsKraftMode(cluster) {
+		// formatting is skipped if the storage has been formatted before
+		sb.WriteString(fmt.Sprintf("%s/bin/kafka-storage.sh format -t ${%s} -c ${CONF} --ignore-formatted\n",
+			DefaultKafkaHome,
+			DefaultKraftClusterIDEnvName))
+	}
+	sb.WriteString(fmt.Sprintf("exec %s/bin/kafka-server-start.sh ${CONF}\n", DefaultKafkaHome))
+	return sb.String()
 }
 
 func constructLogConfig() string {
@@ -124,8 +272,22 @@ func (r *KafkaClusterReconciler) constructHeadlessService(cluster *kafkav1.Kafka
 			},
 			Selector:  ClusterResourceLabels(cluster),
 			ClusterIP: corev1.ClusterIPNone,
+			// the members of the controller quorum must be resolvable before they are ready
+			PublishNotReadyAddresses: true,
 		},
 	}
+	if HasReplicationListener(cluster) {
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name: DefaultReplicationPortName,
+			Port: DefaultReplicationPort,
+		})
+	}
+	if IsKraftMode(cluster) {
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name: DefaultControllerPortName,
+			Port: DefaultControllerPort,
+		})
+	}
 	if err := ctrl.SetControllerReference(cluster, svc, r.Scheme); err != nil {
 		return svc, err
 	}
@@ -160,6 +322,15 @@ func (r *KafkaClusterReconciler) constructService(cluster *kafkav1.KafkaCluster)
 	return svc, nil
 }
 
+func constructConfigData(cluster *kafkav1.KafkaCluster) map[string]string {
+	data := map[string]string{
+		DefaultKafkaConfigFileName: constructClusterConfig(cluster),
+		DefaultLogConfigFileName:   constructLogConfig(),
+		DefaultStartScriptFileName: constructStartScript(cluster),
+	}
+	return data
+}
+
 func (r *KafkaClusterReconciler) constructConfigMap(cluster *kafkav1.KafkaCluster) (*corev1.ConfigMap, error) {
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -167,10 +338,7 @@ func (r *KafkaClusterReconciler) constructConfigMap(cluster *kafkav1.KafkaCluste
 			Namespace: cluster.Namespace,
 			Labels:    ClusterResourceLabels(cluster),
 		},
-		Data: map[string]string{
-			DefaultKafkaConfigFileName: constructClusterConfig(cluster),
-			DefaultLogConfigFileName:   constructLogConfig(),
-		},
+		Data: constructConfigData(cluster),
 	}
 	if err := ctrl.SetControllerReference(cluster, cm, r.Scheme); err != nil {
 		return cm, err
@@ -187,8 +355,8 @@ func (r *KafkaClusterReconciler) getStorageRequests(cluster *kafkav1.KafkaCluste
 	return capacityPerVolume(DefaultKafkaVolumeSize)
 }
 
-func (r *KafkaClusterReconciler) defaultKafkaPorts() []corev1.ContainerPort {
-	return []corev1.ContainerPort{
+func (r *KafkaClusterReconciler) defaultKafkaPorts(cluster *kafkav1.KafkaCluster) []corev1.ContainerPort {
+	ports := []corev1.ContainerPort{
 		{
 			Name:          DefaultInternalPortName,
 			ContainerPort: DefaultInternalPort,
@@ -198,6 +366,41 @@ func (r *KafkaClusterReconciler) defaultKafkaPorts() []corev1.ContainerPort {
 			ContainerPort: DefaultExternalPort,
 		},
 	}
+	if HasReplicationListener(cluster) {
+		ports = append(ports, corev1.ContainerPort{
+			Name:          DefaultReplicationPortName,
+			ContainerPort: DefaultReplicationPort,
+		})
+	}
+	if IsKraftMode(cluster) {
+		ports = append(ports, corev1.ContainerPort{
+			Name:          DefaultControllerPortName,
+			ContainerPort: DefaultControllerPort,
+		})
+	}
+	return ports
+}
+
+func (r *KafkaClusterReconciler) constructEnvs(cluster *kafkav1.KafkaCluster) []corev1.EnvVar {
+	envs := DefaultEnvs()
+	if IsKraftMode(cluster) {
+		envs = append(envs, corev1.EnvVar{
+			Name:  DefaultKraftClusterIDEnvName,
+			Value: cluster.Status.ClusterID,
+		})
+	}
+	if IsCruiseControlEnabled(cluster) {
+		// kafka-run-class.sh appends the libs of the kafka to the classpath
+		envs = append(envs, corev1.EnvVar{
+			Name:  "CLASSPATH",
+			Value: DefaultMetricsReporterPath + "/*",
+		})
+	}
+	return envs
+}
+
+func (r *KafkaClusterReconciler) constructCommand(cluster *kafkav1.KafkaCluster) []string {
+	return []string{"/bin/bash", fmt.Sprintf("%s/%s", DefaultConfPath, DefaultStartScriptFileName)}
 }
 
 func (r *KafkaClusterReconciler) constructVolumeMounts(cluster *kafkav1.KafkaCluster) []corev1.VolumeMount {
@@ -218,6 +421,38 @@ func (r *KafkaClusterReconciler) constructVolumeMounts(cluster *kafkav1.KafkaClu
 			MountPath: DefaultLogPath,
 		},
 	}
+	volumeMounts = append(volumeMounts,
+		corev1.VolumeMount{
+			Name:      ClusterResourceName(cluster, DefaultConfigNameSuffix),
+			MountPath: fmt.Sprintf("%s/%s", DefaultConfPath, DefaultStartScriptFileName),
+			SubPath:   DefaultStartScriptFileName,
+		},
+		corev1.VolumeMount{
+			Name:      DefaultRuntimeVolumeName,
+			MountPath: DefaultRuntimePath,
+		},
+	)
+	if IsTLSEnabled(cluster) {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      DefaultTLSVolumeName,
+			MountPath: DefaultTLSPath,
+			ReadOnly:  true,
+		})
+	}
+	if IsTLSAuthenticationEnabled(cluster) {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      DefaultClientsCAVolumeName,
+			MountPath: DefaultClientsCAPath,
+			ReadOnly:  true,
+		})
+	}
+	if IsCruiseControlEnabled(cluster) {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      DefaultMetricsReporterVolumeName,
+			MountPath: DefaultMetricsReporterPath,
+			ReadOnly:  true,
+		})
+	}
 	for i := 0; i < num; i++ {
 		volumeName := fmt.Sprintf("%s%d", DefaultDiskPathPrefix, i)
 		volumeMounts = append(volumeMounts, corev1.VolumeMount{
@@ -253,6 +488,45 @@ func (r *KafkaClusterReconciler) constructVolumes(cluster *kafkav1.KafkaCluster)
 		},
 	}
 
+	volumes[0].ConfigMap.Items = append(volumes[0].ConfigMap.Items, corev1.KeyToPath{
+		Key:  DefaultStartScriptFileName,
+		Path: DefaultStartScriptFileName,
+	})
+	volumes = append(volumes, corev1.Volume{
+		Name: DefaultRuntimeVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	})
+	if IsTLSEnabled(cluster) {
+		volumes = append(volumes, corev1.Volume{
+			Name: DefaultTLSVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: GetBrokersTLSSecretName(cluster),
+				},
+			},
+		})
+	}
+	if IsTLSAuthenticationEnabled(cluster) {
+		volumes = append(volumes, corev1.Volume{
+			Name: DefaultClientsCAVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: GetClientsCACertSecretName(cluster),
+				},
+			},
+		})
+	}
+	if IsCruiseControlEnabled(cluster) {
+		volumes = append(volumes, corev1.Volume{
+			Name: DefaultMetricsReporterVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+	}
+
 	volumes = append(volumes, corev1.Volume{
 		Name: DefaultLogVolumeName,
 		VolumeSource: corev1.VolumeSource{
@@ -366,14 +640,20 @@ func (r *KafkaClusterReconciler) constructKafkaPodSpec(cluster *kafkav1.KafkaClu
 		tmpPullSecrets = make([]corev1.LocalObjectReference, 0)
 		tmpPullSecrets = append(tmpPullSecrets, corev1.LocalObjectReference{Name: ic.PullSecrets})
 	}
+	var initContainers []corev1.Container
+	if IsCruiseControlEnabled(cluster) {
+		initContainers = append(initContainers, constructMetricsReporterInitContainer(cluster))
+	}
 	return corev1.PodSpec{
+		InitContainers: initContainers,
 		Containers: []corev1.Container{
 			{
 				Name:            cluster.Name,
 				Image:           ic.Repository + ":" + ic.Tag,
 				ImagePullPolicy: corev1.PullPolicy(ic.PullPolicy),
-				Ports:           r.defaultKafkaPorts(),
-				Env:             DefaultEnvs(),
+				Command:         r.constructCommand(cluster),
+				Ports:           r.defaultKafkaPorts(cluster),
+				Env:             r.constructEnvs(cluster),
 				ReadinessProbe:  r.constructReadinessProbe(cluster),
 				LivenessProbe:   r.constructLivenessProbe(cluster),
 				VolumeMounts:    r.constructVolumeMounts(cluster),
@@ -400,7 +680,24 @@ func (r *KafkaClusterReconciler) constructKafkaPodSpec(cluster *kafkav1.KafkaClu
 func (r *KafkaClusterReconciler) constructKafkaWorkload(cluster *kafkav1.KafkaCluster) (*appsv1.StatefulSet, error) {
 	pvcs, err := r.constructPVCs(cluster)
 	if err != nil {
-		return nil, nil
+		return nil, err
+	}
+	// the brokers only read the configs on start, so a change of the configs must roll the pods.
+	// the dynamic configs are excluded since they are applied to the running brokers
+	configData := constructConfigData(cluster)
+	configData[DefaultKafkaConfigFileName] = map2String(getReadOnlyConfigs(constructClusterConfKeyValue(cluster)))
+	configHash, err := hashObject(configData)
+	if err != nil {
+		return nil, err
+	}
+	podAnnotations := map[string]string{
+		DefaultConfigHashAnnotation: configHash,
+	}
+	if cluster.Status.CertificatesHash != "" {
+		podAnnotations[DefaultCertificatesHashAnnotation] = cluster.Status.CertificatesHash
+	}
+	if cluster.Status.ClientsCAHash != "" {
+		podAnnotations[DefaultClientsCAHashAnnotation] = cluster.Status.ClientsCAHash
 	}
 	stsDesired := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
@@ -412,11 +709,16 @@ func (r *KafkaClusterReconciler) constructKafkaWorkload(cluster *kafkav1.KafkaCl
 			Selector: &metav1.LabelSelector{
 				MatchLabels: ClusterResourceLabels(cluster),
 			},
-			ServiceName: ClusterResourceName(cluster),
-			Replicas:    int32Ptr(getReplicas(cluster)),
+			ServiceName: ClusterResourceName(cluster, DefaultHeadlessSvcNameSuffix),
+			Replicas:    int32Ptr(getWorkloadReplicas(cluster)),
+			// the brokers are restarted by the operator one at a time once the partitions are in sync
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.OnDeleteStatefulSetStrategyType,
+			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: ClusterResourceLabels(cluster),
+					Labels:      ClusterResourceLabels(cluster),
+					Annotations: podAnnotations,
 				},
 				Spec: r.constructKafkaPodSpec(cluster),
 			},
@@ -424,6 +726,14 @@ func (r *KafkaClusterReconciler) constructKafkaWorkload(cluster *kafkav1.KafkaCl
 		},
 	}
 
+	specHash, err := hashObject(stsDesired.Spec)
+	if err != nil {
+		return nil, err
+	}
+	stsDesired.Annotations = map[string]string{
+		DefaultSpecHashAnnotation: specHash,
+	}
+
 	if err := ctrl.SetControllerReference(cluster, stsDesired, r.Scheme); err != nil {
 		return stsDesired, err
 	}
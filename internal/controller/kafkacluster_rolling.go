@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func getPodOrdinal(pod *corev1.Pod) int {
+	ordinal, err := strconv.Atoi(pod.Name[strings.LastIndex(pod.Name, "-")+1:])
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func (r *KafkaClusterReconciler) listClusterPods(cluster *kafkav1.KafkaCluster) ([]corev1.Pod, error) {
+	existsPods := &corev1.PodList{}
+	listOps := &client.ListOptions{
+		Namespace:     cluster.Namespace,
+		LabelSelector: labels.SelectorFromSet(ClusterResourceLabels(cluster)),
+	}
+	if err := r.Client.List(context.TODO(), existsPods, listOps); err != nil {
+		return nil, err
+	}
+	pods := existsPods.Items
+	sort.Slice(pods, func(i, j int) bool {
+		return getPodOrdinal(&pods[i]) < getPodOrdinal(&pods[j])
+	})
+	return pods, nil
+}
+
+// checkBrokerInSync returns nil if there is no under replicated partition and the broker
+// of the pod is back in the isr of all the partitions it hosts
+func (r *KafkaClusterReconciler) checkBrokerInSync(cluster *kafkav1.KafkaCluster, pod *corev1.Pod) error {
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	brokers, _, err := admin.DescribeCluster()
+	if err != nil {
+		return err
+	}
+	brokerID, ok := getBrokerIDOfPod(brokers, pod.Name)
+	if !ok {
+		return fmt.Errorf("broker of the pod %s has not joined the cluster yet", pod.Name)
+	}
+	topics, err := describeAllTopics(admin)
+	if err != nil {
+		return err
+	}
+	underReplicated, outOfSync := getUnderReplicatedPartitions(topics, brokerID)
+	if underReplicated != 0 || outOfSync != 0 {
+		return fmt.Errorf("%d partitions are under replicated and broker %d is out of the isr of %d partitions",
+			underReplicated, brokerID, outOfSync)
+	}
+	return nil
+}
+
+// isPodOutdated returns true if the pod runs an outdated revision, or was created by a StatefulSet with another
+// service name, whose pods are not resolved by the headless service until they are recreated
+func isPodOutdated(pod *corev1.Pod, sts *appsv1.StatefulSet) bool {
+	return pod.Labels[DefaultRevisionLabel] != sts.Status.UpdateRevision || pod.Spec.Subdomain != sts.Spec.ServiceName
+}
+
+// getLastRestartedPod returns the pod of the update revision created last
+func getLastRestartedPod(pods []corev1.Pod, updateRevision string) *corev1.Pod {
+	var last *corev1.Pod
+	for i := range pods {
+		if pods[i].Labels[DefaultRevisionLabel] != updateRevision {
+			continue
+		}
+		if last == nil || pods[i].CreationTimestamp.After(last.CreationTimestamp.Time) {
+			last = &pods[i]
+		}
+	}
+	return last
+}
+
+// isBrokersRolled returns true if all the brokers run the latest revision carrying the current certificates
+func (r *KafkaClusterReconciler) isBrokersRolled(cluster *kafkav1.KafkaCluster) (bool, error) {
+	sts := &appsv1.StatefulSet{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: ClusterResourceName(cluster), Namespace: cluster.Namespace}, sts)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	if sts.Spec.Template.Annotations[DefaultCertificatesHashAnnotation] != cluster.Status.CertificatesHash {
+		return false, nil
+	}
+	if sts.Status.ObservedGeneration < sts.Generation || sts.Status.UpdateRevision == "" {
+		return false, nil
+	}
+	pods, err := r.listClusterPods(cluster)
+	if err != nil {
+		return false, err
+	}
+	if len(pods) < int(*sts.Spec.Replicas) {
+		return false, nil
+	}
+	for i := range pods {
+		if isPodOutdated(&pods[i], sts) || !isPodReady(&pods[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// reconcileRollingRestart restarts the brokers running an outdated revision one at a time. The next broker
+// is only restarted once the last one is ready and every partition is fully replicated again.
+func (r *KafkaClusterReconciler) reconcileRollingRestart(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	sts := &appsv1.StatefulSet{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: ClusterResourceName(cluster), Namespace: cluster.Namespace}, sts)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if sts.Status.ObservedGeneration < sts.Generation || sts.Status.UpdateRevision == "" {
+		// wait for the StatefulSet controller to compute the new revision
+		return nil
+	}
+	pods, err := r.listClusterPods(cluster)
+	if err != nil {
+		return err
+	}
+
+	outdated := make([]*corev1.Pod, 0)
+	for i := range pods {
+		if isPodOutdated(&pods[i], sts) {
+			outdated = append(outdated, &pods[i])
+		}
+	}
+	if len(outdated) == 0 && !cluster.Status.IsClusterInUpgradingState() {
+		return nil
+	}
+
+	replicas := int(*sts.Spec.Replicas)
+	progress := fmt.Sprintf("%d/%d brokers rolled", replicas-len(outdated), replicas)
+	if !cluster.Status.IsClusterInUpgradingState() {
+		logger.Info("Starting rolling restart of the brokers")
+		cluster.Status.SetUpgradingConditionTrue(kafkav1.UpdatingClusterReason, progress)
+		cluster.Status.TargetVersion = getImageConfig(cluster).Tag
+	} else {
+		cluster.Status.UpdateProgress(kafkav1.UpdatingClusterReason, progress)
+	}
+
+	// the broker restarted last must have recovered before the next one is taken down
+	if last := getLastRestartedPod(pods, sts.Status.UpdateRevision); last != nil {
+		err = nil
+		if !isPodReady(last) {
+			err = fmt.Errorf("pod %s is not ready", last.Name)
+		} else {
+			err = r.checkBrokerInSync(cluster, last)
+		}
+		if err != nil {
+			if last.CreationTimestamp.Add(DefaultRollingRestartTimeout).Before(time.Now()) {
+				logger.Error(err, "Pausing rolling restart of the brokers")
+				cluster.Status.SetErrorConditionTrue(kafkav1.UpgradeFailedReason,
+					fmt.Sprintf("rolling restart paused, broker pod %s did not recover within %s: %s",
+						last.Name, DefaultRollingRestartTimeout, err.Error()))
+				return nil
+			}
+			logger.Info(fmt.Sprintf("Waiting for the broker pod %s to recover: %s", last.Name, err.Error()))
+			return nil
+		}
+	}
+	if cluster.Status.IsClusterInUpgradeFailedState() {
+		logger.Info("Resuming rolling restart of the brokers")
+		cluster.Status.SetErrorConditionFalse()
+	}
+	if len(outdated) == 0 {
+		logger.Info("Rolling restart of the brokers finished")
+		cluster.Status.SetUpgradingConditionFalse()
+		cluster.Status.CurrentVersion = cluster.Status.TargetVersion
+		return nil
+	}
+
+	// brokers which are not ready do not serve any partition, so they are restarted first
+	sort.SliceStable(outdated, func(i, j int) bool {
+		if isPodReady(outdated[i]) != isPodReady(outdated[j]) {
+			return !isPodReady(outdated[i])
+		}
+		return getPodOrdinal(outdated[i]) > getPodOrdinal(outdated[j])
+	})
+	next := outdated[0]
+	for i := range pods {
+		if pods[i].DeletionTimestamp != nil {
+			// still terminating from the last restart
+			return nil
+		}
+		if !isPodReady(&pods[i]) && pods[i].Name != next.Name {
+			logger.Info(fmt.Sprintf("Waiting for the broker pod %s to be ready before restarting %s", pods[i].Name, next.Name))
+			return nil
+		}
+	}
+	if len(pods) < replicas {
+		// a restarted pod has not been recreated yet
+		return nil
+	}
+	logger.Info(fmt.Sprintf("Restarting broker pod %s", next.Name))
+	return r.Client.Delete(context.TODO(), next)
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// getRemovedBrokers returns the ids of the brokers of the pods from the ordinal on
+func getRemovedBrokers(cluster *kafkav1.KafkaCluster, brokers []*sarama.Broker, from int32, to int32) []int32 {
+	removed := make([]int32, 0)
+	for i := from; i < to; i++ {
+		id, ok := getBrokerIDOfPod(brokers, fmt.Sprintf("%s-%d", ClusterResourceName(cluster), i))
+		if !ok {
+			id = GetBrokerID(cluster, int(i))
+		}
+		removed = append(removed, id)
+	}
+	return removed
+}
+
+func getMaxReplicationFactor(topics []*sarama.TopicMetadata) int32 {
+	max := int32(0)
+	for _, t := range topics {
+		if factor := getTopicReplicationFactorOf(t); factor > max {
+			max = factor
+		}
+	}
+	return max
+}
+
+// reconcileScaleDown keeps the brokers being removed until all their replicas are moved to the remaining brokers.
+// It also records the highest replication factor of the topics, which the brokers can not be scaled down below
+func (r *KafkaClusterReconciler) reconcileScaleDown(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	sts := &appsv1.StatefulSet{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: ClusterResourceName(cluster), Namespace: cluster.Namespace}, sts)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if cluster.Status.Drain == nil && sts.Spec.Replicas != nil && getReplicas(cluster) < *sts.Spec.Replicas {
+		logger.Info(fmt.Sprintf("Draining the brokers before scaling down from %d to %d", *sts.Spec.Replicas, getReplicas(cluster)))
+		cluster.Status.Drain = &kafkav1.BrokerDrainStatus{
+			Replicas:  *sts.Spec.Replicas,
+			StartTime: time.Now().Format(time.RFC3339),
+		}
+	}
+	if cluster.Status.Drain == nil {
+		if !cluster.Status.IsClusterInReadyState() || cluster.Status.IsClusterInUpgradingState() {
+			return nil
+		}
+		// the replication factor is best effort, the cluster is reconciled regardless of it
+		if err = r.updateMaxReplicationFactor(cluster); err != nil {
+			logger.Error(err, "Error occurred during describing the topics")
+		}
+		return nil
+	}
+	// the brokers are kept while the drain fails, so the cluster is reconciled regardless of it
+	if err = r.drainBrokers(cluster, logger); err != nil {
+		logger.Error(err, "Error occurred during draining the brokers")
+	}
+	return nil
+}
+
+func (r *KafkaClusterReconciler) updateMaxReplicationFactor(cluster *kafkav1.KafkaCluster) error {
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	topics, err := describeAllTopics(admin)
+	if err != nil {
+		return err
+	}
+	cluster.Status.MaxReplicationFactor = getMaxReplicationFactor(topics)
+	return nil
+}
+
+// drainBrokers waits for the replicas being moved, then moves the replicas left on the brokers being removed.
+// The drain completes once no replica is left on them, and the StatefulSet is scaled down afterwards
+func (r *KafkaClusterReconciler) drainBrokers(cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	drain := cluster.Status.Drain
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	engine := newReassignmentEngine(r.Client, admin, cluster, nil)
+	if drain.IsReassigning() {
+		done, err := engine.reconcile(&drain.ReassignmentStatus, logger)
+		if err != nil || !done {
+			return err
+		}
+	}
+
+	// the scale down is cancelled if the brokers are scaled back up
+	if getReplicas(cluster) >= drain.Replicas {
+		logger.Info("The brokers are scaled back up, the drain is stopped")
+		cluster.Status.Drain = nil
+		return nil
+	}
+	brokers, _, err := admin.DescribeCluster()
+	if err != nil {
+		return err
+	}
+	drain.Brokers = getRemovedBrokers(cluster, brokers, getReplicas(cluster), drain.Replicas)
+	topics, err := describeAllTopics(admin)
+	if err != nil {
+		return err
+	}
+	cluster.Status.MaxReplicationFactor = getMaxReplicationFactor(topics)
+	plans, err := planBrokerRemoval(topics, brokers, drain.Brokers)
+	if err != nil {
+		return err
+	}
+	if len(plans) == 0 {
+		logger.Info(fmt.Sprintf("The brokers %v are drained, scaling down to %d", drain.Brokers, getReplicas(cluster)))
+		cluster.Status.Drain = nil
+		return nil
+	}
+
+	// the drain is planned again once the movements are done, until no replica is left on the brokers
+	startReassignment(&drain.ReassignmentStatus, getPartitionMovements(topics, plans), getReassignmentThrottleRate(cluster))
+	logger.Info(fmt.Sprintf("Moving the replicas of %d partitions off the brokers %v", drain.Partitions, drain.Brokers))
+	_, err = engine.reconcile(&drain.ReassignmentStatus, logger)
+	return err
+}
@@ -0,0 +1,513 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func IsInternalTLSEnabled(cluster *kafkav1.KafkaCluster) bool {
+	return cluster.Spec.Listeners != nil && cluster.Spec.Listeners.Internal != nil && cluster.Spec.Listeners.Internal.TLS
+}
+
+// IsExternalTLSEnabled returns true if the external listener uses the tls, which is required by the SNI routing
+// of the ingresses and the gateways regardless of the tls in the spec
+func IsExternalTLSEnabled(cluster *kafkav1.KafkaCluster) bool {
+	return IsExternalListenerEnabled(cluster) && (cluster.Spec.Listeners.External.TLS || isExternalRouted(cluster))
+}
+
+func IsTLSEnabled(cluster *kafkav1.KafkaCluster) bool {
+	return IsInternalTLSEnabled(cluster) || IsExternalTLSEnabled(cluster)
+}
+
+func getInternalSecurityProtocol(cluster *kafkav1.KafkaCluster) string {
+	return getSecurityProtocol(IsInternalTLSEnabled(cluster), getInternalAuthentication(cluster))
+}
+
+func GetClusterCASecretName(cluster *kafkav1.KafkaCluster) string {
+	return ClusterResourceName(cluster, DefaultClusterCASecretSuffix)
+}
+
+func GetClusterCACertSecretName(cluster *kafkav1.KafkaCluster) string {
+	return ClusterResourceName(cluster, DefaultClusterCACertSecretSuffix)
+}
+
+func GetBrokersTLSSecretName(cluster *kafkav1.KafkaCluster) string {
+	return ClusterResourceName(cluster, DefaultBrokersTLSSecretSuffix)
+}
+
+// getSvcDNSNames returns all the names the service can be resolved by inside the k8s
+func getSvcDNSNames(cluster *kafkav1.KafkaCluster, name string) []string {
+	return []string{
+		name,
+		fmt.Sprintf("%s.%s", name, cluster.Namespace),
+		fmt.Sprintf("%s.%s.svc", name, cluster.Namespace),
+		fmt.Sprintf("%s.%s.svc.%s", name, cluster.Namespace, GetClusterDomain(cluster)),
+	}
+}
+
+// getBrokerSANs returns the names of the broker covered by its certificate, the node ips of the
+// NodePort external listener are not covered since the pods move between the nodes
+func getBrokerSANs(cluster *kafkav1.KafkaCluster, ordinal int) []string {
+	headlessSvc := ClusterResourceName(cluster, DefaultHeadlessSvcNameSuffix)
+	sans := getSvcDNSNames(cluster, fmt.Sprintf("%s-%d.%s", ClusterResourceName(cluster), ordinal, headlessSvc))
+	sans = append(sans, getSvcDNSNames(cluster, headlessSvc)...)
+	sans = append(sans, getSvcDNSNames(cluster, ClusterResourceName(cluster))...)
+	if !IsExternalListenerEnabled(cluster) {
+		return sans
+	}
+	switch getExternalListenerType(cluster) {
+	case kafkav1.ExternalListenerTypeIngress, kafkav1.ExternalListenerTypeGateway:
+		sans = append(sans, getExternalHost(cluster, ordinal), getExternalBootstrapHost(cluster))
+	case kafkav1.ExternalListenerTypeLoadBalancer:
+		for _, addr := range cluster.Status.ExternalAddresses {
+			if int(addr.Ordinal) == ordinal {
+				sans = append(sans, addr.Host)
+			}
+		}
+		if host, _, err := net.SplitHostPort(cluster.Status.ExternalClientEndpoint); err == nil {
+			sans = append(sans, host)
+		}
+	}
+	return sans
+}
+
+// constructTLSScript renders the keystore of the pod and the truststore into the config
+func constructTLSScript(cluster *kafkav1.KafkaCluster) string {
+	if !IsTLSEnabled(cluster) {
+		return ""
+	}
+	if _, ok := cluster.Spec.Conf["ssl.keystore.location"]; ok {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("echo \"ssl.keystore.type=PEM\" >> ${CONF}\n")
+	sb.WriteString(fmt.Sprintf("echo \"ssl.keystore.location=%s/${POD_NAME}%s\" >> ${CONF}\n", DefaultTLSPath, DefaultKeystoreSuffix))
+	sb.WriteString("echo \"ssl.truststore.type=PEM\" >> ${CONF}\n")
+	sb.WriteString(fmt.Sprintf("echo \"ssl.truststore.location=%s/%s\" >> ${CONF}\n", DefaultTLSPath, DefaultCACertKey))
+	return sb.String()
+}
+
+func (r *KafkaClusterReconciler) constructSecret(cluster *kafkav1.KafkaCluster, name string, data map[string][]byte) (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cluster.Namespace,
+			Labels:    ClusterResourceLabels(cluster),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	if err := ctrl.SetControllerReference(cluster, secret, r.Scheme); err != nil {
+		return secret, err
+	}
+	return secret, nil
+}
+
+func (r *KafkaClusterReconciler) applySecret(desiredSecret *corev1.Secret, logger logr.Logger) error {
+	existsSecret := &corev1.Secret{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: desiredSecret.Name, Namespace: desiredSecret.Namespace}, existsSecret)
+	if err != nil && errors.IsNotFound(err) {
+		logger.Info(fmt.Sprintf("Creating a new secret %s", desiredSecret.Name))
+		return r.Client.Create(context.TODO(), desiredSecret)
+	} else if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existsSecret.Data, desiredSecret.Data) {
+		return nil
+	}
+	logger.Info(fmt.Sprintf("Updating the secret %s", desiredSecret.Name))
+	existsSecret.Data = desiredSecret.Data
+	return r.Client.Update(context.TODO(), existsSecret)
+}
+
+func (r *KafkaClusterReconciler) getSecret(cluster *kafkav1.KafkaCluster, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: cluster.Namespace}, secret)
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func getCertRenewBefore(cluster *kafkav1.KafkaCluster) time.Duration {
+	if cluster.Spec.TLS != nil && cluster.Spec.TLS.RenewBefore != nil {
+		return cluster.Spec.TLS.RenewBefore.Duration
+	}
+	return DefaultCertRenewBefore
+}
+
+func getCARenewBefore(cluster *kafkav1.KafkaCluster) time.Duration {
+	if cluster.Spec.TLS != nil && cluster.Spec.TLS.CARenewBefore != nil {
+		return cluster.Spec.TLS.CARenewBefore.Duration
+	}
+	return DefaultCARenewBefore
+}
+
+// reconcileClusterCA loads the cluster CA, the CA is created along with the cluster and replaced
+// by a new one once it is in the renewal window. the brokers are moved to the new CA by reconcileKeystores
+func (r *KafkaClusterReconciler) reconcileClusterCA(cluster *kafkav1.KafkaCluster, logger logr.Logger) (*certificateAuthority, error) {
+	return r.reconcileCA(cluster, GetClusterCASecretName(cluster), logger)
+}
+
+// reconcileCA loads the CA stored in the secret, a new CA is created if the secret does not exist or the CA is in the renewal window
+func (r *KafkaClusterReconciler) reconcileCA(cluster *kafkav1.KafkaCluster, name string, logger logr.Logger) (*certificateAuthority, error) {
+	secret, err := r.getSecret(cluster, name)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		ca, err := parseCertificateAuthority(secret.Data[DefaultCACertKey], secret.Data[DefaultCAKeyKey])
+		if err != nil {
+			return nil, err
+		}
+		if !isCertificateExpiring(ca.cert, getCARenewBefore(cluster)) {
+			return ca, nil
+		}
+		logger.Info(fmt.Sprintf("Renewing the CA %s expiring at %s", name, ca.cert.NotAfter.Format(time.RFC3339)))
+	} else {
+		logger.Info(fmt.Sprintf("Creating the CA %s", name))
+	}
+	ca, err := newCertificateAuthority(name)
+	if err != nil {
+		return nil, err
+	}
+	desiredSecret, err := r.constructSecret(cluster, name, map[string][]byte{
+		DefaultCACertKey: ca.certPEM,
+		DefaultCAKeyKey:  ca.keyPEM,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = r.applySecret(desiredSecret, logger); err != nil {
+		return nil, err
+	}
+	return ca, nil
+}
+
+// keystoreIssuer returns the keystore of the broker, the exists one is nil for a new broker
+type keystoreIssuer func(ordinal int, exists []byte) ([]byte, error)
+
+// reconcileKeystores stores the keystores of the brokers and the truststore in the secret mounted by the brokers.
+// a new CA is rolled out in phases, so the brokers and the clients never see a certificate they do not trust:
+// the brokers trust both CAs and roll, then switch to the certificates signed by the new CA and roll again,
+// then the old CA is dropped
+func (r *KafkaClusterReconciler) reconcileKeystores(cluster *kafkav1.KafkaCluster, caPEM []byte, issue keystoreIssuer, logger logr.Logger) ([]byte, error) {
+	existsData, err := r.getBrokersKeystores(cluster)
+	if err != nil {
+		return nil, err
+	}
+	trusted := existsData[DefaultCACertKey]
+	phase := cluster.Status.CARotationPhase
+	if phase == "" && len(trusted) > 0 && !containsCertificates(trusted, caPEM) {
+		logger.Info("Starting the rotation of the CA, the brokers trust both the old and the new CA")
+		phase = kafkav1.CARotationPhaseTrustNew
+	} else if phase != "" {
+		rolled, err := r.isBrokersRolled(cluster)
+		if err != nil {
+			return nil, err
+		}
+		if rolled && phase == kafkav1.CARotationPhaseTrustNew {
+			logger.Info("Switching the brokers to the certificates signed by the new CA")
+			phase = kafkav1.CARotationPhaseSignNew
+		} else if rolled && phase == kafkav1.CARotationPhaseSignNew {
+			logger.Info("Finishing the rotation of the CA, dropping the old CA")
+			phase = ""
+		}
+	}
+
+	truststore := caPEM
+	if phase != "" {
+		truststore = mergeCertificatesPEM(trusted, caPEM)
+	}
+	data := map[string][]byte{
+		DefaultCACertKey: truststore,
+	}
+	for i := 0; i < int(getWorkloadReplicas(cluster)); i++ {
+		key := getBrokerKeystoreKey(cluster, i)
+		exists, ok := existsData[key]
+		if ok && phase == kafkav1.CARotationPhaseTrustNew {
+			data[key] = exists
+			continue
+		}
+		keystore, err := issue(i, exists)
+		if err != nil {
+			return nil, err
+		}
+		data[key] = keystore
+	}
+
+	desiredSecret, err := r.constructSecret(cluster, GetBrokersTLSSecretName(cluster), data)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.applySecret(desiredSecret, logger); err != nil {
+		return nil, err
+	}
+	if err = r.publishClusterCACert(cluster, truststore, logger); err != nil {
+		return nil, err
+	}
+	// the keystores of the new brokers are picked up on start and the old CA can be dropped
+	// without a restart, so only the changed keystores and the new CA roll the brokers
+	changed := cluster.Status.CertificatesHash == ""
+	for k, v := range data {
+		if exists, ok := existsData[k]; ok && !bytes.Equal(exists, v) {
+			changed = changed || k != DefaultCACertKey || phase != ""
+		}
+	}
+	if changed {
+		hash, err := hashObject(data)
+		if err != nil {
+			return nil, err
+		}
+		cluster.Status.CertificatesHash = hash
+	}
+	cluster.Status.CARotationPhase = phase
+	updateCertificatesStatus(cluster, data)
+	return truststore, nil
+}
+
+// updateCertificatesStatus records the expiry of the CA certificates and the certificates of the brokers
+func updateCertificatesStatus(cluster *kafkav1.KafkaCluster, data map[string][]byte) {
+	cluster.Status.ClusterCACertificates = getCertificatesStatus(data[DefaultCACertKey])
+	cluster.Status.BrokerCertificates = nil
+	for _, key := range sortedKeys(data) {
+		if key == DefaultCACertKey {
+			continue
+		}
+		cert, err := parseCertificatePEM(data[key])
+		if err != nil {
+			continue
+		}
+		cluster.Status.BrokerCertificates = append(cluster.Status.BrokerCertificates, kafkav1.CertificateStatus{
+			Name:     strings.TrimSuffix(key, DefaultKeystoreSuffix),
+			NotAfter: cert.NotAfter.Format(time.RFC3339),
+		})
+	}
+}
+
+// reconcileBrokersTLS issues the certificates of the brokers signed by the cluster CA. the certificates
+// are reissued once they are signed by another CA, in the renewal window or the sans change
+func (r *KafkaClusterReconciler) reconcileBrokersTLS(cluster *kafkav1.KafkaCluster, ca *certificateAuthority, logger logr.Logger) error {
+	truststore, err := r.reconcileKeystores(cluster, ca.certPEM, func(ordinal int, exists []byte) ([]byte, error) {
+		dnsNames, ips := splitSANs(getBrokerSANs(cluster, ordinal))
+		if exists != nil {
+			cert, err := parseCertificatePEM(exists)
+			if err == nil && ca.isCertificateValid(cert, dnsNames, ips, getCertRenewBefore(cluster)) {
+				return exists, nil
+			}
+		}
+		logger.Info(fmt.Sprintf("Issuing the certificate of the broker %d", ordinal))
+		certPEM, keyPEM, err := ca.issueCertificate(GetPodFullName(cluster, ordinal), dnsNames, ips)
+		if err != nil {
+			return nil, err
+		}
+		return joinKeystore(certPEM, keyPEM), nil
+	}, logger)
+	if err != nil {
+		return err
+	}
+	return r.reconcileOperatorTLS(cluster, ca, truststore, logger)
+}
+
+func getBrokerKeystoreKey(cluster *kafkav1.KafkaCluster, ordinal int) string {
+	return fmt.Sprintf("%s-%d%s", ClusterResourceName(cluster), ordinal, DefaultKeystoreSuffix)
+}
+
+// getBrokersKeystores returns the keystores currently mounted by the brokers
+func (r *KafkaClusterReconciler) getBrokersKeystores(cluster *kafkav1.KafkaCluster) (map[string][]byte, error) {
+	secret, err := r.getSecret(cluster, GetBrokersTLSSecretName(cluster))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return map[string][]byte{}, nil
+		}
+		return nil, err
+	}
+	return secret.Data, nil
+}
+
+func (r *KafkaClusterReconciler) publishClusterCACert(cluster *kafkav1.KafkaCluster, caCertPEM []byte, logger logr.Logger) error {
+	caCertSecret, err := r.constructSecret(cluster, GetClusterCACertSecretName(cluster), map[string][]byte{
+		DefaultCACertKey: caCertPEM,
+	})
+	if err != nil {
+		return err
+	}
+	return r.applySecret(caCertSecret, logger)
+}
+
+// reconcileTLS creates the cluster CA, publishes the CA certificate to the clients
+// and issues the certificates of the brokers, or requests them from cert-manager
+func (r *KafkaClusterReconciler) reconcileTLS(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	if !IsTLSEnabled(cluster) || !IsCertManagerEnabled(cluster) {
+		if err := r.deleteStaleCertificates(cluster, map[string]bool{}, logger); err != nil {
+			return err
+		}
+		cluster.Status.SetCertificatesPendingConditionFalse()
+	}
+	if !IsTLSEnabled(cluster) {
+		cluster.Status.CertificatesHash = ""
+		cluster.Status.ClusterCACertificates = nil
+		cluster.Status.BrokerCertificates = nil
+		cluster.Status.OperatorCertificate = nil
+		cluster.Status.CARotationPhase = ""
+		return nil
+	}
+	if !getKafkaVersion(cluster).IsAtLeast(sarama.V2_7_0_0) {
+		return fmt.Errorf("the PEM keystores of the brokers require kafka 2.7.0 or later")
+	}
+	if IsCertManagerEnabled(cluster) {
+		// the certificate of the operator is renewed by cert-manager
+		cluster.Status.OperatorCertificate = nil
+		return r.reconcileCertManagerTLS(cluster, logger)
+	}
+	ca, err := r.reconcileClusterCA(cluster, logger)
+	if err != nil {
+		return err
+	}
+	return r.reconcileBrokersTLS(cluster, ca, logger)
+}
+
+func GetOperatorTLSSecretName(cluster *kafkav1.KafkaCluster) string {
+	return ClusterResourceName(cluster, DefaultOperatorTLSSecretSuffix)
+}
+
+// isOperatorCertificateRequired returns true if the operator authenticates to the replication listener by its certificate
+func isOperatorCertificateRequired(cluster *kafkav1.KafkaCluster) bool {
+	return HasReplicationListener(cluster) && IsInternalTLSEnabled(cluster)
+}
+
+// getCertificatesStatus returns the expiry of the certificates of a PEM bundle
+func getCertificatesStatus(bundlePEM []byte) []kafkav1.CertificateStatus {
+	var certificates []kafkav1.CertificateStatus
+	for _, cert := range parseCertificatesPEM(bundlePEM) {
+		certificates = append(certificates, kafkav1.CertificateStatus{
+			Name:     cert.Subject.CommonName,
+			NotAfter: cert.NotAfter.Format(time.RFC3339),
+		})
+	}
+	return certificates
+}
+
+// getCertificatesRenewAfter returns how long until a certificate in the status enters its renewal window, or
+// expires if it is already in it since the expired CAs are dropped from the truststores. zero if none is due
+func getCertificatesRenewAfter(cluster *kafkav1.KafkaCluster) time.Duration {
+	var after time.Duration
+	due := func(certificates []kafkav1.CertificateStatus, renewBefore time.Duration) {
+		for _, certificate := range certificates {
+			notAfter, err := time.Parse(time.RFC3339, certificate.NotAfter)
+			if err != nil {
+				continue
+			}
+			d := time.Until(notAfter.Add(-renewBefore))
+			if d <= 0 {
+				d = time.Until(notAfter)
+			}
+			if d > 0 && (after == 0 || d < after) {
+				after = d
+			}
+		}
+	}
+	due(cluster.Status.ClusterCACertificates, getCARenewBefore(cluster))
+	due(cluster.Status.ClientsCACertificates, getCARenewBefore(cluster))
+	due(cluster.Status.BrokerCertificates, getCertRenewBefore(cluster))
+	if cluster.Status.OperatorCertificate != nil {
+		due([]kafkav1.CertificateStatus{*cluster.Status.OperatorCertificate}, getCertRenewBefore(cluster))
+	}
+	return after
+}
+
+// getOperatorCommonName returns the common name of the client certificate of the operator
+func getOperatorCommonName(cluster *kafkav1.KafkaCluster) string {
+	return ClusterResourceName(cluster, "-operator")
+}
+
+// reconcileOperatorTLS issues the client certificate of the operator signed by the cluster CA. the certificate
+// is kept as long as it is signed by a CA trusted by the brokers, so the operator keeps access during a CA rotation
+func (r *KafkaClusterReconciler) reconcileOperatorTLS(cluster *kafkav1.KafkaCluster, ca *certificateAuthority, truststore []byte, logger logr.Logger) error {
+	cluster.Status.OperatorCertificate = nil
+	if !isOperatorCertificateRequired(cluster) {
+		return nil
+	}
+	secret, err := r.getSecret(cluster, GetOperatorTLSSecretName(cluster))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if secret != nil {
+		if cert, err := parseCertificatePEM(secret.Data[corev1.TLSCertKey]); err == nil {
+			for _, trusted := range parseCertificatesPEM(truststore) {
+				if cert.CheckSignatureFrom(trusted) == nil && !isCertificateExpiring(cert, getCertRenewBefore(cluster)) {
+					cluster.Status.OperatorCertificate = &getCertificatesStatus(secret.Data[corev1.TLSCertKey])[0]
+					return nil
+				}
+			}
+		}
+	}
+	logger.Info("Issuing the client certificate of the operator")
+	certPEM, keyPEM, err := ca.issueCertificate(getOperatorCommonName(cluster), nil, nil)
+	if err != nil {
+		return err
+	}
+	desiredSecret, err := r.constructSecret(cluster, GetOperatorTLSSecretName(cluster), map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+	})
+	if err != nil {
+		return err
+	}
+	desiredSecret.Type = corev1.SecretTypeTLS
+	if err = r.applySecret(desiredSecret, logger); err != nil {
+		return err
+	}
+	cluster.Status.OperatorCertificate = &getCertificatesStatus(certPEM)[0]
+	return nil
+}
+
+// getAdminTLSConfig returns the tls config to connect to the listener used by the operator, nil if the tls is disabled
+func getAdminTLSConfig(c client.Client, cluster *kafkav1.KafkaCluster) (*tls.Config, error) {
+	if !IsInternalTLSEnabled(cluster) {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: GetClusterCACertSecretName(cluster), Namespace: cluster.Namespace}, secret)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(secret.Data[DefaultCACertKey]) {
+		return nil, fmt.Errorf("no valid CA certificate found in the secret %s", secret.Name)
+	}
+	tlsConfig := &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	if isOperatorCertificateRequired(cluster) {
+		operatorSecret := &corev1.Secret{}
+		err = c.Get(context.TODO(), types.NamespacedName{Name: GetOperatorTLSSecretName(cluster), Namespace: cluster.Namespace}, operatorSecret)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.X509KeyPair(operatorSecret.Data[corev1.TLSCertKey], operatorSecret.Data[corev1.TLSPrivateKeyKey])
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+func TestGetCertificatesRenewAfter(t *testing.T) {
+	notAfter := func(d time.Duration) string { return time.Now().Add(d).Format(time.RFC3339) }
+	tests := []struct {
+		name   string
+		status kafkav1.KafkaClusterStatus
+		min    time.Duration
+		max    time.Duration
+	}{
+		{name: "no certificates"},
+		{
+			name: "earliest renewal window",
+			status: kafkav1.KafkaClusterStatus{
+				ClusterCACertificates: []kafkav1.CertificateStatus{{Name: "ca", NotAfter: notAfter(DefaultCARenewBefore + 48*time.Hour)}},
+				BrokerCertificates:    []kafkav1.CertificateStatus{{Name: "kafka-0", NotAfter: notAfter(DefaultCertRenewBefore + 24*time.Hour)}},
+			},
+			min: 23 * time.Hour, max: 24 * time.Hour,
+		},
+		{
+			name: "expiry of a replaced CA",
+			status: kafkav1.KafkaClusterStatus{
+				ClientsCACertificates: []kafkav1.CertificateStatus{{Name: "old", NotAfter: notAfter(time.Hour)}},
+			},
+			min: 59 * time.Minute, max: time.Hour,
+		},
+		{
+			name: "expired certificates",
+			status: kafkav1.KafkaClusterStatus{
+				OperatorCertificate: &kafkav1.CertificateStatus{Name: "operator", NotAfter: notAfter(-time.Hour)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			after := getCertificatesRenewAfter(&kafkav1.KafkaCluster{Status: tt.status})
+			g.Expect(after).To(BeNumerically(">=", tt.min))
+			g.Expect(after).To(BeNumerically("<=", tt.max))
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func IsTopicSyncEnabled(cluster *kafkav1.KafkaCluster) bool {
+	return cluster.Spec.TopicSync != nil
+}
+
+func getTopicSyncInterval(cluster *kafkav1.KafkaCluster) time.Duration {
+	if cluster.Spec.TopicSync != nil && cluster.Spec.TopicSync.Interval != nil {
+		return cluster.Spec.TopicSync.Interval.Duration
+	}
+	return DefaultTopicSyncInterval
+}
+
+// isTopicExcluded returns true if the topic is internal or matches the exclude patterns of the topic sync
+func isTopicExcluded(cluster *kafkav1.KafkaCluster, name string) bool {
+	if strings.HasPrefix(name, DefaultInternalTopicPrefix) {
+		return true
+	}
+	for _, pattern := range cluster.Spec.TopicSync.ExcludePatterns {
+		if matched, err := regexp.MatchString(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+var invalidResourceNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// getAdoptedTopicResourceName returns the name of the KafkaTopic of an adopted topic, the topic names
+// not valid as resource names are sanitized and suffixed with a hash of the topic name to stay unique
+func getAdoptedTopicResourceName(cluster *kafkav1.KafkaCluster, topicName string) string {
+	name := fmt.Sprintf("%s-%s", cluster.Name, topicName)
+	sanitized := strings.Trim(invalidResourceNameChars.ReplaceAllString(strings.ToLower(name), "-"), ".-")
+	if sanitized == name && len(name) <= DefaultResourceNameMaxLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(topicName))
+	suffix := hex.EncodeToString(sum[:])[:8]
+	if len(sanitized) > DefaultResourceNameMaxLength-len(suffix)-1 {
+		sanitized = strings.Trim(sanitized[:DefaultResourceNameMaxLength-len(suffix)-1], ".-")
+	}
+	return fmt.Sprintf("%s-%s", sanitized, suffix)
+}
+
+// listClusterTopics returns the KafkaTopics of the cluster by their topic names
+func (r *KafkaClusterReconciler) listClusterTopics(cluster *kafkav1.KafkaCluster) (map[string]*kafkav1.KafkaTopic, error) {
+	topicList := &kafkav1.KafkaTopicList{}
+	if err := r.Client.List(context.TODO(), topicList, client.InNamespace(cluster.Namespace)); err != nil {
+		return nil, err
+	}
+	topics := make(map[string]*kafkav1.KafkaTopic)
+	for i := range topicList.Items {
+		topic := &topicList.Items[i]
+		if topic.Spec.ClusterRef == cluster.Name {
+			topics[GetTopicName(topic)] = topic
+		}
+	}
+	return topics, nil
+}
+
+// reconcileTopicSync adopts the topics created outside the operator into KafkaTopics with the observed partitions,
+// replication factor and topic configs, while the KafkaTopics are applied to the cluster by the topic controller.
+// the adopted topics are retained on deletion unless the policy is changed
+func (r *KafkaClusterReconciler) reconcileTopicSync(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	if !IsTopicSyncEnabled(cluster) || !cluster.Status.IsClusterInReadyState() || cluster.Status.IsClusterInUpgradingState() {
+		return nil
+	}
+	if last, err := time.Parse(time.RFC3339, cluster.Status.LastTopicSyncTime); err == nil && time.Since(last) < getTopicSyncInterval(cluster) {
+		return nil
+	}
+	// the topic sync is best effort, the cluster is reconciled regardless of it
+	if err := r.syncTopics(cluster, logger); err != nil {
+		logger.Error(err, "Error occurred during syncing the topics")
+		return nil
+	}
+	cluster.Status.LastTopicSyncTime = time.Now().Format(time.RFC3339)
+	return nil
+}
+
+func (r *KafkaClusterReconciler) syncTopics(cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	details, err := admin.ListTopics()
+	if err != nil {
+		return err
+	}
+	topics, err := r.listClusterTopics(cluster)
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(details) {
+		if existing, ok := topics[name]; ok {
+			// the partitions added outside the operator can not be removed, so the adopted topics follow them
+			if existing.Labels[DefaultAdoptedLabel] == "true" && details[name].NumPartitions > existing.Spec.Partitions {
+				logger.Info(fmt.Sprintf("Following the partitions of the adopted topic %s increased to %d", name, details[name].NumPartitions))
+				existing.Spec.Partitions = details[name].NumPartitions
+				if err = r.Client.Update(context.TODO(), existing); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if isTopicExcluded(cluster, name) {
+			continue
+		}
+		config, err := describeTopicConfig(admin, name)
+		if err != nil {
+			return err
+		}
+		topic := &kafkav1.KafkaTopic{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      getAdoptedTopicResourceName(cluster, name),
+				Namespace: cluster.Namespace,
+				Labels: map[string]string{
+					"cluster":           cluster.Name,
+					DefaultAdoptedLabel: "true",
+				},
+			},
+			Spec: kafkav1.KafkaTopicSpec{
+				ClusterRef:        cluster.Name,
+				TopicName:         name,
+				Partitions:        details[name].NumPartitions,
+				ReplicationFactor: int32(details[name].ReplicationFactor),
+				Config:            config,
+				DeletionPolicy:    kafkav1.TopicDeletionPolicyRetain,
+			},
+		}
+		logger.Info(fmt.Sprintf("Adopting the topic %s into the KafkaTopic %s", name, topic.Name))
+		if err = r.Client.Create(context.TODO(), topic); err != nil {
+			return err
+		}
+	}
+	return nil
+}
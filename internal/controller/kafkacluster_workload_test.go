@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+func newFakeReconciler(objs ...runtime.Object) *KafkaClusterReconciler {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = kafkav1.AddToScheme(scheme)
+	return &KafkaClusterReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).WithStatusSubresource(&kafkav1.KafkaCluster{}).Build(),
+		Scheme: scheme,
+	}
+}
+
+// TestUpdateLegacyWorkload upgrades the StatefulSet created by the operator before the headless service was used
+func TestUpdateLegacyWorkload(t *testing.T) {
+	g := NewWithT(t)
+	cluster := &kafkav1.KafkaCluster{ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"}}
+	legacy := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: ClusterResourceName(cluster), Namespace: cluster.Namespace},
+		Spec: appsv1.StatefulSetSpec{
+			ServiceName: ClusterResourceName(cluster),
+			Selector:    &metav1.LabelSelector{MatchLabels: ClusterResourceLabels(cluster)},
+		},
+	}
+	desired := legacy.DeepCopy()
+	desired.Spec.ServiceName = ClusterResourceName(cluster, DefaultHeadlessSvcNameSuffix)
+	r := newFakeReconciler(legacy.DeepCopy())
+
+	g.Expect(r.updateWorkload(context.TODO(), cluster, legacy, desired, logr.Discard())).To(Succeed())
+	g.Expect(cluster.Status.IsClusterInErrorState(kafkav1.ImmutableFieldChangedReason)).To(BeFalse())
+	err := r.Get(context.TODO(), types.NamespacedName{Name: legacy.Name, Namespace: legacy.Namespace}, &appsv1.StatefulSet{})
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+
+	// the pods of the legacy StatefulSet are recreated by the rolling restart once adopted
+	sts := &appsv1.StatefulSet{Spec: desired.Spec, Status: appsv1.StatefulSetStatus{UpdateRevision: "rev"}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{DefaultRevisionLabel: "rev"}}}
+	pod.Spec.Subdomain = ClusterResourceName(cluster)
+	g.Expect(isPodOutdated(pod, sts)).To(BeTrue())
+	pod.Spec.Subdomain = sts.Spec.ServiceName
+	g.Expect(isPodOutdated(pod, sts)).To(BeFalse())
+
+	// any other change of the service name is still refused
+	other := desired.DeepCopy()
+	other.Spec.ServiceName = "other"
+	g.Expect(isLegacyWorkload(cluster, desired, other)).To(BeFalse())
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2024 nineinfra.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+// KafkaQuotaReconciler reconciles a KafkaQuota object
+type KafkaQuotaReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkaquotas,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkaquotas/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkaquotas/finalizers,verbs=update
+
+// Reconcile applies the quotas of the entity in the cluster and removes the ones no longer in the spec
+func (r *KafkaQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var quota kafkav1.KafkaQuota
+	err := r.Get(ctx, req.NamespacedName, &quota)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("Object not found, it could have been deleted")
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Error occurred during fetching the object")
+		return ctrl.Result{}, err
+	}
+
+	if !quota.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, finalizeClusterResource(r.Client, &quota, quota.Spec.ClusterRef, func(cluster *kafkav1.KafkaCluster) error {
+			return r.finalizeQuota(&quota, cluster, logger)
+		})
+	}
+	if err = addFinalizer(r.Client, &quota); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// an entity without a user and a client id is not retried until the spec changes
+	if quota.Spec.Entity.User == "" && quota.Spec.Entity.ClientID == "" {
+		return ctrl.Result{}, r.updateQuotaStatus(&quota, false, "at least one of the user and the client id of the entity is required")
+	}
+	cluster, err := getClusterRef(r.Client, quota.Namespace, quota.Spec.ClusterRef)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{RequeueAfter: DefaultRequeueInterval},
+				r.updateQuotaStatus(&quota, false, fmt.Sprintf("the cluster %s is not found", quota.Spec.ClusterRef))
+		}
+		return ctrl.Result{}, err
+	}
+	if err = r.reconcileQuota(&quota, cluster, logger); err != nil {
+		logger.Error(err, "Error occurred during reconciling the quota")
+		if statusErr := r.updateQuotaStatus(&quota, false, err.Error()); statusErr != nil {
+			logger.Error(statusErr, "Error occurred during updating the quota status")
+		}
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, r.updateQuotaStatus(&quota, true, "")
+}
+
+func (r *KafkaQuotaReconciler) updateQuotaStatus(quota *kafkav1.KafkaQuota, ready bool, message string) error {
+	quota.Status.Ready = ready
+	quota.Status.Message = message
+	if ready {
+		quota.Status.ObservedGeneration = quota.Generation
+	}
+	return r.Status().Update(context.TODO(), quota)
+}
+
+// parseQuotaValue returns the value of a quota given as an integer or a decimal string
+func parseQuotaValue(value intstr.IntOrString) (float64, error) {
+	if value.Type == intstr.Int {
+		return float64(value.IntVal), nil
+	}
+	return strconv.ParseFloat(value.StrVal, 64)
+}
+
+// getDesiredQuotas returns the quotas in the spec by the keys of the client quotas
+func getDesiredQuotas(quota *kafkav1.KafkaQuota) (map[string]float64, error) {
+	desired := make(map[string]float64)
+	quotas := quota.Spec.Quotas
+	if quotas.ProducerByteRate != nil {
+		desired[ProducerByteRateQuota] = float64(*quotas.ProducerByteRate)
+	}
+	if quotas.ConsumerByteRate != nil {
+		desired[ConsumerByteRateQuota] = float64(*quotas.ConsumerByteRate)
+	}
+	decimals := map[string]*intstr.IntOrString{
+		RequestPercentageQuota:      quotas.RequestPercentage,
+		ControllerMutationRateQuota: quotas.ControllerMutationRate,
+	}
+	for key, value := range decimals {
+		if value == nil {
+			continue
+		}
+		v, err := parseQuotaValue(*value)
+		if err == nil && v < 0 {
+			err = fmt.Errorf("negative value")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %s of the quota %s: %v", value.String(), key, err)
+		}
+		desired[key] = v
+	}
+	return desired, nil
+}
+
+func getQuotaEntityComponent(entityType sarama.QuotaEntityType, name string) sarama.QuotaEntityComponent {
+	if name == kafkav1.QuotaEntityDefault {
+		return sarama.QuotaEntityComponent{EntityType: entityType, MatchType: sarama.QuotaMatchDefault}
+	}
+	return sarama.QuotaEntityComponent{EntityType: entityType, MatchType: sarama.QuotaMatchExact, Name: name}
+}
+
+// getQuotaEntity returns the components of the entity, the user goes first
+func getQuotaEntity(entity kafkav1.QuotaEntity) []sarama.QuotaEntityComponent {
+	components := make([]sarama.QuotaEntityComponent, 0)
+	if entity.User != "" {
+		components = append(components, getQuotaEntityComponent(sarama.QuotaEntityUser, entity.User))
+	}
+	if entity.ClientID != "" {
+		components = append(components, getQuotaEntityComponent(sarama.QuotaEntityClientID, entity.ClientID))
+	}
+	return components
+}
+
+// describeQuotas returns the quotas of exactly the entity
+func describeQuotas(admin sarama.ClusterAdmin, entity []sarama.QuotaEntityComponent) (map[string]float64, error) {
+	filters := make([]sarama.QuotaFilterComponent, 0, len(entity))
+	for _, component := range entity {
+		filters = append(filters, sarama.QuotaFilterComponent{
+			EntityType: component.EntityType,
+			MatchType:  component.MatchType,
+			Match:      component.Name,
+		})
+	}
+	entries, err := admin.DescribeClientQuotas(filters, true)
+	if err != nil {
+		return nil, err
+	}
+	quotas := make(map[string]float64)
+	for _, entry := range entries {
+		for k, v := range entry.Values {
+			quotas[k] = v
+		}
+	}
+	return quotas, nil
+}
+
+func removeQuotas(admin sarama.ClusterAdmin, entity []sarama.QuotaEntityComponent, keys []string) error {
+	for _, key := range keys {
+		if err := admin.AlterClientQuotas(entity, sarama.ClientQuotasOp{Key: key, Remove: true}, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileQuota sets the quotas in the spec and removes the owned ones no longer in the spec,
+// the owned ones move along when the entity changes
+func (r *KafkaQuotaReconciler) reconcileQuota(quota *kafkav1.KafkaQuota, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	entity := getQuotaEntity(quota.Spec.Entity)
+	desired, err := getDesiredQuotas(quota)
+	if err != nil {
+		return err
+	}
+	if quota.Status.Entity != nil && *quota.Status.Entity != quota.Spec.Entity {
+		logger.Info(fmt.Sprintf("Removing the quotas %v of the previous entity %v", quota.Status.OwnedQuotas, *quota.Status.Entity))
+		if err = removeQuotas(admin, getQuotaEntity(*quota.Status.Entity), quota.Status.OwnedQuotas); err != nil {
+			return err
+		}
+		quota.Status.OwnedQuotas = nil
+	}
+	quota.Status.Entity = quota.Spec.Entity.DeepCopy()
+
+	removed := make([]string, 0)
+	for _, key := range quota.Status.OwnedQuotas {
+		if _, ok := desired[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	if len(removed) > 0 {
+		logger.Info(fmt.Sprintf("Removing the quotas %v of the entity %v", removed, quota.Spec.Entity))
+		if err = removeQuotas(admin, entity, removed); err != nil {
+			return err
+		}
+	}
+
+	exists, err := describeQuotas(admin, entity)
+	if err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(desired) {
+		if value, ok := exists[key]; ok && value == desired[key] {
+			continue
+		}
+		logger.Info(fmt.Sprintf("Setting the quota %s of the entity %v to %v", key, quota.Spec.Entity, desired[key]))
+		if err = admin.AlterClientQuotas(entity, sarama.ClientQuotasOp{Key: key, Value: desired[key]}, false); err != nil {
+			return err
+		}
+	}
+	quota.Status.OwnedQuotas = sortedKeys(desired)
+
+	entries, err := admin.DescribeClientQuotas(nil, false)
+	if err != nil {
+		return err
+	}
+	quota.Status.EffectiveQuotas = make(map[string]string)
+	for k, v := range resolveQuotas(entries, quota.Spec.Entity) {
+		quota.Status.EffectiveQuotas[k] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return nil
+}
+
+// getQuotaFallbacks returns the entities whose quotas apply to the clients of the entity in the order the brokers
+// look them up, the user or the client id left out of the entity stands for any one without quotas of its own
+func getQuotaFallbacks(entity kafkav1.QuotaEntity) []kafkav1.QuotaEntity {
+	users, clients := []string{kafkav1.QuotaEntityDefault}, []string{kafkav1.QuotaEntityDefault}
+	if entity.User != "" && entity.User != kafkav1.QuotaEntityDefault {
+		users = append([]string{entity.User}, users...)
+	}
+	if entity.ClientID != "" && entity.ClientID != kafkav1.QuotaEntityDefault {
+		clients = append([]string{entity.ClientID}, clients...)
+	}
+	fallbacks := make([]kafkav1.QuotaEntity, 0)
+	for _, user := range users {
+		for _, client := range clients {
+			fallbacks = append(fallbacks, kafkav1.QuotaEntity{User: user, ClientID: client})
+		}
+		fallbacks = append(fallbacks, kafkav1.QuotaEntity{User: user})
+	}
+	for _, client := range clients {
+		fallbacks = append(fallbacks, kafkav1.QuotaEntity{ClientID: client})
+	}
+	return fallbacks
+}
+
+// getQuotaEntityOf returns the entity of the components described by the brokers
+func getQuotaEntityOf(components []sarama.QuotaEntityComponent) kafkav1.QuotaEntity {
+	entity := kafkav1.QuotaEntity{}
+	for _, component := range components {
+		name := component.Name
+		if component.MatchType == sarama.QuotaMatchDefault {
+			name = kafkav1.QuotaEntityDefault
+		}
+		switch component.EntityType {
+		case sarama.QuotaEntityUser:
+			entity.User = name
+		case sarama.QuotaEntityClientID:
+			entity.ClientID = name
+		}
+	}
+	return entity
+}
+
+// resolveQuotas returns each quota applying to the clients of the entity from the first of its fallbacks setting it
+func resolveQuotas(entries []sarama.DescribeClientQuotasEntry, entity kafkav1.QuotaEntity) map[string]float64 {
+	values := make(map[kafkav1.QuotaEntity]map[string]float64)
+	for _, entry := range entries {
+		values[getQuotaEntityOf(entry.Entity)] = entry.Values
+	}
+	quotas := make(map[string]float64)
+	for _, fallback := range getQuotaFallbacks(entity) {
+		for k, v := range values[fallback] {
+			if _, ok := quotas[k]; !ok {
+				quotas[k] = v
+			}
+		}
+	}
+	return quotas
+}
+
+// finalizeQuota removes the owned quotas from the cluster
+func (r *KafkaQuotaReconciler) finalizeQuota(quota *kafkav1.KafkaQuota, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	if quota.Status.Entity == nil || len(quota.Status.OwnedQuotas) == 0 {
+		return nil
+	}
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	logger.Info(fmt.Sprintf("Removing the quotas %v of the entity %v", quota.Status.OwnedQuotas, *quota.Status.Entity))
+	return removeQuotas(admin, getQuotaEntity(*quota.Status.Entity), quota.Status.OwnedQuotas)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KafkaQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kafkav1.KafkaQuota{}).
+		Complete(r)
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+func TestResolveQuotas(t *testing.T) {
+	user := func(name string) sarama.QuotaEntityComponent {
+		return getQuotaEntityComponent(sarama.QuotaEntityUser, name)
+	}
+	clientID := func(name string) sarama.QuotaEntityComponent {
+		return getQuotaEntityComponent(sarama.QuotaEntityClientID, name)
+	}
+	entries := []sarama.DescribeClientQuotasEntry{
+		{Entity: []sarama.QuotaEntityComponent{user("alice")}, Values: map[string]float64{ProducerByteRateQuota: 1024}},
+		{Entity: []sarama.QuotaEntityComponent{user("alice"), clientID("app")}, Values: map[string]float64{RequestPercentageQuota: 12.5}},
+		{Entity: []sarama.QuotaEntityComponent{user(kafkav1.QuotaEntityDefault)}, Values: map[string]float64{
+			ProducerByteRateQuota: 512, ConsumerByteRateQuota: 2048,
+		}},
+		{Entity: []sarama.QuotaEntityComponent{clientID(kafkav1.QuotaEntityDefault)}, Values: map[string]float64{
+			ConsumerByteRateQuota: 4096, ControllerMutationRateQuota: 0.5,
+		}},
+	}
+	for _, tc := range []struct {
+		name     string
+		entity   kafkav1.QuotaEntity
+		expected map[string]float64
+	}{
+		{
+			name:   "user and client id",
+			entity: kafkav1.QuotaEntity{User: "alice", ClientID: "app"},
+			expected: map[string]float64{
+				RequestPercentageQuota: 12.5, ProducerByteRateQuota: 1024, ConsumerByteRateQuota: 2048, ControllerMutationRateQuota: 0.5,
+			},
+		},
+		{
+			name:   "user falling back to the default user",
+			entity: kafkav1.QuotaEntity{User: "bob"},
+			expected: map[string]float64{
+				ProducerByteRateQuota: 512, ConsumerByteRateQuota: 2048, ControllerMutationRateQuota: 0.5,
+			},
+		},
+		{
+			name:   "client id behind the default user",
+			entity: kafkav1.QuotaEntity{ClientID: "app"},
+			expected: map[string]float64{
+				ProducerByteRateQuota: 512, ConsumerByteRateQuota: 2048, ControllerMutationRateQuota: 0.5,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(resolveQuotas(entries, tc.entity)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestGetDesiredQuotas(t *testing.T) {
+	g := NewWithT(t)
+	percentage, rate := intstr.FromString("12.5"), intstr.FromInt32(2)
+	quota := &kafkav1.KafkaQuota{Spec: kafkav1.KafkaQuotaSpec{Quotas: kafkav1.Quotas{
+		RequestPercentage: &percentage, ControllerMutationRate: &rate,
+	}}}
+	desired, err := getDesiredQuotas(quota)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(desired).To(Equal(map[string]float64{RequestPercentageQuota: 12.5, ControllerMutationRateQuota: 2}))
+
+	rate = intstr.FromString("fast")
+	_, err = getDesiredQuotas(quota)
+	g.Expect(err).To(MatchError(ContainSubstring("invalid value fast")))
+}
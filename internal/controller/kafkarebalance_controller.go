@@ -0,0 +1,430 @@
+/*
+Copyright 2024 nineinfra.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+// KafkaRebalanceReconciler reconciles a KafkaRebalance object
+type KafkaRebalanceReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkarebalances,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkarebalances/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkarebalances/finalizers,verbs=update
+
+// Reconcile computes the proposal of the rebalance, and executes it once approved by the annotation
+func (r *KafkaRebalanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var rebalance kafkav1.KafkaRebalance
+	err := r.Get(ctx, req.NamespacedName, &rebalance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("Object not found, it could have been deleted")
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Error occurred during fetching the object")
+		return ctrl.Result{}, err
+	}
+
+	if !rebalance.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, finalizeClusterResource(r.Client, &rebalance, rebalance.Spec.ClusterRef, func(cluster *kafkav1.KafkaCluster) error {
+			return r.finalizeRebalance(&rebalance, cluster, logger)
+		})
+	}
+	if err = addFinalizer(r.Client, &rebalance); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	cluster, err := getClusterRef(r.Client, rebalance.Namespace, rebalance.Spec.ClusterRef)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{RequeueAfter: DefaultRequeueInterval},
+				r.updateRebalanceStatus(&rebalance, kafkav1.RebalanceStateNotReady, fmt.Sprintf("the cluster %s is not found", rebalance.Spec.ClusterRef))
+		}
+		return ctrl.Result{}, err
+	}
+	action := rebalance.Annotations[DefaultRebalanceAnnotation]
+	consumed, err := r.reconcileRebalance(&rebalance, cluster, action, logger)
+	if err != nil {
+		logger.Error(err, "Error occurred during reconciling the rebalance")
+		// the proposal and the replicas being moved are kept, so an approval never applies an unreviewed proposal
+		state := rebalance.Status.State
+		if state == "" {
+			state = kafkav1.RebalanceStateNotReady
+		}
+		if statusErr := r.updateRebalanceStatus(&rebalance, state, err.Error()); statusErr != nil {
+			logger.Error(statusErr, "Error occurred during updating the rebalance status")
+		}
+		return ctrl.Result{}, err
+	}
+	if err = r.Status().Update(context.TODO(), &rebalance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if consumed {
+		// an annotation changed since it was read is left for the next reconciliation
+		patch := client.MergeFromWithOptions(rebalance.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		delete(rebalance.Annotations, DefaultRebalanceAnnotation)
+		if err = r.Patch(context.TODO(), &rebalance, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	// an approved proposal waits for the reassignments of the others
+	if rebalance.Status.State == kafkav1.RebalanceStateRebalancing || rebalance.Status.State == kafkav1.RebalanceStatePendingProposal ||
+		rebalance.Status.State == kafkav1.RebalanceStateProposalReady && action == RebalanceActionApprove && !consumed {
+		return ctrl.Result{RequeueAfter: DefaultRequeueInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *KafkaRebalanceReconciler) updateRebalanceStatus(rebalance *kafkav1.KafkaRebalance, state kafkav1.RebalanceState, message string) error {
+	rebalance.Status.State = state
+	rebalance.Status.Message = message
+	return r.Status().Update(context.TODO(), rebalance)
+}
+
+func getRebalanceThrottleRate(rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster) int64 {
+	if rebalance.Spec.ThrottleRate > 0 {
+		return rebalance.Spec.ThrottleRate
+	}
+	return getReassignmentThrottleRate(cluster)
+}
+
+// reconcileRebalance moves the rebalance through its states, it returns true if the action of the annotation is done.
+//
+//	ProposalReady -approve-> Rebalancing -> Ready
+//	Rebalancing -stop-> Stopped
+//	any state but Rebalancing -refresh-> ProposalReady
+//
+// the annotation is dropped whenever the proposal is computed, so an approval only applies the proposal it was given
+// for. the rebalances of a cluster running a cruise control are driven by the cruise control instead
+func (r *KafkaRebalanceReconciler) reconcileRebalance(rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster, action string, logger logr.Logger) (bool, error) {
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return false, err
+	}
+	defer admin.Close()
+	if isCruiseControlRebalance(rebalance, cluster) {
+		return r.reconcileCruiseControlRebalance(admin, rebalance, cluster, action, logger)
+	}
+
+	status := &rebalance.Status
+	switch {
+	case status.State == kafkav1.RebalanceStateRebalancing && action == RebalanceActionStop:
+		return true, r.stopRebalance(admin, rebalance, cluster, logger)
+	case status.State == kafkav1.RebalanceStateRebalancing:
+		return false, r.reconcileRebalanceProgress(admin, rebalance, cluster, logger)
+	case status.State == "" || status.State == kafkav1.RebalanceStateNotReady ||
+		status.ObservedGeneration != rebalance.Generation || action == RebalanceActionRefresh:
+		return action != "", r.computeProposal(admin, rebalance, cluster, logger)
+	case status.State == kafkav1.RebalanceStateProposalReady && action == RebalanceActionApprove:
+		return r.executeProposal(admin, rebalance, cluster, logger)
+	}
+	return false, nil
+}
+
+// getRebalanceBrokers returns the brokers in the spec, or all the brokers of the cluster
+func getRebalanceBrokers(rebalance *kafkav1.KafkaRebalance, brokers []*sarama.Broker) ([]*sarama.Broker, error) {
+	if len(rebalance.Spec.Brokers) == 0 {
+		return brokers, nil
+	}
+	selected := make([]*sarama.Broker, 0, len(rebalance.Spec.Brokers))
+	for _, id := range rebalance.Spec.Brokers {
+		found := false
+		for _, b := range brokers {
+			if b.ID() == id {
+				selected = append(selected, b)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("the broker %d is not found in the cluster", id)
+		}
+	}
+	return selected, nil
+}
+
+// computeProposal plans the partition movements balancing the brokers and estimates the data copied by them
+func (r *KafkaRebalanceReconciler) computeProposal(admin sarama.ClusterAdmin, rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	brokers, _, err := admin.DescribeCluster()
+	if err != nil {
+		return err
+	}
+	selected, err := getRebalanceBrokers(rebalance, brokers)
+	if err != nil {
+		return err
+	}
+	topics, err := describeAllTopics(admin)
+	if err != nil {
+		return err
+	}
+	loadType := rebalance.Spec.BalanceBy
+	if loadType == "" {
+		loadType = kafkav1.RebalanceLoadPartitions
+	}
+	var proposal *kafkav1.RebalanceProposal
+	if rebalance.Spec.IntraBroker {
+		proposal, err = getLogDirRebalanceProposal(admin, topics, selected, loadType)
+	} else {
+		proposal, err = getBrokerRebalanceProposal(admin, topics, brokers, selected, loadType)
+	}
+	if err != nil {
+		return err
+	}
+	rebalance.Status = kafkav1.KafkaRebalanceStatus{
+		Proposal:           proposal,
+		ObservedGeneration: rebalance.Generation,
+	}
+	if proposal.PartitionMovements == 0 {
+		rebalance.Status.State = kafkav1.RebalanceStateReady
+		rebalance.Status.Message = "the brokers are balanced, nothing to move"
+		return nil
+	}
+	logger.Info(fmt.Sprintf("Proposed moving %d partitions with %d bytes", proposal.PartitionMovements, proposal.DataToMoveBytes))
+	rebalance.Status.State = kafkav1.RebalanceStateProposalReady
+	rebalance.Status.Message = fmt.Sprintf("annotate %s=%s to move %d partitions", DefaultRebalanceAnnotation, RebalanceActionApprove, proposal.PartitionMovements)
+	return nil
+}
+
+// getBrokerRebalanceProposal plans the partition movements balancing the load across the brokers
+func getBrokerRebalanceProposal(admin sarama.ClusterAdmin, topics []*sarama.TopicMetadata, brokers []*sarama.Broker,
+	selected []*sarama.Broker, loadType kafkav1.RebalanceLoadType) (*kafkav1.RebalanceProposal, error) {
+	sizes, err := describePartitionSizes(admin, brokers)
+	if err != nil {
+		return nil, err
+	}
+	plans, before, after := planRebalance(topics, selected, getReplicaWeight(loadType, sizes))
+
+	proposal := &kafkav1.RebalanceProposal{
+		Plan:         getRebalancePlanStatus(before, after),
+		CreationTime: time.Now().Format(time.RFC3339),
+	}
+	for _, t := range topics {
+		plan, ok := plans[t.Name]
+		if !ok {
+			continue
+		}
+		current := getCurrentAssignment(t)
+		for _, id := range sortedPartitionIDs(plan) {
+			if equalReplicas(current[id], plan[id]) {
+				continue
+			}
+			proposal.Movements = append(proposal.Movements, kafkav1.PartitionMovement{
+				Topic: t.Name, Partition: id, From: current[id], To: plan[id],
+			})
+			proposal.PartitionMovements++
+			for _, b := range plan[id] {
+				if !containsBroker(current[id], b) {
+					proposal.ReplicaMovements++
+					proposal.DataToMoveBytes += sizes[replicaRef{topic: t.Name, partition: id}]
+				}
+			}
+		}
+	}
+	return proposal, nil
+}
+
+// getLogDirRebalanceProposal plans the movements balancing the load across the disks of each broker, the plan is
+// the load of each disk
+func getLogDirRebalanceProposal(admin sarama.ClusterAdmin, topics []*sarama.TopicMetadata, selected []*sarama.Broker,
+	loadType kafkav1.RebalanceLoadType) (*kafkav1.RebalanceProposal, error) {
+	ids := make([]int32, 0, len(selected))
+	for _, b := range selected {
+		ids = append(ids, b.ID())
+	}
+	logDirs, err := admin.DescribeLogDirs(ids)
+	if err != nil {
+		return nil, err
+	}
+	movements, plan, moved := planLogDirRebalance(topics, logDirs, ids, loadType == kafkav1.RebalanceLoadSize)
+	proposal := &kafkav1.RebalanceProposal{
+		PartitionMovements: int32(len(movements)),
+		DataToMoveBytes:    moved,
+		Plan:               plan,
+		CreationTime:       time.Now().Format(time.RFC3339),
+	}
+	if len(movements) > 0 {
+		proposal.Movements = movements
+	}
+	for _, m := range movements {
+		for i := range m.To {
+			if getMovedLogDir(m, i) != "" {
+				proposal.ReplicaMovements++
+			}
+		}
+	}
+	return proposal, nil
+}
+
+// getProposalPlans returns the assignments of the topics of the proposal, nil if the replicas have changed since the proposal
+func getProposalPlans(proposal *kafkav1.RebalanceProposal, topics []*sarama.TopicMetadata) map[string]replicaAssignment {
+	currents := make(map[string]replicaAssignment)
+	for _, t := range topics {
+		currents[t.Name] = getCurrentAssignment(t)
+	}
+	plans := make(map[string]replicaAssignment)
+	for _, m := range proposal.Movements {
+		current, ok := currents[m.Topic]
+		if !ok || !equalReplicas(current[m.Partition], m.From) {
+			return nil
+		}
+		if _, ok = plans[m.Topic]; !ok {
+			plans[m.Topic] = make(replicaAssignment)
+			for id, replicas := range current {
+				plans[m.Topic][id] = replicas
+			}
+		}
+		plans[m.Topic][m.Partition] = m.To
+	}
+	return plans
+}
+
+// executeProposal submits the movements of the approved proposal, a proposal outdated by the changes of the
+// replicas is computed again and needs to be approved again
+func (r *KafkaRebalanceReconciler) executeProposal(admin sarama.ClusterAdmin, rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster, logger logr.Logger) (bool, error) {
+	topics, err := describeAllTopics(admin)
+	if err != nil {
+		return false, err
+	}
+	if getProposalPlans(rebalance.Status.Proposal, topics) == nil {
+		logger.Info("The replicas have changed since the proposal, computing the proposal again")
+		if err = r.computeProposal(admin, rebalance, cluster, logger); err != nil {
+			return false, err
+		}
+		rebalance.Status.Message = "the replicas have changed since the proposal, " + rebalance.Status.Message
+		return true, nil
+	}
+	logger.Info(fmt.Sprintf("Moving the replicas of %d partitions", rebalance.Status.Proposal.PartitionMovements))
+	rebalance.Status.State = kafkav1.RebalanceStateRebalancing
+	rebalance.Status.Message = ""
+	rebalance.Status.StartTime = time.Now().Format(time.RFC3339)
+	startReassignment(&rebalance.Status.ReassignmentStatus, rebalance.Status.Proposal.Movements, getRebalanceThrottleRate(rebalance, cluster))
+	// the movements are recorded, so the approval is done even if the first batch fails, which is retried by the progress
+	if _, err = newReassignmentEngine(r.Client, admin, cluster, rebalance).reconcile(&rebalance.Status.ReassignmentStatus, logger); err != nil {
+		logger.Error(err, "Error occurred during moving the replicas of the proposal")
+		rebalance.Status.Message = err.Error()
+	}
+	return true, nil
+}
+
+// countMovedPartitions returns the num of the partitions of the proposal whose replicas match the proposal, the
+// replicas moved between the disks are on their log dirs too
+func countMovedPartitions(proposal *kafkav1.RebalanceProposal, topics []*sarama.TopicMetadata, states map[replicaLogDir]logDirState) int32 {
+	currents := make(map[string]replicaAssignment)
+	for _, t := range topics {
+		currents[t.Name] = getCurrentAssignment(t)
+	}
+	moved := int32(0)
+	for _, m := range proposal.Movements {
+		if equalReplicas(currents[m.Topic][m.Partition], m.To) && isLogDirMoved(m, states) {
+			moved++
+		}
+	}
+	return moved
+}
+
+// reconcileRebalanceProgress moves the replicas of the proposal in batches, the proposal is done once no movement is left
+func (r *KafkaRebalanceReconciler) reconcileRebalanceProgress(admin sarama.ClusterAdmin, rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	done, err := newReassignmentEngine(r.Client, admin, cluster, rebalance).reconcile(&rebalance.Status.ReassignmentStatus, logger)
+	if err != nil {
+		return err
+	}
+	topics, err := describeAllTopics(admin)
+	if err != nil {
+		return err
+	}
+	proposal := rebalance.Status.Proposal
+	states, err := describeReplicaLogDirs(admin, getLogDirBrokers(proposal.Movements))
+	if err != nil {
+		return err
+	}
+	rebalance.Status.CompletedPartitions = countMovedPartitions(proposal, topics, states)
+	if !done {
+		return nil
+	}
+	rebalance.Status.CompletionTime = time.Now().Format(time.RFC3339)
+	if rebalance.Status.CompletedPartitions < proposal.PartitionMovements {
+		rebalance.Status.State = kafkav1.RebalanceStateNotReady
+		rebalance.Status.Message = fmt.Sprintf("only %d of %d partitions have been moved", rebalance.Status.CompletedPartitions, proposal.PartitionMovements)
+		return nil
+	}
+	logger.Info(fmt.Sprintf("Moved the replicas of %d partitions", proposal.PartitionMovements))
+	rebalance.Status.State = kafkav1.RebalanceStateReady
+	rebalance.Status.Message = ""
+	return nil
+}
+
+// stopRebalance cancels the movements in progress, the partitions already moved are kept
+func (r *KafkaRebalanceReconciler) stopRebalance(admin sarama.ClusterAdmin, rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	logger.Info("Stopping the rebalance")
+	if err := newReassignmentEngine(r.Client, admin, cluster, rebalance).cancel(&rebalance.Status.ReassignmentStatus); err != nil {
+		return err
+	}
+	rebalance.Status.State = kafkav1.RebalanceStateStopped
+	rebalance.Status.Message = fmt.Sprintf("stopped after %d of %d partitions have been moved",
+		rebalance.Status.CompletedPartitions, rebalance.Status.Proposal.PartitionMovements)
+	rebalance.Status.CompletionTime = time.Now().Format(time.RFC3339)
+	return nil
+}
+
+// finalizeRebalance stops the rebalance in progress
+func (r *KafkaRebalanceReconciler) finalizeRebalance(rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	if rebalance.Status.State != kafkav1.RebalanceStateRebalancing {
+		return nil
+	}
+	if rebalance.Status.UserTaskID != "" {
+		// the execution of a removed cruise control is gone with it
+		if !IsCruiseControlEnabled(cluster) {
+			return nil
+		}
+		cc, err := getCruiseControlClient(r.Client, cluster)
+		if err != nil {
+			return err
+		}
+		return r.stopCruiseControlRebalance(cc, rebalance, logger)
+	}
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	return r.stopRebalance(admin, rebalance, cluster, logger)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KafkaRebalanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kafkav1.KafkaRebalance{}).
+		Complete(r)
+}
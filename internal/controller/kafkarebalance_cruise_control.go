@@ -0,0 +1,286 @@
+package controller
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+// isCruiseControlRebalance returns true if the rebalance is driven by the cruise control. the replicas being moved
+// by the operator keep being tracked by it even after the cruise control is enabled, and vice versa. the rebalances
+// between the disks of the brokers are always driven by the operator
+func isCruiseControlRebalance(rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster) bool {
+	if rebalance.Status.State == kafkav1.RebalanceStateRebalancing {
+		return rebalance.Status.UserTaskID != ""
+	}
+	return IsCruiseControlEnabled(cluster) && !rebalance.Spec.IntraBroker
+}
+
+func getCruiseControlRebalanceParams(rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster, dryRun bool) url.Values {
+	params := url.Values{}
+	params.Set("dryrun", strconv.FormatBool(dryRun))
+	params.Set("verbose", "true")
+	if len(rebalance.Spec.Goals) > 0 {
+		params.Set("goals", strings.Join(rebalance.Spec.Goals, ","))
+	}
+	if len(rebalance.Spec.Brokers) > 0 {
+		brokers := make([]string, 0, len(rebalance.Spec.Brokers))
+		for _, id := range rebalance.Spec.Brokers {
+			brokers = append(brokers, strconv.Itoa(int(id)))
+		}
+		params.Set("destination_broker_ids", strings.Join(brokers, ","))
+	}
+	if !dryRun {
+		params.Set("replication_throttle", strconv.FormatInt(getRebalanceThrottleRate(rebalance, cluster), 10))
+	}
+	return params
+}
+
+// getCruiseControlProposal converts the result of the cruise control, the plan is the num of the replicas of each broker
+func getCruiseControlProposal(result *cruiseControlOptimizationResult) *kafkav1.RebalanceProposal {
+	proposal := &kafkav1.RebalanceProposal{
+		ReplicaMovements: result.Summary.NumReplicaMovements,
+		DataToMoveBytes:  int64(result.Summary.DataToMoveMB * 1024 * 1024),
+		CreationTime:     time.Now().Format(time.RFC3339),
+	}
+	for _, p := range result.Proposals {
+		proposal.Movements = append(proposal.Movements, kafkav1.PartitionMovement{
+			Topic:     p.TopicPartition.Topic,
+			Partition: p.TopicPartition.Partition,
+			From:      p.OldReplicas,
+			To:        p.NewReplicas,
+		})
+	}
+	sort.Slice(proposal.Movements, func(i, j int) bool {
+		a, b := proposal.Movements[i], proposal.Movements[j]
+		return a.Topic < b.Topic || a.Topic == b.Topic && a.Partition < b.Partition
+	})
+	proposal.PartitionMovements = int32(len(proposal.Movements))
+	if result.LoadBeforeOptimization != nil && result.LoadAfterOptimization != nil {
+		before, after := make(map[int32]int64), make(map[int32]int64)
+		for _, b := range result.LoadBeforeOptimization.Brokers {
+			before[b.Broker] = b.Replicas
+		}
+		for _, b := range result.LoadAfterOptimization.Brokers {
+			after[b.Broker] = b.Replicas
+		}
+		proposal.Plan = getRebalancePlanStatus(before, after)
+	}
+	return proposal
+}
+
+// reconcileCruiseControlRebalance moves the rebalance through the same states as reconcileRebalance, while the
+// proposal is computed and executed by the cruise control. the proposal may be pending while it is computed
+//
+//	PendingProposal -> ProposalReady -approve-> Rebalancing -> Ready
+func (r *KafkaRebalanceReconciler) reconcileCruiseControlRebalance(admin sarama.ClusterAdmin, rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster, action string, logger logr.Logger) (bool, error) {
+	status := &rebalance.Status
+	if !IsCruiseControlEnabled(cluster) {
+		// the replicas are not moved anymore once the cruise control is removed
+		status.State = kafkav1.RebalanceStateStopped
+		status.Message = "the cruise control has been removed"
+		status.UserTaskID = ""
+		status.CompletionTime = time.Now().Format(time.RFC3339)
+		return false, nil
+	}
+	cc, err := getCruiseControlClient(r.Client, cluster)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case status.State == kafkav1.RebalanceStateRebalancing && action == RebalanceActionStop:
+		return true, r.stopCruiseControlRebalance(cc, rebalance, logger)
+	case status.State == kafkav1.RebalanceStateRebalancing:
+		return false, r.reconcileCruiseControlProgress(admin, cc, rebalance, cluster, logger)
+	case status.State == "" || status.State == kafkav1.RebalanceStateNotReady ||
+		status.ObservedGeneration != rebalance.Generation || action == RebalanceActionRefresh:
+		return action != "", r.computeCruiseControlProposal(cc, rebalance, cluster, "", logger)
+	case status.State == kafkav1.RebalanceStatePendingProposal:
+		return action != "", r.computeCruiseControlProposal(cc, rebalance, cluster, status.UserTaskID, logger)
+	case status.State == kafkav1.RebalanceStateProposalReady && action == RebalanceActionApprove:
+		return r.executeCruiseControlProposal(admin, cc, rebalance, cluster, logger)
+	}
+	return false, nil
+}
+
+// computeCruiseControlProposal asks the cruise control for the proposal, or for the result of the task computing it
+func (r *KafkaRebalanceReconciler) computeCruiseControlProposal(cc *cruiseControlClient, rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster, userTaskID string, logger logr.Logger) error {
+	result, taskID, err := cc.rebalance(getCruiseControlRebalanceParams(rebalance, cluster, true), userTaskID)
+	if err != nil {
+		// the task may be gone with a restart of the cruise control, so the proposal is computed again
+		rebalance.Status.State = kafkav1.RebalanceStateNotReady
+		rebalance.Status.UserTaskID = ""
+		return err
+	}
+	rebalance.Status = kafkav1.KafkaRebalanceStatus{
+		ObservedGeneration: rebalance.Generation,
+	}
+	if result == nil {
+		rebalance.Status.State = kafkav1.RebalanceStatePendingProposal
+		rebalance.Status.Message = "the cruise control is computing the proposal"
+		rebalance.Status.UserTaskID = taskID
+		return nil
+	}
+	proposal := getCruiseControlProposal(result)
+	rebalance.Status.Proposal = proposal
+	if proposal.PartitionMovements == 0 {
+		rebalance.Status.State = kafkav1.RebalanceStateReady
+		rebalance.Status.Message = "the brokers are balanced, nothing to move"
+		return nil
+	}
+	logger.Info(fmt.Sprintf("The cruise control proposed moving %d partitions with %d bytes", proposal.PartitionMovements, proposal.DataToMoveBytes))
+	rebalance.Status.State = kafkav1.RebalanceStateProposalReady
+	rebalance.Status.Message = fmt.Sprintf("annotate %s=%s to move %d partitions", DefaultRebalanceAnnotation, RebalanceActionApprove, proposal.PartitionMovements)
+	return nil
+}
+
+// executeCruiseControlProposal asks the cruise control to execute the approved proposal, a proposal outdated by the
+// changes of the replicas is computed again and needs to be approved again. the cruise control computes the executed
+// proposal again, so it is verified against the approved one once it is known
+func (r *KafkaRebalanceReconciler) executeCruiseControlProposal(admin sarama.ClusterAdmin, cc *cruiseControlClient, rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster, logger logr.Logger) (bool, error) {
+	topics, err := describeAllTopics(admin)
+	if err != nil {
+		return false, err
+	}
+	if getProposalPlans(rebalance.Status.Proposal, topics) == nil {
+		logger.Info("The replicas have changed since the proposal, computing the proposal again")
+		if err = r.computeCruiseControlProposal(cc, rebalance, cluster, "", logger); err != nil {
+			return false, err
+		}
+		rebalance.Status.Message = "the replicas have changed since the proposal, " + rebalance.Status.Message
+		return true, nil
+	}
+	result, taskID, err := cc.rebalance(getCruiseControlRebalanceParams(rebalance, cluster, false), "")
+	if err != nil {
+		return false, err
+	}
+	logger.Info(fmt.Sprintf("The cruise control is moving the replicas of the proposal in the task %s", taskID))
+	rebalance.Status.State = kafkav1.RebalanceStateRebalancing
+	rebalance.Status.Message = ""
+	rebalance.Status.UserTaskID = taskID
+	rebalance.Status.CompletedPartitions = 0
+	rebalance.Status.StartTime = time.Now().Format(time.RFC3339)
+	if result != nil {
+		return true, r.verifyCruiseControlExecution(cc, rebalance, result, logger)
+	}
+	return true, nil
+}
+
+// verifyCruiseControlExecution stops the execution of a proposal differing from the approved one, which happens if the
+// load changed since the approval. the executed proposal is ready to be approved instead
+func (r *KafkaRebalanceReconciler) verifyCruiseControlExecution(cc *cruiseControlClient, rebalance *kafkav1.KafkaRebalance, result *cruiseControlOptimizationResult, logger logr.Logger) error {
+	executed := getCruiseControlProposal(result)
+	if isSameProposal(rebalance.Status.Proposal, executed) {
+		return nil
+	}
+	logger.Info("The cruise control is executing a proposal differing from the approved one, stopping it")
+	if err := cc.stopProposalExecution(); err != nil {
+		return err
+	}
+	// the partitions moved before the stop outdate the proposal, which is then computed again on the approval
+	rebalance.Status = kafkav1.KafkaRebalanceStatus{
+		ObservedGeneration: rebalance.Status.ObservedGeneration,
+		State:              kafkav1.RebalanceStateProposalReady,
+		Proposal:           executed,
+		Message: fmt.Sprintf("the cruise control proposed other movements on the execution, annotate %s=%s to move %d partitions",
+			DefaultRebalanceAnnotation, RebalanceActionApprove, executed.PartitionMovements),
+	}
+	return nil
+}
+
+// isSameProposal returns true if the proposals move the same partitions onto the same replicas
+func isSameProposal(a *kafkav1.RebalanceProposal, b *kafkav1.RebalanceProposal) bool {
+	if a == nil || b == nil || len(a.Movements) != len(b.Movements) {
+		return false
+	}
+	for i := range a.Movements {
+		if a.Movements[i].Topic != b.Movements[i].Topic || a.Movements[i].Partition != b.Movements[i].Partition ||
+			!equalReplicas(a.Movements[i].To, b.Movements[i].To) {
+			return false
+		}
+	}
+	return true
+}
+
+// reconcileCruiseControlProgress updates the progress of the movements from the task of the cruise control. a task
+// forgotten by a restart of the cruise control is done once the executor is idle
+func (r *KafkaRebalanceReconciler) reconcileCruiseControlProgress(admin sarama.ClusterAdmin, cc *cruiseControlClient, rebalance *kafkav1.KafkaRebalance, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	task, err := cc.userTask(rebalance.Status.UserTaskID)
+	if err != nil {
+		return err
+	}
+	if task != nil && task.Status == UserTaskInExecution {
+		// the executed proposal is only known once the cruise control computed it
+		result, _, err := cc.rebalance(getCruiseControlRebalanceParams(rebalance, cluster, false), rebalance.Status.UserTaskID)
+		if err != nil {
+			return err
+		}
+		if result != nil {
+			if err = r.verifyCruiseControlExecution(cc, rebalance, result, logger); err != nil {
+				return err
+			}
+			if rebalance.Status.State != kafkav1.RebalanceStateRebalancing {
+				return nil
+			}
+		}
+	}
+	topics, err := describeAllTopics(admin)
+	if err != nil {
+		return err
+	}
+	proposal := rebalance.Status.Proposal
+	rebalance.Status.CompletedPartitions = countMovedPartitions(proposal, topics, nil)
+	taskStatus := ""
+	if task != nil {
+		taskStatus = task.Status
+	} else {
+		state, err := cc.state()
+		if err != nil {
+			return err
+		}
+		if state.ExecutorState.State != ExecutorNoTaskInProgress {
+			return nil
+		}
+	}
+	switch taskStatus {
+	case UserTaskActive, UserTaskInExecution:
+		return nil
+	case UserTaskCompletedWithError:
+		rebalance.Status.State = kafkav1.RebalanceStateNotReady
+		rebalance.Status.Message = fmt.Sprintf("the task %s of the cruise control failed after %d of %d partitions have been moved",
+			rebalance.Status.UserTaskID, rebalance.Status.CompletedPartitions, proposal.PartitionMovements)
+	default:
+		if rebalance.Status.CompletedPartitions < proposal.PartitionMovements {
+			rebalance.Status.State = kafkav1.RebalanceStateNotReady
+			rebalance.Status.Message = fmt.Sprintf("only %d of %d partitions have been moved", rebalance.Status.CompletedPartitions, proposal.PartitionMovements)
+		} else {
+			logger.Info(fmt.Sprintf("The cruise control moved the replicas of %d partitions", proposal.PartitionMovements))
+			rebalance.Status.State = kafkav1.RebalanceStateReady
+			rebalance.Status.Message = ""
+		}
+	}
+	rebalance.Status.UserTaskID = ""
+	rebalance.Status.CompletionTime = time.Now().Format(time.RFC3339)
+	return nil
+}
+
+// stopCruiseControlRebalance stops the execution of the cruise control, the partitions already moved are kept
+func (r *KafkaRebalanceReconciler) stopCruiseControlRebalance(cc *cruiseControlClient, rebalance *kafkav1.KafkaRebalance, logger logr.Logger) error {
+	logger.Info("Stopping the rebalance of the cruise control")
+	if err := cc.stopProposalExecution(); err != nil {
+		return err
+	}
+	rebalance.Status.State = kafkav1.RebalanceStateStopped
+	rebalance.Status.Message = fmt.Sprintf("stopped after %d of %d partitions have been moved",
+		rebalance.Status.CompletedPartitions, rebalance.Status.Proposal.PartitionMovements)
+	rebalance.Status.UserTaskID = ""
+	rebalance.Status.CompletionTime = time.Now().Format(time.RFC3339)
+	return nil
+}
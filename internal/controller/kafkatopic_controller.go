@@ -0,0 +1,400 @@
+/*
+Copyright 2024 nineinfra.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+// KafkaTopicReconciler reconciles a KafkaTopic object
+type KafkaTopicReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkatopics,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkatopics/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkatopics/finalizers,verbs=update
+
+// Reconcile creates the topic in the cluster and moves the topic towards the spec
+func (r *KafkaTopicReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var topic kafkav1.KafkaTopic
+	err := r.Get(ctx, req.NamespacedName, &topic)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("Object not found, it could have been deleted")
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Error occurred during fetching the object")
+		return ctrl.Result{}, err
+	}
+
+	if !topic.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, finalizeClusterResource(r.Client, &topic, topic.Spec.ClusterRef, func(cluster *kafkav1.KafkaCluster) error {
+			return r.finalizeTopic(&topic, cluster, logger)
+		})
+	}
+	if err = addFinalizer(r.Client, &topic); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	cluster, err := getClusterRef(r.Client, topic.Namespace, topic.Spec.ClusterRef)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{RequeueAfter: DefaultRequeueInterval},
+				r.updateTopicStatus(&topic, false, fmt.Sprintf("the cluster %s is not found", topic.Spec.ClusterRef))
+		}
+		return ctrl.Result{}, err
+	}
+	rejected, err := r.reconcileTopic(&topic, cluster, logger)
+	if err != nil {
+		logger.Error(err, "Error occurred during reconciling the topic")
+		if statusErr := r.updateTopicStatus(&topic, false, err.Error()); statusErr != nil {
+			logger.Error(statusErr, "Error occurred during updating the topic status")
+		}
+		return ctrl.Result{}, err
+	}
+	// a rejected spec is not retried until the spec changes
+	if rejected != "" {
+		logger.Info(rejected)
+		return ctrl.Result{}, r.updateTopicStatus(&topic, false, rejected)
+	}
+	if reassignment := topic.Status.Reassignment; reassignment != nil {
+		return ctrl.Result{RequeueAfter: DefaultRequeueInterval}, r.updateTopicStatus(&topic, false,
+			fmt.Sprintf("changing the replication factor to %d, %d of %d partitions completed",
+				reassignment.ReplicationFactor, reassignment.CompletedPartitions, reassignment.Partitions))
+	}
+	return ctrl.Result{}, r.updateTopicStatus(&topic, true, "")
+}
+
+func (r *KafkaTopicReconciler) updateTopicStatus(topic *kafkav1.KafkaTopic, ready bool, message string) error {
+	topic.Status.TopicName = GetTopicName(topic)
+	topic.Status.Ready = ready
+	topic.Status.Message = message
+	if ready {
+		topic.Status.ObservedGeneration = topic.Generation
+	}
+	return r.Status().Update(context.TODO(), topic)
+}
+
+func GetTopicName(topic *kafkav1.KafkaTopic) string {
+	if topic.Spec.TopicName != "" {
+		return topic.Spec.TopicName
+	}
+	return topic.Name
+}
+
+// getTopicPartitions returns the partitions in the spec, or num.partitions of the cluster
+func getTopicPartitions(topic *kafkav1.KafkaTopic, cluster *kafkav1.KafkaCluster) int32 {
+	if topic.Spec.Partitions > 0 {
+		return topic.Spec.Partitions
+	}
+	partitions, err := strconv.Atoi(getClusterConfigValue(cluster, "num.partitions", strconv.Itoa(DefaultPartitionsPerTopic)))
+	if err != nil {
+		return DefaultPartitionsPerTopic
+	}
+	return int32(partitions)
+}
+
+// getTopicReplicationFactor returns the replication factor in the spec, or default.replication.factor of the cluster
+func getTopicReplicationFactor(topic *kafkav1.KafkaTopic, cluster *kafkav1.KafkaCluster) int16 {
+	if topic.Spec.ReplicationFactor > 0 {
+		return int16(topic.Spec.ReplicationFactor)
+	}
+	factor, err := strconv.Atoi(getClusterConfigValue(cluster, "default.replication.factor", strconv.Itoa(DefaultReplicationFactorPerTopic)))
+	if err != nil {
+		return DefaultReplicationFactorPerTopic
+	}
+	return int16(factor)
+}
+
+// describeTopic returns the metadata of the topic, nil if the topic does not exist
+func describeTopic(admin sarama.ClusterAdmin, name string) (*sarama.TopicMetadata, error) {
+	metadata, err := admin.DescribeTopics([]string{name})
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata) == 0 || metadata[0].Err == sarama.ErrUnknownTopicOrPartition {
+		return nil, nil
+	}
+	if metadata[0].Err != sarama.ErrNoError {
+		return nil, metadata[0].Err
+	}
+	return metadata[0], nil
+}
+
+func getTopicReplicationFactorOf(metadata *sarama.TopicMetadata) int32 {
+	if len(metadata.Partitions) == 0 {
+		return 0
+	}
+	return int32(len(metadata.Partitions[0].Replicas))
+}
+
+// describeTopicConfig returns the configs of the topic overriding the ones of the cluster
+func describeTopicConfig(admin sarama.ClusterAdmin, name string) (map[string]string, error) {
+	entries, err := admin.DescribeConfig(sarama.ConfigResource{Type: sarama.TopicResource, Name: name})
+	if err != nil {
+		return nil, err
+	}
+	config := make(map[string]string)
+	for _, entry := range entries {
+		if entry.Source == sarama.SourceTopic && !isThrottleConfig(entry.Name) {
+			config[entry.Name] = entry.Value
+		}
+	}
+	return config, nil
+}
+
+// reconcileTopicConfig sets the configs in the spec and deletes the other overrides of the topic
+func reconcileTopicConfig(admin sarama.ClusterAdmin, name string, desired map[string]string, logger logr.Logger) (map[string]string, error) {
+	exists, err := describeTopicConfig(admin, name)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]sarama.IncrementalAlterConfigsEntry)
+	for k, v := range desired {
+		if value, ok := exists[k]; !ok || value != v {
+			value := v
+			entries[k] = sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &value}
+		}
+	}
+	for k := range exists {
+		if _, ok := desired[k]; !ok && !isThrottleConfig(k) {
+			entries[k] = sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationDelete}
+		}
+	}
+	if len(entries) == 0 {
+		return exists, nil
+	}
+	logger.Info(fmt.Sprintf("Altering the configs %v of the topic %s", sortedKeys(entries), name))
+	if err = admin.IncrementalAlterConfig(sarama.TopicResource, name, entries, false); err != nil {
+		return nil, err
+	}
+	return desired, nil
+}
+
+// reconcileTopic creates the topic or moves it towards the spec, it returns the reason if the spec is rejected
+func (r *KafkaTopicReconciler) reconcileTopic(topic *kafkav1.KafkaTopic, cluster *kafkav1.KafkaCluster, logger logr.Logger) (string, error) {
+	name := GetTopicName(topic)
+	claimant, err := getTopicClaimant(r.Client, topic)
+	if err != nil {
+		return "", err
+	}
+	if claimant != nil {
+		return fmt.Sprintf("the topic %s is already claimed by the KafkaTopic %s", name, claimant.Name), nil
+	}
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return "", err
+	}
+	defer admin.Close()
+
+	metadata, err := describeTopic(admin, name)
+	if err != nil {
+		return "", err
+	}
+	if metadata == nil {
+		partitions, factor := getTopicPartitions(topic, cluster), getTopicReplicationFactor(topic, cluster)
+		configEntries := make(map[string]*string)
+		for k, v := range topic.Spec.Config {
+			value := v
+			configEntries[k] = &value
+		}
+		logger.Info(fmt.Sprintf("Creating the topic %s with %d partitions and replication factor %d", name, partitions, factor))
+		err = admin.CreateTopic(name, &sarama.TopicDetail{
+			NumPartitions:     partitions,
+			ReplicationFactor: factor,
+			ConfigEntries:     configEntries,
+		}, false)
+		if err != nil && !isKafkaError(err, sarama.ErrTopicAlreadyExists) {
+			return "", err
+		}
+		topic.Status.Partitions = partitions
+		topic.Status.ReplicationFactor = int32(factor)
+		topic.Status.Config = topic.Spec.Config
+		return "", nil
+	}
+
+	observedPartitions := int32(len(metadata.Partitions))
+	topic.Status.Partitions = observedPartitions
+	topic.Status.ReplicationFactor = getTopicReplicationFactorOf(metadata)
+	if topic.Status.Reassignment != nil {
+		return "", r.reconcileTopicReassignment(admin, topic, cluster, logger)
+	}
+	if topic.Spec.Partitions > 0 && topic.Spec.Partitions < observedPartitions {
+		return fmt.Sprintf("the partitions of the topic %s can not be decreased from %d to %d", name, observedPartitions, topic.Spec.Partitions), nil
+	}
+	if topic.Spec.ReplicationFactor > 0 && topic.Spec.ReplicationFactor != topic.Status.ReplicationFactor {
+		return r.changeTopicReplicationFactor(admin, topic, cluster, metadata, logger)
+	}
+	if topic.Spec.Partitions > observedPartitions {
+		logger.Info(fmt.Sprintf("Increasing the partitions of the topic %s from %d to %d", name, observedPartitions, topic.Spec.Partitions))
+		if err = admin.CreatePartitions(name, topic.Spec.Partitions, nil, false); err != nil {
+			return "", err
+		}
+		topic.Status.Partitions = topic.Spec.Partitions
+	}
+	config, err := reconcileTopicConfig(admin, name, topic.Spec.Config, logger)
+	if err != nil {
+		return "", err
+	}
+	topic.Status.Config = config
+	return "", nil
+}
+
+// changeTopicReplicationFactor starts moving the replicas of the topic to the replication factor in the spec
+func (r *KafkaTopicReconciler) changeTopicReplicationFactor(admin sarama.ClusterAdmin, topic *kafkav1.KafkaTopic, cluster *kafkav1.KafkaCluster, metadata *sarama.TopicMetadata, logger logr.Logger) (string, error) {
+	name := GetTopicName(topic)
+	brokers, _, err := admin.DescribeCluster()
+	if err != nil {
+		return "", err
+	}
+	if int(topic.Spec.ReplicationFactor) > len(brokers) {
+		return fmt.Sprintf("the replication factor %d of the topic %s is larger than the num of the brokers %d",
+			topic.Spec.ReplicationFactor, name, len(brokers)), nil
+	}
+	target, err := planReplicationFactor(metadata, brokers, int(topic.Spec.ReplicationFactor))
+	if err != nil {
+		return "", err
+	}
+	logger.Info(fmt.Sprintf("Changing the replication factor of the topic %s from %d to %d",
+		name, topic.Status.ReplicationFactor, topic.Spec.ReplicationFactor))
+	topic.Status.Reassignment = &kafkav1.TopicReassignmentStatus{
+		ReplicationFactor: topic.Spec.ReplicationFactor,
+		StartTime:         time.Now().Format(time.RFC3339),
+	}
+	movements := getPartitionMovements([]*sarama.TopicMetadata{metadata}, map[string]replicaAssignment{name: target})
+	startReassignment(&topic.Status.Reassignment.ReassignmentStatus, movements, getReassignmentThrottleRate(cluster))
+	return "", r.reconcileTopicReassignment(admin, topic, cluster, logger)
+}
+
+// reconcileTopicReassignment moves the replicas of the topic in batches and clears the throttles once completed
+func (r *KafkaTopicReconciler) reconcileTopicReassignment(admin sarama.ClusterAdmin, topic *kafkav1.KafkaTopic, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	reassignment := topic.Status.Reassignment
+	done, err := newReassignmentEngine(r.Client, admin, cluster, topic).reconcile(&reassignment.ReassignmentStatus, logger)
+	if err != nil || !done {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Changed the replication factor of the topic %s to %d", GetTopicName(topic), reassignment.ReplicationFactor))
+	topic.Status.Reassignment = nil
+	return nil
+}
+
+// finalizeTopic deletes the topic from the cluster unless the topic is retained or claimed by another KafkaTopic
+func (r *KafkaTopicReconciler) finalizeTopic(topic *kafkav1.KafkaTopic, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	retained := topic.Spec.DeletionPolicy == kafkav1.TopicDeletionPolicyRetain
+	if retained && topic.Status.Reassignment == nil {
+		return nil
+	}
+	claimant, err := getTopicClaimant(r.Client, topic)
+	if err != nil || claimant != nil {
+		return err
+	}
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	// the throttles of an unfinished reassignment are not left behind, the pending movements are dropped
+	if topic.Status.Reassignment != nil {
+		engine := newReassignmentEngine(r.Client, admin, cluster, topic)
+		if err = engine.clearThrottles(&topic.Status.Reassignment.ReassignmentStatus); err != nil {
+			return err
+		}
+	}
+	if !retained {
+		logger.Info(fmt.Sprintf("Deleting the topic %s", GetTopicName(topic)))
+		if err = admin.DeleteTopic(GetTopicName(topic)); err != nil && !isKafkaError(err, sarama.ErrUnknownTopicOrPartition) {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTopicClaimedBefore returns true if the topic claimed its name before the other one, the older one wins
+func isTopicClaimedBefore(topic *kafkav1.KafkaTopic, other *kafkav1.KafkaTopic) bool {
+	if !topic.CreationTimestamp.Equal(&other.CreationTimestamp) {
+		return topic.CreationTimestamp.Before(&other.CreationTimestamp)
+	}
+	return topic.Name < other.Name
+}
+
+// getTopicClaimant returns the KafkaTopic of the same cluster owning the name of the topic, nil if the topic owns it
+func getTopicClaimant(c client.Client, topic *kafkav1.KafkaTopic) (*kafkav1.KafkaTopic, error) {
+	topics := &kafkav1.KafkaTopicList{}
+	if err := c.List(context.TODO(), topics, client.InNamespace(topic.Namespace)); err != nil {
+		return nil, err
+	}
+	for i := range topics.Items {
+		other := &topics.Items[i]
+		if other.Name == topic.Name || other.Spec.ClusterRef != topic.Spec.ClusterRef || GetTopicName(other) != GetTopicName(topic) {
+			continue
+		}
+		if isTopicClaimedBefore(other, topic) {
+			return other, nil
+		}
+	}
+	return nil, nil
+}
+
+// getTopicsOfName maps a topic to the other topics of the same cluster and name, so the name passes to the next
+// claimant once the owner renames or releases it
+func (r *KafkaTopicReconciler) getTopicsOfName(ctx context.Context, obj client.Object) []reconcile.Request {
+	topic, ok := obj.(*kafkav1.KafkaTopic)
+	if !ok {
+		return nil
+	}
+	topics := &kafkav1.KafkaTopicList{}
+	if err := r.List(ctx, topics, client.InNamespace(topic.Namespace)); err != nil {
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for _, other := range topics.Items {
+		if other.Name != topic.Name && other.Spec.ClusterRef == topic.Spec.ClusterRef && other.Status.TopicName == GetTopicName(topic) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: other.Name, Namespace: other.Namespace},
+			})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KafkaTopicReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kafkav1.KafkaTopic{}).
+		Watches(&kafkav1.KafkaTopic{}, handler.EnqueueRequestsFromMapFunc(r.getTopicsOfName)).
+		Complete(r)
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+// TestReconcileTopicClaimedName rejects the topics resolving to the name of an older topic of the same cluster
+func TestReconcileTopicClaimedName(t *testing.T) {
+	g := NewWithT(t)
+	cluster := &kafkav1.KafkaCluster{ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"}}
+	newTopic := func(name string, topicName string, clusterRef string, created time.Time) *kafkav1.KafkaTopic {
+		return &kafkav1.KafkaTopic{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: metav1.NewTime(created)},
+			Spec:       kafkav1.KafkaTopicSpec{ClusterRef: clusterRef, TopicName: topicName},
+		}
+	}
+	now := time.Now().Truncate(time.Second)
+	owner := newTopic("orders", "", cluster.Name, now.Add(-time.Hour))
+	duplicate := newTopic("orders-copy", "orders", cluster.Name, now)
+	other := newTopic("orders-other", "orders", "other", now.Add(-2*time.Hour))
+	fake := newFakeReconciler(cluster, owner, duplicate, other)
+	r := &KafkaTopicReconciler{Client: fake.Client, Scheme: fake.Scheme}
+
+	rejected, err := r.reconcileTopic(duplicate, cluster, logr.Discard())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rejected).To(ContainSubstring("already claimed by the KafkaTopic orders"))
+
+	claimant, err := getTopicClaimant(r.Client, owner)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(claimant).To(BeNil())
+
+	// the duplicate releasing the name leaves the topic of the owner alone
+	g.Expect(r.finalizeTopic(duplicate, cluster, logr.Discard())).To(Succeed())
+}
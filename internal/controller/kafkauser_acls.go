@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+func getUserPrincipal(user *kafkav1.KafkaUser) string {
+	return "User:" + user.Name
+}
+
+// aclBinding is an acl of the principal on a resource
+type aclBinding struct {
+	resource sarama.Resource
+	acl      sarama.Acl
+}
+
+func (b aclBinding) String() string {
+	return fmt.Sprintf("%s:%s:%s:%s:%s:%s", b.resource.ResourceType.String(), b.resource.ResourceName,
+		b.resource.ResourcePatternType.String(), b.acl.Operation.String(), b.acl.PermissionType.String(), b.acl.Host)
+}
+
+// getDesiredAclBindings converts the acls in the spec of the user to the bindings of the principal of the user
+func getDesiredAclBindings(user *kafkav1.KafkaUser) (map[string]aclBinding, error) {
+	bindings := make(map[string]aclBinding)
+	if user.Spec.Authorization == nil {
+		return bindings, nil
+	}
+	for _, rule := range user.Spec.Authorization.Acls {
+		resource := sarama.Resource{
+			ResourceName:        rule.ResourceName,
+			ResourcePatternType: sarama.AclPatternLiteral,
+		}
+		if err := resource.ResourceType.UnmarshalText([]byte(rule.ResourceType)); err != nil {
+			return nil, err
+		}
+		if rule.ResourceType == kafkav1.AclResourceCluster {
+			resource.ResourceName = DefaultAclClusterResourceName
+		} else if rule.ResourceName == "" {
+			return nil, fmt.Errorf("the resource name of the %s acl is required", rule.ResourceType)
+		}
+		if rule.PatternType != "" {
+			if err := resource.ResourcePatternType.UnmarshalText([]byte(rule.PatternType)); err != nil {
+				return nil, err
+			}
+		}
+		host := rule.Host
+		if host == "" {
+			host = "*"
+		}
+		for _, operation := range rule.Operations {
+			acl := sarama.Acl{
+				Principal:      getUserPrincipal(user),
+				Host:           host,
+				PermissionType: sarama.AclPermissionAllow,
+			}
+			if err := acl.Operation.UnmarshalText([]byte(operation)); err != nil {
+				return nil, err
+			}
+			binding := aclBinding{resource: resource, acl: acl}
+			bindings[binding.String()] = binding
+		}
+	}
+	return bindings, nil
+}
+
+// listAclBindings returns the acls of the principal in the cluster
+func listAclBindings(admin sarama.ClusterAdmin, principal string) (map[string]aclBinding, error) {
+	resourceAcls, err := admin.ListAcls(sarama.AclFilter{
+		ResourceType:              sarama.AclResourceAny,
+		ResourcePatternTypeFilter: sarama.AclPatternAny,
+		Principal:                 &principal,
+		Operation:                 sarama.AclOperationAny,
+		PermissionType:            sarama.AclPermissionAny,
+	})
+	if err != nil {
+		return nil, err
+	}
+	bindings := make(map[string]aclBinding)
+	for _, resourceAcl := range resourceAcls {
+		for _, acl := range resourceAcl.Acls {
+			binding := aclBinding{resource: resourceAcl.Resource, acl: *acl}
+			bindings[binding.String()] = binding
+		}
+	}
+	return bindings, nil
+}
+
+func deleteAclBinding(admin sarama.ClusterAdmin, binding aclBinding) error {
+	_, err := admin.DeleteACL(sarama.AclFilter{
+		ResourceType:              binding.resource.ResourceType,
+		ResourceName:              &binding.resource.ResourceName,
+		ResourcePatternTypeFilter: binding.resource.ResourcePatternType,
+		Principal:                 &binding.acl.Principal,
+		Host:                      &binding.acl.Host,
+		Operation:                 binding.acl.Operation,
+		PermissionType:            binding.acl.PermissionType,
+	}, false)
+	return err
+}
+
+// reconcileUserAcls creates the missing acls of the user and deletes the ones not in the spec, the principal
+// of the user is owned by the user. the differences found once the spec has been applied are reported as drift
+func (r *KafkaUserReconciler) reconcileUserAcls(user *kafkav1.KafkaUser, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	desired, err := getDesiredAclBindings(user)
+	if err != nil {
+		return err
+	}
+	if !IsAuthorizationEnabled(cluster) {
+		if len(desired) > 0 {
+			return fmt.Errorf("the authorization of the cluster %s is not enabled", cluster.Name)
+		}
+		user.Status.AclDrift = nil
+		user.Status.LastAclDriftTime = ""
+		return nil
+	}
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	exists, err := listAclBindings(admin, getUserPrincipal(user))
+	if err != nil {
+		return err
+	}
+
+	drift := make([]string, 0)
+	missing := make([]*sarama.ResourceAcls, 0)
+	for _, key := range sortedKeys(desired) {
+		if _, ok := exists[key]; ok {
+			continue
+		}
+		binding := desired[key]
+		drift = append(drift, "missing "+key)
+		missing = append(missing, &sarama.ResourceAcls{Resource: binding.resource, Acls: []*sarama.Acl{&binding.acl}})
+	}
+	if len(missing) > 0 {
+		logger.Info(fmt.Sprintf("Creating %d acls of the user %s", len(missing), user.Name))
+		if err = admin.CreateACLs(missing); err != nil {
+			return err
+		}
+	}
+	for _, key := range sortedKeys(exists) {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		drift = append(drift, "unexpected "+key)
+		logger.Info(fmt.Sprintf("Deleting the acl %s of the user %s", key, user.Name))
+		if err = deleteAclBinding(admin, exists[key]); err != nil {
+			return err
+		}
+	}
+	// the differences are expected while a new spec is being applied
+	if len(drift) > 0 && user.Status.Ready && user.Status.ObservedGeneration == user.Generation {
+		logger.Info(fmt.Sprintf("The acls of the user %s drifted: %v", user.Name, drift))
+		user.Status.AclDrift = drift
+		user.Status.LastAclDriftTime = time.Now().Format(time.RFC3339)
+	}
+	return nil
+}
+
+// deleteUserAcls deletes all the acls of the principal of the user
+func deleteUserAcls(admin sarama.ClusterAdmin, user *kafkav1.KafkaUser) error {
+	principal := getUserPrincipal(user)
+	_, err := admin.DeleteACL(sarama.AclFilter{
+		ResourceType:              sarama.AclResourceAny,
+		ResourcePatternTypeFilter: sarama.AclPatternAny,
+		Principal:                 &principal,
+		Operation:                 sarama.AclOperationAny,
+		PermissionType:            sarama.AclPermissionAny,
+	}, false)
+	return err
+}
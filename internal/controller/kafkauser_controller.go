@@ -0,0 +1,445 @@
+/*
+Copyright 2024 nineinfra.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+// KafkaUserReconciler reconciles a KafkaUser object
+type KafkaUserReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkausers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkausers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kafka.nineinfra.tech,resources=kafkausers/finalizers,verbs=update
+
+// Reconcile creates the credential of the user in the cluster and writes the connection secret of the user
+func (r *KafkaUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var user kafkav1.KafkaUser
+	err := r.Get(ctx, req.NamespacedName, &user)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("Object not found, it could have been deleted")
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Error occurred during fetching the object")
+		return ctrl.Result{}, err
+	}
+
+	if !user.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, finalizeClusterResource(r.Client, &user, user.Spec.ClusterRef, func(cluster *kafkav1.KafkaCluster) error {
+			return r.finalizeUser(&user, cluster, logger)
+		})
+	}
+	if err = addFinalizer(r.Client, &user); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	cluster, err := getClusterRef(r.Client, user.Namespace, user.Spec.ClusterRef)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{RequeueAfter: DefaultRequeueInterval},
+				r.updateUserStatus(&user, false, fmt.Sprintf("the cluster %s is not found", user.Spec.ClusterRef))
+		}
+		return ctrl.Result{}, err
+	}
+	renewAfter, err := r.reconcileUser(&user, cluster, logger)
+	if err != nil {
+		logger.Error(err, "Error occurred during reconciling the user")
+		if statusErr := r.updateUserStatus(&user, false, err.Error()); statusErr != nil {
+			logger.Error(statusErr, "Error occurred during updating the user status")
+		}
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: renewAfter}, r.updateUserStatus(&user, true, "")
+}
+
+func (r *KafkaUserReconciler) updateUserStatus(user *kafkav1.KafkaUser, ready bool, message string) error {
+	user.Status.Username = user.Name
+	user.Status.SecretName = user.Name
+	user.Status.Ready = ready
+	user.Status.Message = message
+	if ready {
+		user.Status.ObservedGeneration = user.Generation
+	}
+	return r.Status().Update(context.TODO(), user)
+}
+
+func newRandomPassword() (string, error) {
+	b := make([]byte, DefaultPasswordSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// getUserPassword returns the password from the secret referenced by the user, the one in the
+// connection secret or a random one, in this order
+func (r *KafkaUserReconciler) getUserPassword(user *kafkav1.KafkaUser, connSecret *corev1.Secret) (string, error) {
+	if user.Spec.Authentication.Password != nil && user.Spec.Authentication.Password.SecretKeyRef != nil {
+		ref := user.Spec.Authentication.Password.SecretKeyRef
+		secret := &corev1.Secret{}
+		err := r.Get(context.TODO(), types.NamespacedName{Name: ref.Name, Namespace: user.Namespace}, secret)
+		if err != nil {
+			return "", err
+		}
+		password, ok := secret.Data[ref.Key]
+		if !ok || len(password) == 0 {
+			return "", fmt.Errorf("the key %s is not found in the secret %s", ref.Key, ref.Name)
+		}
+		return string(password), nil
+	}
+	if connSecret != nil {
+		if password, ok := connSecret.Data[DefaultPasswordKey]; ok && len(password) > 0 {
+			return string(password), nil
+		}
+	}
+	return newRandomPassword()
+}
+
+func getScramJaasConfig(username string, password string) string {
+	return fmt.Sprintf("org.apache.kafka.common.security.scram.ScramLoginModule required username=\"%s\" password=\"%s\";",
+		username, password)
+}
+
+func (r *KafkaUserReconciler) constructConnectionSecret(user *kafkav1.KafkaUser, cluster *kafkav1.KafkaCluster, data map[string][]byte) (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      user.Name,
+			Namespace: user.Namespace,
+			Labels: map[string]string{
+				"cluster": cluster.Name,
+				"app":     DefaultUserSign,
+			},
+		},
+		Data: data,
+	}
+	if err := ctrl.SetControllerReference(user, secret, r.Scheme); err != nil {
+		return secret, err
+	}
+	return secret, nil
+}
+
+func (r *KafkaUserReconciler) getConnectionSecret(user *kafkav1.KafkaUser) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := r.Get(context.TODO(), types.NamespacedName{Name: user.Name, Namespace: user.Namespace}, secret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return secret, nil
+}
+
+// checkAlterResults returns the first error in the results of altering the scram credentials
+func checkAlterResults(results []*sarama.AlterUserScramCredentialsResult, ignored ...sarama.KError) error {
+	for _, result := range results {
+		if result.ErrorCode == sarama.ErrNoError {
+			continue
+		}
+		skip := false
+		for _, code := range ignored {
+			if result.ErrorCode == code {
+				skip = true
+			}
+		}
+		if skip {
+			continue
+		}
+		if result.ErrorMessage != nil {
+			return fmt.Errorf("%s: %s", result.ErrorCode.Error(), *result.ErrorMessage)
+		}
+		return result.ErrorCode
+	}
+	return nil
+}
+
+func upsertScramCredential(admin sarama.ClusterAdmin, username string, password string) error {
+	salt := make([]byte, DefaultScramSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	results, err := admin.UpsertUserScramCredentials([]sarama.AlterUserScramCredentialsUpsert{
+		{
+			Name:       username,
+			Mechanism:  sarama.SCRAM_MECHANISM_SHA_512,
+			Iterations: DefaultScramIterations,
+			Salt:       salt,
+			Password:   []byte(password),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return checkAlterResults(results)
+}
+
+// getUserListener returns the bootstrap servers and the security protocol of the listener
+// authenticating the users of the type, the internal listener is preferred
+func getUserListener(cluster *kafkav1.KafkaCluster, authentication kafkav1.UserAuthenticationType) (string, string, error) {
+	if string(getInternalAuthentication(cluster)) == string(authentication) {
+		return GetBootstrapServers(cluster), getInternalSecurityProtocol(cluster), nil
+	}
+	if string(getExternalAuthentication(cluster)) == string(authentication) {
+		if cluster.Status.ExternalClientEndpoint == "" {
+			return "", "", fmt.Errorf("the external address of the cluster %s is pending", cluster.Name)
+		}
+		return cluster.Status.ExternalClientEndpoint, getExternalSecurityProtocol(cluster), nil
+	}
+	return "", "", fmt.Errorf("no listener of the cluster %s has the %s authentication enabled", cluster.Name, authentication)
+}
+
+// reconcileUser creates the credential and the acls of the user and writes the connection secret, it returns
+// when the user needs to be reconciled again to renew the credential or check the acls, zero if never
+func (r *KafkaUserReconciler) reconcileUser(user *kafkav1.KafkaUser, cluster *kafkav1.KafkaCluster, logger logr.Logger) (time.Duration, error) {
+	if isReservedPrincipal(cluster, user.Name) {
+		return 0, fmt.Errorf("the name %s is reserved for the principals of the brokers and the operator", user.Name)
+	}
+	if password := user.Spec.Authentication.Password; password != nil && password.SecretKeyRef != nil && password.SecretKeyRef.Name == user.Name {
+		return 0, fmt.Errorf("the password can not be taken from the connection secret %s written by the operator", user.Name)
+	}
+	bootstrapServers, securityProtocol, err := getUserListener(cluster, user.Spec.Authentication.Type)
+	if err != nil {
+		return 0, err
+	}
+	connSecret, err := r.getConnectionSecret(user)
+	if err != nil {
+		return 0, err
+	}
+	// the secrets of the same name created by others are left alone
+	if connSecret != nil && !metav1.IsControlledBy(connSecret, user) {
+		return 0, fmt.Errorf("the secret %s already exists and is not owned by the user", connSecret.Name)
+	}
+	var (
+		data       map[string][]byte
+		renewAfter time.Duration
+	)
+	switch user.Spec.Authentication.Type {
+	case kafkav1.UserAuthenticationTLS:
+		data, renewAfter, err = r.reconcileTLSUser(user, cluster, connSecret, logger)
+	default:
+		data, err = r.reconcileScramUser(user, cluster, connSecret, logger)
+	}
+	if err != nil {
+		return 0, err
+	}
+	data[DefaultUsernameKey] = []byte(user.Name)
+	data[DefaultSecurityProtocolKey] = []byte(securityProtocol)
+	data[DefaultBootstrapServersKey] = []byte(bootstrapServers)
+
+	if err = r.reconcileUserAcls(user, cluster, logger); err != nil {
+		return 0, err
+	}
+	if IsAuthorizationEnabled(cluster) && (renewAfter == 0 || renewAfter > DefaultAclResyncInterval) {
+		renewAfter = DefaultAclResyncInterval
+	}
+
+	desiredSecret, err := r.constructConnectionSecret(user, cluster, data)
+	if err != nil {
+		return 0, err
+	}
+	if connSecret == nil {
+		logger.Info("Creating a new connection secret")
+		return renewAfter, r.Create(context.TODO(), desiredSecret)
+	}
+	connSecret.Labels = desiredSecret.Labels
+	connSecret.Data = desiredSecret.Data
+	return renewAfter, r.Update(context.TODO(), connSecret)
+}
+
+// reconcileScramUser upserts the credential only if the password or the spec changed, since an upsert
+// with a new salt is a credential change for the brokers
+func (r *KafkaUserReconciler) reconcileScramUser(user *kafkav1.KafkaUser, cluster *kafkav1.KafkaCluster, connSecret *corev1.Secret, logger logr.Logger) (map[string][]byte, error) {
+	password, err := r.getUserPassword(user, connSecret)
+	if err != nil {
+		return nil, err
+	}
+	changed := connSecret == nil || string(connSecret.Data[DefaultPasswordKey]) != password
+	if changed || !user.Status.Ready || user.Status.ObservedGeneration != user.Generation {
+		admin, err := newKafkaAdmin(r.Client, cluster)
+		if err != nil {
+			return nil, err
+		}
+		defer admin.Close()
+		logger.Info(fmt.Sprintf("Upserting the scram credential of the user %s", user.Name))
+		if err = upsertScramCredential(admin, user.Name, password); err != nil {
+			return nil, err
+		}
+	}
+	user.Status.CertificateNotAfter = ""
+	return map[string][]byte{
+		DefaultPasswordKey:       []byte(password),
+		DefaultSaslMechanismKey:  []byte(DefaultScramMechanism),
+		DefaultSaslJaasConfigKey: []byte(getScramJaasConfig(user.Name, password)),
+	}, nil
+}
+
+// reconcileTLSUser issues the client certificate of the user signed by the clients CA. the certificate is kept
+// as long as it is signed by a CA trusted by the brokers and out of the renewal window
+func (r *KafkaUserReconciler) reconcileTLSUser(user *kafkav1.KafkaUser, cluster *kafkav1.KafkaCluster, connSecret *corev1.Secret, logger logr.Logger) (map[string][]byte, time.Duration, error) {
+	if connSecret != nil && len(connSecret.Data[DefaultPasswordKey]) > 0 {
+		if err := r.deleteScramCredential(user, cluster, logger); err != nil {
+			return nil, 0, err
+		}
+	}
+	caSecret := &corev1.Secret{}
+	err := r.Get(context.TODO(), types.NamespacedName{Name: GetClientsCASecretName(cluster), Namespace: cluster.Namespace}, caSecret)
+	if err != nil {
+		return nil, 0, err
+	}
+	ca, err := parseCertificateAuthority(caSecret.Data[DefaultCACertKey], caSecret.Data[DefaultCAKeyKey])
+	if err != nil {
+		return nil, 0, err
+	}
+	trustedSecret := &corev1.Secret{}
+	err = r.Get(context.TODO(), types.NamespacedName{Name: GetClientsCACertSecretName(cluster), Namespace: cluster.Namespace}, trustedSecret)
+	if err != nil {
+		return nil, 0, err
+	}
+	clusterCASecret := &corev1.Secret{}
+	err = r.Get(context.TODO(), types.NamespacedName{Name: GetClusterCACertSecretName(cluster), Namespace: cluster.Namespace}, clusterCASecret)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	renewBefore := getCertRenewBefore(cluster)
+	var certPEM, keyPEM []byte
+	if connSecret != nil {
+		cert, err := parseCertificatePEM(connSecret.Data[DefaultUserCertKey])
+		if err == nil && cert.Subject.CommonName == user.Name && !isCertificateExpiring(cert, renewBefore) {
+			for _, trusted := range parseCertificatesPEM(trustedSecret.Data[DefaultCACertKey]) {
+				if cert.CheckSignatureFrom(trusted) == nil {
+					certPEM, keyPEM = connSecret.Data[DefaultUserCertKey], connSecret.Data[DefaultUserKeyKey]
+					break
+				}
+			}
+		}
+	}
+	if certPEM == nil {
+		logger.Info(fmt.Sprintf("Issuing the client certificate of the user %s", user.Name))
+		certPEM, keyPEM, err = ca.issueCertificate(user.Name, nil, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	cert, err := parseCertificatePEM(certPEM)
+	if err != nil {
+		return nil, 0, err
+	}
+	user.Status.CertificateNotAfter = cert.NotAfter.Format(time.RFC3339)
+	renewAfter := time.Until(cert.NotAfter.Add(-renewBefore))
+	if renewAfter <= 0 {
+		renewAfter = DefaultRequeueInterval
+	}
+	return map[string][]byte{
+		DefaultUserCertKey: certPEM,
+		DefaultUserKeyKey:  keyPEM,
+		DefaultCACertKey:   clusterCASecret.Data[DefaultCACertKey],
+	}, renewAfter, nil
+}
+
+// deleteScramCredential deletes the scram credential of the user, a missing credential is not an error
+func (r *KafkaUserReconciler) deleteScramCredential(user *kafkav1.KafkaUser, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	logger.Info(fmt.Sprintf("Deleting the scram credential of the user %s", user.Name))
+	results, err := admin.DeleteUserScramCredentials([]sarama.AlterUserScramCredentialsDelete{
+		{
+			Name:      user.Name,
+			Mechanism: sarama.SCRAM_MECHANISM_SHA_512,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return checkAlterResults(results, KafkaErrResourceNotFound)
+}
+
+// finalizeUser deletes the scram credential and the acls of the user from the cluster before releasing the user
+func (r *KafkaUserReconciler) finalizeUser(user *kafkav1.KafkaUser, cluster *kafkav1.KafkaCluster, logger logr.Logger) error {
+	// the certificates of the tls users can not be revoked, they are gone with the connection secret
+	if user.Spec.Authentication.Type != kafkav1.UserAuthenticationTLS {
+		if err := r.deleteScramCredential(user, cluster, logger); err != nil {
+			return err
+		}
+	}
+	if !IsAuthorizationEnabled(cluster) {
+		return nil
+	}
+	admin, err := newKafkaAdmin(r.Client, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	logger.Info(fmt.Sprintf("Deleting the acls of the user %s", user.Name))
+	return deleteUserAcls(admin, user)
+}
+
+// getUsersOfSecret maps a secret to the users taking their passwords from it
+func (r *KafkaUserReconciler) getUsersOfSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	users := &kafkav1.KafkaUserList{}
+	if err := r.List(ctx, users, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for _, user := range users.Items {
+		password := user.Spec.Authentication.Password
+		if password != nil && password.SecretKeyRef != nil && password.SecretKeyRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: user.Name, Namespace: user.Namespace},
+			})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KafkaUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kafkav1.KafkaUser{}).
+		Owns(&corev1.Secret{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.getUsersOfSecret)).
+		Complete(r)
+}
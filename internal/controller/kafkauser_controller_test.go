@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+)
+
+// TestReconcileUserConnectionSecret leaves alone the secrets not written by the operator for the user
+func TestReconcileUserConnectionSecret(t *testing.T) {
+	g := NewWithT(t)
+	cluster := &kafkav1.KafkaCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec: kafkav1.KafkaClusterSpec{Listeners: &kafkav1.ListenersConfig{
+			Internal: &kafkav1.InternalListenerConfig{TLS: true, Authentication: kafkav1.ListenerAuthenticationScramSHA512},
+		}},
+	}
+	user := &kafkav1.KafkaUser{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: kafkav1.KafkaUserSpec{
+			ClusterRef:     cluster.Name,
+			Authentication: kafkav1.UserAuthentication{Type: kafkav1.UserAuthenticationScramSHA512},
+		},
+	}
+	foreign := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: user.Name, Namespace: user.Namespace},
+		Data:       map[string][]byte{"token": []byte("value")},
+	}
+	fake := newFakeReconciler(cluster, user, foreign)
+	r := &KafkaUserReconciler{Client: fake.Client, Scheme: fake.Scheme}
+
+	_, err := r.reconcileUser(user, cluster, logr.Discard())
+	g.Expect(err).To(MatchError(ContainSubstring("not owned by the user")))
+
+	user.Spec.Authentication.Password = &kafkav1.PasswordSource{SecretKeyRef: &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: user.Name}, Key: "token",
+	}}
+	_, err = r.reconcileUser(user, cluster, logr.Discard())
+	g.Expect(err).To(MatchError(ContainSubstring("connection secret")))
+}
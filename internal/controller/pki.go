@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"time"
+)
+
+type certificateAuthority struct {
+	cert    *x509.Certificate
+	key     *rsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// encodePrivateKeyPEM encodes the key in PKCS#8, which is the only format supported by the PEM keystores of kafka
+func encodePrivateKeyPEM(key *rsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func encodeCertificatePEM(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// parseCertificatePEM parses the first certificate in the PEM data
+func parseCertificatePEM(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no certificate found")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+func newCertificateAuthority(commonName string) (*certificateAuthority, error) {
+	key, err := rsa.GenerateKey(rand.Reader, DefaultKeySize)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(DefaultCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodePrivateKeyPEM(key)
+	if err != nil {
+		return nil, err
+	}
+	return parseCertificateAuthority(encodeCertificatePEM(der), keyPEM)
+}
+
+func parseCertificateAuthority(certPEM []byte, keyPEM []byte) (*certificateAuthority, error) {
+	cert, err := parseCertificatePEM(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no private key found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key of the CA must be a rsa key")
+	}
+	return &certificateAuthority{cert: cert, key: key, certPEM: certPEM, keyPEM: keyPEM}, nil
+}
+
+// issueCertificate issues a certificate for both the server and the client authentication,
+// and returns the certificate along with the chain and the key in PEM
+func (ca *certificateAuthority) issueCertificate(commonName string, dnsNames []string, ips []net.IP) ([]byte, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, DefaultKeySize)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	notAfter := now.Add(DefaultCertValidity)
+	if notAfter.After(ca.cert.NotAfter) {
+		notAfter = ca.cert.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := encodePrivateKeyPEM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(encodeCertificatePEM(der), ca.certPEM...), keyPEM, nil
+}
+
+// isCertificateValid returns true if the certificate is signed by the CA, not in the renewal window and covers exactly the sans
+func (ca *certificateAuthority) isCertificateValid(cert *x509.Certificate, dnsNames []string, ips []net.IP, renewBefore time.Duration) bool {
+	if err := cert.CheckSignatureFrom(ca.cert); err != nil {
+		return false
+	}
+	if isCertificateExpiring(cert, renewBefore) {
+		return false
+	}
+	if !equalStrings(cert.DNSNames, dnsNames) {
+		return false
+	}
+	certIPs := make([]string, 0, len(cert.IPAddresses))
+	for _, ip := range cert.IPAddresses {
+		certIPs = append(certIPs, ip.String())
+	}
+	desiredIPs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		desiredIPs = append(desiredIPs, ip.String())
+	}
+	return equalStrings(certIPs, desiredIPs)
+}
+
+func isCertificateExpiring(cert *x509.Certificate, renewBefore time.Duration) bool {
+	return time.Now().Add(renewBefore).After(cert.NotAfter)
+}
+
+// parseCertificatesPEM parses all the certificates in the PEM data
+func parseCertificatesPEM(data []byte) []*x509.Certificate {
+	certs := make([]*x509.Certificate, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
+
+// containsCertificates returns true if all the certificates are in the bundle
+func containsCertificates(bundle []byte, certsPEM []byte) bool {
+	bundleCerts := parseCertificatesPEM(bundle)
+	for _, cert := range parseCertificatesPEM(certsPEM) {
+		found := false
+		for _, c := range bundleCerts {
+			if c.Equal(cert) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeCertificatesPEM returns a bundle of the certificates in both bundles without the duplicated ones
+func mergeCertificatesPEM(bundle []byte, certsPEM []byte) []byte {
+	merged := append([]byte{}, bundle...)
+	bundleCerts := parseCertificatesPEM(bundle)
+	for _, cert := range parseCertificatesPEM(certsPEM) {
+		found := false
+		for _, c := range bundleCerts {
+			if c.Equal(cert) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, encodeCertificatePEM(cert.Raw)...)
+		}
+	}
+	return merged
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitSANs splits the sans into the dns names and the ips, dropping the duplicated ones
+func splitSANs(sans []string) ([]string, []net.IP) {
+	dnsNames := make([]string, 0)
+	ips := make([]net.IP, 0)
+	seen := make(map[string]bool)
+	for _, san := range sans {
+		if san == "" || seen[san] {
+			continue
+		}
+		seen[san] = true
+		if ip := net.ParseIP(san); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = append(dnsNames, san)
+		}
+	}
+	return dnsNames, ips
+}
+
+// joinKeystore joins the key and the certificate chain into a PEM keystore
+func joinKeystore(certPEM []byte, keyPEM []byte) []byte {
+	return bytes.Join([][]byte{keyPEM, certPEM}, nil)
+}
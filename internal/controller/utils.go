@@ -1,12 +1,31 @@
 package controller
 
-import "strings"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+)
 
 func int32Ptr(i int32) *int32 { return &i }
 
+func sortedKeys[V any](kv map[string]V) []string {
+	keys := make([]string, 0, len(kv))
+	for key := range kv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// map2String renders the k/v in the order of the keys, so that the same map always results in the same string
 func map2String(kv map[string]string) string {
 	var sb strings.Builder
-	for key, value := range kv {
+	for _, key := range sortedKeys(kv) {
+		value := kv[key]
 		sb.WriteString(key)
 		sb.WriteString("=")
 		sb.WriteString(value)
@@ -14,3 +33,32 @@ func map2String(kv map[string]string) string {
 	}
 	return sb.String()
 }
+
+// newKafkaClusterID generates a random cluster id in the same format as
+// the kafka-storage.sh random-uuid command
+func newKafkaClusterID() (string, error) {
+	for {
+		uuid := make([]byte, 16)
+		if _, err := rand.Read(uuid); err != nil {
+			return "", err
+		}
+		// version 4 and IETF variant, same as java.util.UUID.randomUUID
+		uuid[6] = (uuid[6] & 0x0f) | 0x40
+		uuid[8] = (uuid[8] & 0x3f) | 0x80
+		id := base64.RawURLEncoding.EncodeToString(uuid)
+		// kafka never hands out ids starting with a dash since they break the command line tools
+		if !strings.HasPrefix(id, "-") {
+			return id, nil
+		}
+	}
+}
+
+// hashObject returns the sha256 of the json encoding of the object
+func hashObject(obj interface{}) (string, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
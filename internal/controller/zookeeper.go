@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-zookeeper/zk"
+	kafkav1 "github.com/nineinfra/kafka-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// splitZookeeperConnect splits a connect string like zk-0:2181,zk-1:2181/kafka into the servers and the chroot
+func splitZookeeperConnect(connect string) (string, string) {
+	if i := strings.Index(connect, "/"); i >= 0 {
+		return connect[:i], connect[i:]
+	}
+	return connect, ""
+}
+
+func getZookeeperChroot(cluster *kafkav1.KafkaCluster) string {
+	zkConf := cluster.Spec.Zookeeper
+	if zkConf.Chroot != "" {
+		return "/" + strings.Trim(zkConf.Chroot, "/")
+	}
+	if _, chroot := splitZookeeperConnect(zkConf.ConnectString); chroot != "" {
+		return "/" + strings.Trim(chroot, "/")
+	}
+	return fmt.Sprintf("%s/%s/%s", DefaultZookeeperChrootPrefix, cluster.Namespace, cluster.Name)
+}
+
+func (r *KafkaClusterReconciler) resolveZookeeperServers(ctx context.Context, cluster *kafkav1.KafkaCluster) (string, error) {
+	zkConf := cluster.Spec.Zookeeper
+	if zkConf.Reference == nil {
+		servers, _ := splitZookeeperConnect(zkConf.ConnectString)
+		if servers == "" {
+			return "", fmt.Errorf("neither the connect string nor the reference of the zookeeper is specified")
+		}
+		return servers, nil
+	}
+
+	ref := zkConf.Reference
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = cluster.Namespace
+	}
+	svcName := ref.Name
+	switch ref.Kind {
+	case ZookeeperReferenceKindService:
+	default:
+		zkCluster := &unstructured.Unstructured{}
+		zkCluster.SetGroupVersionKind(schema.GroupVersionKind{
+			Group:   ZookeeperClusterGroup,
+			Version: ZookeeperClusterVersion,
+			Kind:    ZookeeperReferenceKindCluster,
+		})
+		if err := r.Client.Get(context.TODO(), types.NamespacedName{Name: ref.Name, Namespace: namespace}, zkCluster); err != nil {
+			return "", err
+		}
+		svcName = ref.Name + DefaultZookeeperSvcNameSuffix
+	}
+
+	svc := &corev1.Service{}
+	if err := r.Client.Get(context.TODO(), types.NamespacedName{Name: svcName, Namespace: namespace}, svc); err != nil {
+		return "", err
+	}
+	port := ref.Port
+	if port == 0 {
+		port = DefaultZookeeperPort
+		if len(svc.Spec.Ports) == 1 {
+			port = svc.Spec.Ports[0].Port
+		}
+	}
+	return fmt.Sprintf("%s.%s.svc.%s:%d", svcName, namespace, GetClusterDomain(cluster), port), nil
+}
+
+// ensureZookeeperChroot creates the chroot and all of its parents if they do not exist yet
+func ensureZookeeperChroot(servers string, chroot string) error {
+	conn, events, err := zk.Connect(strings.Split(servers, ","), DefaultZookeeperSessionTimeout, zk.WithLogInfo(false))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	timeout := time.After(DefaultZookeeperSessionTimeout)
+	for connected := false; !connected; {
+		select {
+		case event := <-events:
+			connected = event.State == zk.StateHasSession
+		case <-timeout:
+			return fmt.Errorf("timed out connecting to the zookeeper %s", servers)
+		}
+	}
+
+	path := ""
+	for _, node := range strings.Split(strings.Trim(chroot, "/"), "/") {
+		path = path + "/" + node
+		_, err := conn.Create(path, nil, 0, zk.WorldACL(zk.PermAll))
+		if err != nil && err != zk.ErrNodeExists {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *KafkaClusterReconciler) reconcileZookeeper(ctx context.Context, cluster *kafkav1.KafkaCluster, logger logr.Logger) (err error) {
+	if IsKraftMode(cluster) || cluster.Spec.Zookeeper == nil {
+		return nil
+	}
+	servers, err := r.resolveZookeeperServers(ctx, cluster)
+	if err != nil {
+		logger.Error(err, "Failed to resolve the zookeeper")
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			cluster.Status.SetZookeeperUnreachableConditionTrue(kafkav1.ZookeeperNotFoundReason, err.Error())
+		} else {
+			cluster.Status.SetZookeeperUnreachableConditionTrue(kafkav1.ZookeeperConnectFailedReason, err.Error())
+		}
+		return r.updateStatusWithError(ctx, cluster, err)
+	}
+	chroot := getZookeeperChroot(cluster)
+	// the zookeeper is only connected to create the chroot once the connect string changes
+	if cluster.Status.ZookeeperConnect == servers+chroot {
+		cluster.Status.SetZookeeperUnreachableConditionFalse()
+		return nil
+	}
+	if err = ensureZookeeperChroot(servers, chroot); err != nil {
+		logger.Error(err, fmt.Sprintf("Failed to create the chroot %s in the zookeeper %s", chroot, servers))
+		cluster.Status.SetZookeeperUnreachableConditionTrue(kafkav1.ZookeeperConnectFailedReason, err.Error())
+		return r.updateStatusWithError(ctx, cluster, err)
+	}
+	cluster.Status.SetZookeeperUnreachableConditionFalse()
+	cluster.Status.ZookeeperConnect = servers + chroot
+	return nil
+}